@@ -2,15 +2,54 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
+var (
+	errAccountNotOwned   = errors.New("account doesn't belong to the authenticated user")
+	errAccountInUse      = errors.New("account currency can only change while the account has no balance and no entries")
+	errAccountHasHistory = errors.New("account with entries, transfers or orders cannot be deleted")
+	errOverdraftNegative = errors.New("overdraft limit must not be negative")
+)
+
+type accountResponse struct {
+	ID             int64      `json:"id"`
+	Owner          string     `json:"owner"`
+	Balance        util.Money `json:"balance"`
+	Currency       string     `json:"currency"`
+	OverdraftLimit util.Money `json:"overdraft_limit"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:             account.ID,
+		Owner:          account.Owner,
+		Balance:        util.NewMoney(account.Balance, account.Currency),
+		Currency:       account.Currency,
+		OverdraftLimit: util.NewMoney(account.OverdraftLimit, account.Currency),
+		CreatedAt:      account.CreatedAt,
+	}
+}
+
+func newAccountsResponse(accounts []db.Account) []accountResponse {
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(account)
+	}
+
+	return rsp
+}
+
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
 	Currency string `json:"currency" binding:"required,currency"`
 }
 
@@ -21,8 +60,9 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.CreateAccountParams{
-		Owner:    req.Owner,
+		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
 	}
@@ -40,7 +80,7 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, account)
+	ctx.JSON(http.StatusCreated, newAccountResponse(account))
 }
 
 type getAccountRequest struct {
@@ -65,7 +105,13 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountNotOwned))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type listAccountsRequest struct {
@@ -80,39 +126,162 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.ListAccountsParams{
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.ListAccountsByOwnerParams{
+		Owner:  authPayload.Username,
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
 
-	accounts, err := server.store.ListAccounts(ctx, arg)
+	accounts, err := server.store.ListAccountsByOwner(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountsResponse(accounts))
+}
+
+type adminListAccountsRequest struct {
+	Owner    string `form:"owner" binding:"omitempty,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=20"`
+}
+
+// adminListAccounts lists the accounts of every owner, or of the one given in the query
+func (server *Server) adminListAccounts(ctx *gin.Context) {
+	var req adminListAccountsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var accounts []db.Account
+	var err error
+
+	if req.Owner != "" {
+		accounts, err = server.store.ListAccountsByOwner(ctx, db.ListAccountsByOwnerParams{
+			Owner:  req.Owner,
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	} else {
+		accounts, err = server.store.ListAccounts(ctx, db.ListAccountsParams{
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountsResponse(accounts))
+}
+
+// adminGetAccount returns any account regardless of its owner
+func (server *Server) adminGetAccount(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
+}
+
+type updateAccountOverdraftRequest struct {
+	// OverdraftLimit is a decimal amount in the currency of the account, such as "100.00"
+	OverdraftLimit util.Decimal `json:"overdraft_limit" binding:"required"`
+}
+
+// adminUpdateAccountOverdraft sets how far below zero the balance of any account may go
+func (server *Server) adminUpdateAccountOverdraft(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateAccountOverdraftRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	limit, err := req.OverdraftLimit.Money(account.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if limit.Amount < 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errOverdraftNegative))
+		return
+	}
+
+	account, err = server.store.UpdateAccountOverdraftLimit(ctx, db.UpdateAccountOverdraftLimitParams{
+		ID:             account.ID,
+		OverdraftLimit: limit.Amount,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
+}
+
+type updateAccountURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
 type updateAccountRequest struct {
-	ID       int64  `uri:"id" binding:"required,min=1"`
-	Owner    string `json:"owner" default:""`
-	Currency string `json:"currency" default:"" binding:"omitempty,currency"`
+	Currency string `json:"currency" binding:"required,currency"`
 }
 
+// updateAccount changes the currency of an account, as long as the account never held money
 func (server *Server) updateAccount(ctx *gin.Context) {
-	var req updateAccountRequest
-	if err := ctx.ShouldBindUri(&req); err != nil {
+	var uri updateAccountURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
+	var req updateAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	account, err := server.store.GetAccount(ctx, req.ID)
+	account, err := server.store.GetAccount(ctx, uri.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
@@ -123,27 +292,43 @@ func (server *Server) updateAccount(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.UpdateAccountParams{
-		ID:       req.ID,
-		Owner:    req.Owner,
-		Currency: req.Currency,
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountNotOwned))
+		return
 	}
 
-	if arg.Owner == "" {
-		arg.Owner = account.Owner
+	if req.Currency == account.Currency {
+		ctx.JSON(http.StatusOK, newAccountResponse(account))
+		return
 	}
 
-	if arg.Currency == "" {
-		arg.Currency = account.Currency
+	if account.Balance != 0 {
+		ctx.JSON(http.StatusConflict, errorResponse(errAccountInUse))
+		return
 	}
 
-	account, err = server.store.UpdateAccount(ctx, arg)
+	// the update itself checks the account has no entries, as they may be booked meanwhile
+	account, err = server.store.UpdateAccount(ctx, db.UpdateAccountParams{
+		ID:       uri.ID,
+		Currency: req.Currency,
+	})
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errAccountInUse))
+			return
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type deleteAccountRequest struct {
@@ -157,7 +342,7 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
-	_, err := server.store.GetAccount(ctx, req.ID)
+	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
@@ -168,8 +353,31 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountNotOwned))
+		return
+	}
+
+	hasEntries, err := server.store.AccountHasEntries(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if hasEntries {
+		ctx.JSON(http.StatusConflict, errorResponse(errAccountHasHistory))
+		return
+	}
+
 	err = server.store.DeleteAccount(ctx, req.ID)
 	if err != nil {
+		// transfers, scheduled transfers and standing orders still refer to the account
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			ctx.JSON(http.StatusConflict, errorResponse(errAccountHasHistory))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidCursor      = errors.New("invalid cursor")
+	errInvalidTimeRange   = errors.New("from must be before to")
+	errInvalidAmountRange = errors.New("min_amount must not exceed max_amount")
+	errAmountNegative     = errors.New("amount must not be negative")
+)
+
+// directionAll lists the money coming in and going out of the account
+const directionAll = "all"
+
+type accountHistoryRequest struct {
+	// Direction keeps the money coming in, going out, or both when empty
+	Direction string    `form:"direction" binding:"omitempty,oneof=all in out"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	// MinAmount and MaxAmount are decimals in the currency of the account, bounds included
+	MinAmount util.Decimal `form:"min_amount"`
+	MaxAmount util.Decimal `form:"max_amount"`
+	// Cursor is the next_cursor of the previous page, empty for the first one
+	Cursor   string `form:"cursor"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=20"`
+}
+
+// accountHistory is the account and filters of a history request
+type accountHistory struct {
+	account   db.Account
+	direction string
+	fromTime  sql.NullTime
+	toTime    sql.NullTime
+	minAmount sql.NullInt64
+	maxAmount sql.NullInt64
+	beforeID  sql.NullInt64
+	pageSize  int32
+}
+
+// encodeCursor makes the opaque cursor of the page following the row with the given ID
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errInvalidCursor
+	}
+
+	return id, nil
+}
+
+// parseAmountFilter turns an optional decimal bound into minor units of the currency
+func parseAmountFilter(amount util.Decimal, currency string) (sql.NullInt64, error) {
+	if amount == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	money, err := amount.Money(currency)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	if money.Amount < 0 {
+		return sql.NullInt64{}, errAmountNegative
+	}
+
+	return sql.NullInt64{Int64: money.Amount, Valid: true}, nil
+}
+
+// ownedAccount loads the account, responding with an error unless it belongs to the authenticated user
+func (server *Server) ownedAccount(ctx *gin.Context, id int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountNotOwned))
+		return account, false
+	}
+
+	return account, true
+}
+
+// bindAccountHistory parses a history request on the account of the URI,
+// responding with an error unless it is valid and the account belongs to the authenticated user
+func (server *Server) bindAccountHistory(ctx *gin.Context) (accountHistory, bool) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return accountHistory{}, false
+	}
+
+	var req accountHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return accountHistory{}, false
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidTimeRange))
+		return accountHistory{}, false
+	}
+
+	history := accountHistory{
+		direction: req.Direction,
+		fromTime:  sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		toTime:    sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		// one more row than asked tells whether another page follows
+		pageSize: req.PageSize + 1,
+	}
+
+	if history.direction == "" {
+		history.direction = directionAll
+	}
+
+	if req.Cursor != "" {
+		id, err := decodeCursor(req.Cursor)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return accountHistory{}, false
+		}
+
+		history.beforeID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	account, valid := server.ownedAccount(ctx, uri.ID)
+	if !valid {
+		return accountHistory{}, false
+	}
+
+	history.account = account
+
+	// amounts are scaled with the currency of the account, known only now
+	var err error
+	history.minAmount, err = parseAmountFilter(req.MinAmount, account.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return accountHistory{}, false
+	}
+
+	history.maxAmount, err = parseAmountFilter(req.MaxAmount, account.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return accountHistory{}, false
+	}
+
+	if history.minAmount.Valid && history.maxAmount.Valid && history.minAmount.Int64 > history.maxAmount.Int64 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidAmountRange))
+		return accountHistory{}, false
+	}
+
+	return history, true
+}
+
+// nextCursor trims the extra row fetched past the page, returning the cursor to the next page if there is one
+func (history accountHistory) nextCursor(count int, lastID func(i int) int64) (int, string) {
+	if count < int(history.pageSize) {
+		return count, ""
+	}
+
+	count--
+	return count, encodeCursor(lastID(count - 1))
+}
+
+type listAccountTransfersResponse struct {
+	Transfers  []transferResponse `json:"transfers"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// listAccountTransfers returns the transfers from and to the account, latest first
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	history, valid := server.bindAccountHistory(ctx)
+	if !valid {
+		return
+	}
+
+	rows, err := server.store.ListAccountTransfers(ctx, db.ListAccountTransfersParams{
+		AccountID: history.account.ID,
+		Direction: history.direction,
+		FromTime:  history.fromTime,
+		ToTime:    history.toTime,
+		MinAmount: history.minAmount,
+		MaxAmount: history.maxAmount,
+		BeforeID:  history.beforeID,
+		PageSize:  history.pageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	count, cursor := history.nextCursor(len(rows), func(i int) int64 {
+		return rows[i].Transfer.ID
+	})
+
+	rsp := listAccountTransfersResponse{
+		Transfers:  make([]transferResponse, count),
+		NextCursor: cursor,
+	}
+	for i, row := range rows[:count] {
+		rsp.Transfers[i] = newTransferResponse(row.Transfer, row.FromCurrency, row.ToCurrency)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type listAccountEntriesResponse struct {
+	Entries    []entryResponse `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// listAccountEntries returns the entries of the account, latest first
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	history, valid := server.bindAccountHistory(ctx)
+	if !valid {
+		return
+	}
+
+	entries, err := server.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+		AccountID: history.account.ID,
+		Direction: history.direction,
+		FromTime:  history.fromTime,
+		ToTime:    history.toTime,
+		MinAmount: history.minAmount,
+		MaxAmount: history.maxAmount,
+		BeforeID:  history.beforeID,
+		PageSize:  history.pageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	count, cursor := history.nextCursor(len(entries), func(i int) int64 {
+		return entries[i].ID
+	})
+
+	rsp := listAccountEntriesResponse{
+		Entries:    make([]entryResponse, count),
+		NextCursor: cursor,
+	}
+	for i, entry := range entries[:count] {
+		rsp.Entries[i] = newEntryResponse(entry, history.account.Currency)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
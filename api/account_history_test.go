@@ -0,0 +1,498 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type accountHistoryTestCases struct {
+	name          string
+	accountID     int64
+	query         url.Values
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func runAccountHistoryTestCases(t *testing.T, resource string, testCases []accountHistoryTestCases) {
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/%s?%s", tc.accountID, resource, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersApi(t *testing.T) {
+	currency := util.USD
+	account := randomAccount(&currency)
+	other := randomAccount(&currency)
+
+	runAccountHistoryTestCases(t, "transfers", getListAccountTransfersTestCases(account, other))
+}
+
+func TestListAccountEntriesApi(t *testing.T) {
+	currency := util.USD
+	account := randomAccount(&currency)
+
+	runAccountHistoryTestCases(t, "entries", getListAccountEntriesTestCases(account))
+}
+
+// historyQuery builds the query of a history request with the given page size and extra filters
+func historyQuery(pageSize int, filters ...string) url.Values {
+	query := url.Values{}
+	query.Set("page_size", fmt.Sprint(pageSize))
+
+	for i := 0; i+1 < len(filters); i += 2 {
+		query.Set(filters[i], filters[i+1])
+	}
+
+	return query
+}
+
+// expectHistoryAccount stubs the lookup of the account whose history is listed
+func expectHistoryAccount(store *mockdb.MockStore, account db.Account) {
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+}
+
+// getAccountHistoryErrorTestCases covers the validation and ownership shared by every history route,
+// expectNoList asserting that the history is never read
+func getAccountHistoryErrorTestCases(account db.Account, expectNoList func(store *mockdb.MockStore)) []accountHistoryTestCases {
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+	}
+
+	badRequest := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+
+	withAccount := func(store *mockdb.MockStore) {
+		expectHistoryAccount(store, account)
+		expectNoList(store)
+	}
+
+	withoutAccount := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Any()).
+			Times(0)
+		expectNoList(store)
+	}
+
+	return []accountHistoryTestCases{
+		{
+			name:      "NotOwned",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: withAccount,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: withoutAccount,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				expectNoList(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:          "InvalidID",
+			accountID:     0,
+			query:         historyQuery(5),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidPageSize",
+			accountID:     account.ID,
+			query:         historyQuery(50),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidDirection",
+			accountID:     account.ID,
+			query:         historyQuery(5, "direction", "sideways"),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidCursor",
+			accountID:     account.ID,
+			query:         historyQuery(5, "cursor", "not a cursor"),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidTimeRange",
+			accountID:     account.ID,
+			query:         historyQuery(5, "from", "2024-02-01T00:00:00Z", "to", "2024-01-01T00:00:00Z"),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidAmountRange",
+			accountID:     account.ID,
+			query:         historyQuery(5, "min_amount", "10.00", "max_amount", "5.00"),
+			setupAuth:     asOwner,
+			buildStubs:    withAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "NegativeAmount",
+			accountID:     account.ID,
+			query:         historyQuery(5, "min_amount", "-1.00"),
+			setupAuth:     asOwner,
+			buildStubs:    withAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "TooManyDecimals",
+			accountID:     account.ID,
+			query:         historyQuery(5, "max_amount", "1.234"),
+			setupAuth:     asOwner,
+			buildStubs:    withAccount,
+			checkResponse: badRequest,
+		},
+	}
+}
+
+func getListAccountTransfersTestCases(account db.Account, other db.Account) []accountHistoryTestCases {
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+	}
+
+	n := 6
+	rows := make([]db.ListAccountTransfersRow, n)
+	for i := range rows {
+		from, to := account, other
+		if i%2 == 1 {
+			from, to = other, account
+		}
+
+		rows[i] = db.ListAccountTransfersRow{
+			Transfer:     randomTransferTxResult(from, to, 100, 100).Transfer,
+			FromCurrency: from.Currency,
+			ToCurrency:   to.Currency,
+		}
+		rows[i].Transfer.ID = int64(100 - i)
+	}
+
+	expectList := func(store *mockdb.MockStore, arg db.ListAccountTransfersParams, rows []db.ListAccountTransfersRow, err error) {
+		expectHistoryAccount(store, account)
+		store.EXPECT().
+			ListAccountTransfers(gomock.Any(), gomock.Eq(arg)).
+			Times(1).
+			Return(rows, err)
+	}
+
+	requireTransfers := func(t *testing.T, recorder *httptest.ResponseRecorder, rows []db.ListAccountTransfersRow, cursor string) {
+		expected := listAccountTransfersResponse{
+			Transfers:  make([]transferResponse, len(rows)),
+			NextCursor: cursor,
+		}
+		for i, row := range rows {
+			expected.Transfers[i] = newTransferResponse(row.Transfer, row.FromCurrency, row.ToCurrency)
+		}
+
+		data, err := json.Marshal(expected)
+		require.NoError(t, err)
+		require.JSONEq(t, string(data), recorder.Body.String())
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	testCases := []accountHistoryTestCases{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountTransfersParams{
+					AccountID: account.ID,
+					Direction: directionAll,
+					PageSize:  6,
+				}, rows[:3], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireTransfers(t, recorder, rows[:3], "")
+				require.NotContains(t, recorder.Body.String(), "next_cursor")
+			},
+		},
+		{
+			name:      "NextPage",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountTransfersParams{
+					AccountID: account.ID,
+					Direction: directionAll,
+					PageSize:  6,
+				}, rows, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				// the extra row is dropped and the cursor resumes after the last one shown
+				requireTransfers(t, recorder, rows[:5], encodeCursor(rows[4].Transfer.ID))
+			},
+		},
+		{
+			name:      "Filters",
+			accountID: account.ID,
+			query: historyQuery(5,
+				"direction", "in",
+				"from", from.Format(time.RFC3339),
+				"to", to.Format(time.RFC3339),
+				"min_amount", "1.50",
+				"max_amount", "20",
+				"cursor", encodeCursor(rows[2].Transfer.ID),
+			),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountTransfersParams{
+					AccountID: account.ID,
+					Direction: "in",
+					FromTime:  sql.NullTime{Time: from, Valid: true},
+					ToTime:    sql.NullTime{Time: to, Valid: true},
+					MinAmount: sql.NullInt64{Int64: 150, Valid: true},
+					MaxAmount: sql.NullInt64{Int64: 2000, Valid: true},
+					BeforeID:  sql.NullInt64{Int64: rows[2].Transfer.ID, Valid: true},
+					PageSize:  6,
+				}, rows[3:4], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireTransfers(t, recorder, rows[3:4], "")
+			},
+		},
+		{
+			name:      "Empty",
+			accountID: account.ID,
+			query:     historyQuery(5, "direction", "out"),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountTransfersParams{
+					AccountID: account.ID,
+					Direction: "out",
+					PageSize:  6,
+				}, []db.ListAccountTransfersRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"transfers": []}`, recorder.Body.String())
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountTransfersParams{
+					AccountID: account.ID,
+					Direction: directionAll,
+					PageSize:  6,
+				}, nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	return append(testCases, getAccountHistoryErrorTestCases(account, func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ListAccountTransfers(gomock.Any(), gomock.Any()).
+			Times(0)
+	})...)
+}
+
+func getListAccountEntriesTestCases(account db.Account) []accountHistoryTestCases {
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+	}
+
+	n := 6
+	entries := make([]db.Entry, n)
+	for i := range entries {
+		entries[i] = db.Entry{
+			ID:         int64(100 - i),
+			AccountID:  sql.NullInt64{Int64: account.ID, Valid: true},
+			Amount:     util.RandomMoney(),
+			TransferID: sql.NullInt64{Int64: int64(util.RandomInt(1, 1000)), Valid: true},
+		}
+	}
+
+	expectList := func(store *mockdb.MockStore, arg db.ListAccountEntriesParams, entries []db.Entry, err error) {
+		expectHistoryAccount(store, account)
+		store.EXPECT().
+			ListAccountEntries(gomock.Any(), gomock.Eq(arg)).
+			Times(1).
+			Return(entries, err)
+	}
+
+	requireEntries := func(t *testing.T, recorder *httptest.ResponseRecorder, entries []db.Entry, cursor string) {
+		expected := listAccountEntriesResponse{
+			Entries:    make([]entryResponse, len(entries)),
+			NextCursor: cursor,
+		}
+		for i, entry := range entries {
+			expected.Entries[i] = newEntryResponse(entry, account.Currency)
+		}
+
+		data, err := json.Marshal(expected)
+		require.NoError(t, err)
+		require.JSONEq(t, string(data), recorder.Body.String())
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []accountHistoryTestCases{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountEntriesParams{
+					AccountID: account.ID,
+					Direction: directionAll,
+					PageSize:  6,
+				}, entries[:2], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireEntries(t, recorder, entries[:2], "")
+			},
+		},
+		{
+			name:      "NextPage",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountEntriesParams{
+					AccountID: account.ID,
+					Direction: directionAll,
+					PageSize:  6,
+				}, entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireEntries(t, recorder, entries[:5], encodeCursor(entries[4].ID))
+			},
+		},
+		{
+			name:      "Filters",
+			accountID: account.ID,
+			query: historyQuery(5,
+				"direction", "out",
+				"from", from.Format(time.RFC3339),
+				"min_amount", "0.01",
+				"cursor", encodeCursor(entries[4].ID),
+			),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountEntriesParams{
+					AccountID: account.ID,
+					Direction: "out",
+					FromTime:  sql.NullTime{Time: from, Valid: true},
+					MinAmount: sql.NullInt64{Int64: 1, Valid: true},
+					BeforeID:  sql.NullInt64{Int64: entries[4].ID, Valid: true},
+					PageSize:  6,
+				}, entries[5:], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireEntries(t, recorder, entries[5:], "")
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     historyQuery(5),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectList(store, db.ListAccountEntriesParams{
+					AccountID: account.ID,
+					Direction: directionAll,
+					PageSize:  6,
+				}, nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	return append(testCases, getAccountHistoryErrorTestCases(account, func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ListAccountEntries(gomock.Any(), gomock.Any()).
+			Times(0)
+	})...)
+}
@@ -8,11 +8,15 @@ import (
 	"io"
 	mockdb "master_class/db/mock"
 	db "master_class/db/sqlc"
+	"master_class/token"
 	"master_class/util"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -20,14 +24,15 @@ import (
 type getOrDeleteAccountTestCases struct {
 	name          string
 	accountID     int64
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	buildStubs    func(store *mockdb.MockStore)
 	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 }
 
 type createAccountTestCases struct {
 	name          string
-	owner         string
 	currency      string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	buildStubs    func(store *mockdb.MockStore)
 	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 }
@@ -36,6 +41,7 @@ type listAccountsTestCases struct {
 	name          string
 	pageID        int32
 	pageSize      int32
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	buildStubs    func(store *mockdb.MockStore)
 	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 }
@@ -43,8 +49,25 @@ type listAccountsTestCases struct {
 type updateAccountTestCases struct {
 	name          string
 	accountID     int64
-	owner         string
 	currency      string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type adminAccountsTestCases struct {
+	name          string
+	url           string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type adminUpdateAccountOverdraftTestCases struct {
+	name          string
+	accountID     int64
+	body          gin.H
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	buildStubs    func(store *mockdb.MockStore)
 	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 }
@@ -63,6 +86,7 @@ func TestGetAccountApi(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -71,6 +95,7 @@ func TestGetAccountApi(t *testing.T) {
 			request, err := http.NewRequest("GET", url, nil)
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -91,17 +116,19 @@ func TestCreateAccountApi(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
-			body, err := json.Marshal(createAccountRequest{Owner: tc.owner, Currency: tc.currency})
+			body, err := json.Marshal(createAccountRequest{Currency: tc.currency})
 			require.NoError(t, err)
 
 			url := "/accounts"
 			request, err := http.NewRequest("POST", url, bytes.NewReader(body))
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -109,7 +136,11 @@ func TestCreateAccountApi(t *testing.T) {
 }
 
 func TestListAccountsApi(t *testing.T) {
+	owner := util.RandomOwner()
 	accounts := []db.Account{randomAccount(nil), randomAccount(nil), randomAccount(nil)}
+	for i := range accounts {
+		accounts[i].Owner = owner
+	}
 
 	testCases := getListAccountsTestCases(accounts)
 
@@ -122,6 +153,7 @@ func TestListAccountsApi(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -130,6 +162,7 @@ func TestListAccountsApi(t *testing.T) {
 			request, err := http.NewRequest("GET", url, nil)
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -138,6 +171,7 @@ func TestListAccountsApi(t *testing.T) {
 
 func TestUpdateAccountApi(t *testing.T) {
 	account := randomAccount(nil)
+	account.Balance = 0
 
 	testCases := getUpdateAccountTestCases(account)
 
@@ -150,17 +184,19 @@ func TestUpdateAccountApi(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
-			body, err := json.Marshal(updateAccountRequest{ID: tc.accountID, Owner: tc.owner, Currency: tc.currency})
+			body, err := json.Marshal(updateAccountRequest{Currency: tc.currency})
 			require.NoError(t, err)
 
 			url := fmt.Sprintf("/accounts/%d", tc.accountID)
 			request, err := http.NewRequest("PATCH", url, bytes.NewReader(body))
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -181,6 +217,7 @@ func TestDeleteAccountApi(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
@@ -189,6 +226,70 @@ func TestDeleteAccountApi(t *testing.T) {
 			request, err := http.NewRequest("DELETE", url, nil)
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminAccountsApi(t *testing.T) {
+	admin := util.RandomOwner()
+	accounts := []db.Account{randomAccount(nil), randomAccount(nil), randomAccount(nil)}
+
+	testCases := getAdminAccountsTestCases(admin, accounts)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminUpdateAccountOverdraftApi(t *testing.T) {
+	admin := util.RandomOwner()
+	currency := util.USD
+	account := randomAccount(&currency)
+
+	testCases := getAdminUpdateAccountOverdraftTestCases(admin, account)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/admin/accounts/%d/overdraft", tc.accountID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -200,6 +301,9 @@ func getGetAccountTestCases(account db.Account) []getOrDeleteAccountTestCases {
 		{
 			name:      "OK",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -214,6 +318,9 @@ func getGetAccountTestCases(account db.Account) []getOrDeleteAccountTestCases {
 		{
 			name:      "Not Found",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -227,6 +334,9 @@ func getGetAccountTestCases(account db.Account) []getOrDeleteAccountTestCases {
 		{
 			name:      "Internal Error",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -240,6 +350,9 @@ func getGetAccountTestCases(account db.Account) []getOrDeleteAccountTestCases {
 		{
 			name:      "Invalid ID",
 			accountID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Any()).
@@ -249,6 +362,35 @@ func getGetAccountTestCases(account db.Account) []getOrDeleteAccountTestCases {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 	}
 }
 
@@ -256,6 +398,9 @@ func getCreateAccountTestCases(account db.Account) []createAccountTestCases {
 	return []createAccountTestCases{
 		{
 			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
@@ -266,7 +411,6 @@ func getCreateAccountTestCases(account db.Account) []createAccountTestCases {
 					Times(1).
 					Return(account, nil)
 			},
-			owner:    account.Owner,
 			currency: account.Currency,
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
@@ -274,57 +418,67 @@ func getCreateAccountTestCases(account db.Account) []createAccountTestCases {
 			},
 		},
 		{
-			name: "Invalid Owner",
+			name: "Invalid Currency",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					CreateAccount(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
-			owner:    "",
-			currency: account.Currency,
+			currency: "INVALID",
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
 		{
-			name: "Invalid Currency",
+			name: "Internal Error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					CreateAccount(gomock.Any(), gomock.Any()).
-					Times(0)
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
 			},
-			owner:    account.Owner,
-			currency: "INVALID",
+			currency: account.Currency,
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
 		{
-			name: "Internal Error",
+			name:      "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					CreateAccount(gomock.Any(), gomock.Any()).
-					Times(1).
-					Return(db.Account{}, sql.ErrConnDone)
+					Times(0)
 			},
-			owner:    account.Owner,
 			currency: account.Currency,
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
 	}
 }
 
 func getListAccountsTestCases(accounts []db.Account) []listAccountsTestCases {
+	owner := accounts[0].Owner
+
 	return []listAccountsTestCases{
 		{
 			name:     "OK",
 			pageID:   1,
 			pageSize: 5,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{
+					ListAccountsByOwner(gomock.Any(), gomock.Eq(db.ListAccountsByOwnerParams{
+						Owner:  owner,
 						Limit:  5,
 						Offset: 0,
 					})).
@@ -340,9 +494,12 @@ func getListAccountsTestCases(accounts []db.Account) []listAccountsTestCases {
 			name:     "Invalid Page ID",
 			pageID:   0,
 			pageSize: 5,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -353,9 +510,12 @@ func getListAccountsTestCases(accounts []db.Account) []listAccountsTestCases {
 			name:     "Invalid Page Size",
 			pageID:   1,
 			pageSize: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -366,9 +526,12 @@ func getListAccountsTestCases(accounts []db.Account) []listAccountsTestCases {
 			name:     "Internal Error",
 			pageID:   1,
 			pageSize: 5,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListAccounts(gomock.Any(), gomock.Any()).
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return([]db.Account{}, sql.ErrConnDone)
 			},
@@ -376,16 +539,36 @@ func getListAccountsTestCases(accounts []db.Account) []listAccountsTestCases {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name:      "NoAuthorization",
+			pageID:    1,
+			pageSize:  5,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 	}
 }
 
 func getUpdateAccountTestCases(account db.Account) []updateAccountTestCases {
+	currency := util.PickOtherCurrency(account.Currency)
+	updated := account
+	updated.Currency = currency
+
 	return []updateAccountTestCases{
 		{
 			name:      "OK",
 			accountID: account.ID,
-			owner:     account.Owner + " updated",
-			currency:  account.Currency,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -395,69 +578,69 @@ func getUpdateAccountTestCases(account db.Account) []updateAccountTestCases {
 				store.EXPECT().
 					UpdateAccount(gomock.Any(), gomock.Eq(db.UpdateAccountParams{
 						ID:       account.ID,
-						Owner:    account.Owner + " updated",
-						Currency: account.Currency,
+						Currency: currency,
 					})).
 					Times(1).
-					Return(db.Account{
-						ID:       account.ID,
-						Owner:    account.Owner + " updated",
-						Balance:  account.Balance,
-						Currency: account.Currency,
-					}, nil)
+					Return(updated, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
-				requireBodyMatchAccount(t, recorder.Body, db.Account{
-					ID:       account.ID,
-					Owner:    account.Owner + " updated",
-					Balance:  account.Balance,
-					Currency: account.Currency,
-				})
+				requireBodyMatchAccount(t, recorder.Body, updated)
 			},
 		},
 		{
-			name:      "Not Found",
+			name:      "SameCurrency",
 			accountID: account.ID,
-			owner:     account.Owner + " updated",
 			currency:  account.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, sql.ErrNoRows)
+					Return(account, nil)
 
 				store.EXPECT().
 					UpdateAccount(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
 		{
-			name:      "Invalid ID",
-			accountID: 0,
-			owner:     account.Owner + " updated",
-			currency:  account.Currency,
+			name:      "NonZeroBalance",
+			accountID: account.ID,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
+				funded := account
+				funded.Balance = util.RandomMoney()
+
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Any()).
-					Times(0)
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(funded, nil)
 
 				store.EXPECT().
 					UpdateAccount(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, http.StatusConflict, recorder.Code)
 			},
 		},
 		{
-			name:      "Update Currency only",
+			name:      "AccountHasEntries",
 			accountID: account.ID,
-			owner:     "",
-			currency:  "EUR",
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -465,69 +648,82 @@ func getUpdateAccountTestCases(account db.Account) []updateAccountTestCases {
 					Return(account, nil)
 
 				store.EXPECT().
-					UpdateAccount(gomock.Any(), gomock.Eq(db.UpdateAccountParams{
-						ID:       account.ID,
-						Owner:    account.Owner,
-						Currency: "EUR",
-					})).
+					UpdateAccount(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.Account{
-						ID:       account.ID,
-						Owner:    account.Owner,
-						Balance:  account.Balance,
-						Currency: "EUR",
-					}, nil)
+					Return(db.Account{}, sql.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
-				requireBodyMatchAccount(t, recorder.Body, db.Account{
-					ID:       account.ID,
-					Owner:    account.Owner,
-					Balance:  account.Balance,
-					Currency: "EUR",
-				})
+				require.Equal(t, http.StatusConflict, recorder.Code)
 			},
 		},
 		{
-			name:      "Update Owner only",
+			name:      "Not Found",
 			accountID: account.ID,
-			owner:     account.Owner + " updated",
-			currency:  "",
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(account, nil)
+					Return(db.Account{}, sql.ErrNoRows)
 
 				store.EXPECT().
-					UpdateAccount(gomock.Any(), gomock.Eq(db.UpdateAccountParams{
-						ID:       account.ID,
-						Owner:    account.Owner + " updated",
-						Currency: account.Currency,
-					})).
-					Times(1).
-					Return(db.Account{
-						ID:       account.ID,
-						Owner:    account.Owner + " updated",
-						Balance:  account.Balance,
-						Currency: account.Currency,
-					}, nil)
+					UpdateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
-				requireBodyMatchAccount(t, recorder.Body, db.Account{
-					ID:       account.ID,
-					Owner:    account.Owner + " updated",
-					Balance:  account.Balance,
-					Currency: account.Currency,
-				})
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "Invalid ID",
+			accountID: 0,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					UpdateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "MissingCurrency",
+			accountID: account.ID,
+			currency:  "",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					UpdateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
 		{
 			name:      "Internal Error - GetAccount",
 			accountID: account.ID,
-			owner:     account.Owner + " updated",
-			currency:  account.Currency,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -545,8 +741,10 @@ func getUpdateAccountTestCases(account db.Account) []updateAccountTestCases {
 		{
 			name:      "Internal Error - UpdateAccount",
 			accountID: account.ID,
-			owner:     account.Owner + " updated",
-			currency:  account.Currency,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -562,6 +760,45 @@ func getUpdateAccountTestCases(account db.Account) []updateAccountTestCases {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					UpdateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			currency:  currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					UpdateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 	}
 }
 
@@ -570,12 +807,20 @@ func getDeleteAccountTestCases(account db.Account) []getOrDeleteAccountTestCases
 		{
 			name:      "OK",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
 					Return(account, nil)
 
+				store.EXPECT().
+					AccountHasEntries(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(false, nil)
+
 				store.EXPECT().
 					DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
@@ -588,6 +833,9 @@ func getDeleteAccountTestCases(account db.Account) []getOrDeleteAccountTestCases
 		{
 			name:      "Not Found",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -605,6 +853,9 @@ func getDeleteAccountTestCases(account db.Account) []getOrDeleteAccountTestCases
 		{
 			name:      "Invalid ID",
 			accountID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Any()).
@@ -621,6 +872,9 @@ func getDeleteAccountTestCases(account db.Account) []getOrDeleteAccountTestCases
 		{
 			name:      "Internal Error - GetAccount",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
@@ -638,12 +892,20 @@ func getDeleteAccountTestCases(account db.Account) []getOrDeleteAccountTestCases
 		{
 			name:      "Internal Error - DeleteAccount",
 			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
 					Return(account, nil)
 
+				store.EXPECT().
+					AccountHasEntries(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(false, nil)
+
 				store.EXPECT().
 					DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
@@ -653,9 +915,442 @@ func getDeleteAccountTestCases(account db.Account) []getOrDeleteAccountTestCases
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
-	}
-}
-
+		{
+			name:      "HasEntries",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					AccountHasEntries(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(true, nil)
+
+				store.EXPECT().
+					DeleteAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "StillReferenced",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					AccountHasEntries(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(false, nil)
+
+				store.EXPECT().
+					DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(&pq.Error{Code: "23503"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "Internal Error - AccountHasEntries",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					AccountHasEntries(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(false, sql.ErrConnDone)
+
+				store.EXPECT().
+					DeleteAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					DeleteAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					DeleteAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+}
+
+func getAdminAccountsTestCases(admin string, accounts []db.Account) []adminAccountsTestCases {
+	asAdmin := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+	}
+
+	return []adminAccountsTestCases{
+		{
+			name:      "ListAll",
+			url:       "/admin/accounts?page_id=1&page_size=5",
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{Limit: 5, Offset: 0})).
+					Times(1).
+					Return(accounts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccounts(t, recorder.Body, accounts)
+			},
+		},
+		{
+			name:      "ListByOwner",
+			url:       fmt.Sprintf("/admin/accounts?owner=%s&page_id=2&page_size=5", accounts[0].Owner),
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Eq(db.ListAccountsByOwnerParams{
+						Owner:  accounts[0].Owner,
+						Limit:  5,
+						Offset: 5,
+					})).
+					Times(1).
+					Return(accounts[:1], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccounts(t, recorder.Body, accounts[:1])
+			},
+		},
+		{
+			name:      "ListInvalidPageSize",
+			url:       "/admin/accounts?page_id=1&page_size=50",
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "GetAnyAccount",
+			url:       fmt.Sprintf("/admin/accounts/%d", accounts[1].ID),
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(accounts[1].ID)).
+					Times(1).
+					Return(accounts[1], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, accounts[1])
+			},
+		},
+		{
+			name:      "GetNotFound",
+			url:       fmt.Sprintf("/admin/accounts/%d", accounts[1].ID),
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(accounts[1].ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "TellerGetAnyAccount",
+			url:  fmt.Sprintf("/admin/accounts/%d", accounts[1].ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.TellerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.TellerRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(accounts[1].ID)).
+					Times(1).
+					Return(accounts[1], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, accounts[1])
+			},
+		},
+		{
+			name: "CustomerForbidden",
+			url:  fmt.Sprintf("/admin/accounts/%d", accounts[1].ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.CustomerRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			url:       "/admin/accounts?page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+}
+
+func getAdminUpdateAccountOverdraftTestCases(admin string, account db.Account) []adminUpdateAccountOverdraftTestCases {
+	asAdmin := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+	}
+
+	updated := account
+	updated.OverdraftLimit = 25000
+
+	return []adminUpdateAccountOverdraftTestCases{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "250.00"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Eq(db.UpdateAccountOverdraftLimitParams{
+						ID:             account.ID,
+						OverdraftLimit: 25000,
+					})).
+					Times(1).
+					Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, updated)
+			},
+		},
+		{
+			name:      "RemoveOverdraft",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "0"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(updated, nil)
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Eq(db.UpdateAccountOverdraftLimitParams{
+						ID:             account.ID,
+						OverdraftLimit: 0,
+					})).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:      "NegativeLimit",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "-1.00"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidLimit",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "1.005"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "MissingLimit",
+			accountID: account.ID,
+			body:      gin.H{},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "250.00"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "250.00"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "TellerForbidden",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "250.00"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.TellerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.TellerRole)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			body:      gin.H{"overdraft_limit": "250.00"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+}
+
 func randomAccount(currency *string) db.Account {
 	var accountCurrency string
 
@@ -677,29 +1372,16 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccount db.Account
-	err = json.Unmarshal(data, &gotAccount)
+	expected, err := json.Marshal(newAccountResponse(account))
 	require.NoError(t, err)
-	require.Equal(t, account, gotAccount)
+	require.JSONEq(t, string(expected), string(data))
 }
 
 func requireBodyMatchAccounts(t *testing.T, body *bytes.Buffer, accounts []db.Account) {
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccounts []db.Account
-	err = json.Unmarshal(data, &gotAccounts)
+	expected, err := json.Marshal(newAccountsResponse(accounts))
 	require.NoError(t, err)
-
-	var hit int
-	for i := range accounts {
-		for j := range gotAccounts {
-			if accounts[i].ID == gotAccounts[j].ID {
-				require.Equal(t, accounts[i], gotAccounts[j])
-				hit++
-			}
-		}
-	}
-
-	require.Equal(t, len(accounts), hit)
+	require.JSONEq(t, string(expected), string(data))
 }
@@ -0,0 +1,13 @@
+package api
+
+import (
+	"master_class/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listCurrencies returns the enabled currencies, so clients know how to scale their amounts
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, util.Currencies().Enabled())
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	db "master_class/db/sqlc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+var (
+	errIdempotencyKeyTooLong = errors.New("idempotency key must be at most 255 characters")
+	errIdempotencyKeyReused  = errors.New("idempotency key was already used for a different request")
+)
+
+// requestFingerprint hashes the route together with the bound request,
+// so the same key cannot be replayed for a different request
+func requestFingerprint(route string, req any) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(append([]byte(route+"\n"), data...))
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// idempotencyKey reads the Idempotency-Key header, responding with 400 when it is invalid
+func idempotencyKey(ctx *gin.Context) (string, bool) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIdempotencyKeyTooLong))
+		return "", false
+	}
+
+	return key, true
+}
+
+// replayIdempotentRequest answers a request whose idempotency key was already used:
+// with the saved response, rendered by render, and its status when the request is the same,
+// with 409 otherwise.
+// It returns false without responding when the key has not been used yet.
+func (server *Server) replayIdempotentRequest(
+	ctx *gin.Context,
+	username string,
+	key string,
+	requestHash string,
+	render func(response json.RawMessage) (any, error),
+) bool {
+	record, err := server.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: username,
+		Key:      key,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if record.RequestHash != requestHash {
+		ctx.JSON(http.StatusConflict, errorResponse(errIdempotencyKeyReused))
+		return true
+	}
+
+	rsp, err := render(record.Response)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	ctx.Header(idempotentReplayedHeader, "true")
+	ctx.JSON(int(record.StatusCode), rsp)
+
+	return true
+}
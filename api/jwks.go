@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"master_class/token"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksMaxAge bounds how long clients cache the key set, so rotated keys show up quickly
+const jwksMaxAge = 15 * time.Minute
+
+// keySetPublisher is implemented by token makers able to publish their verification keys
+type keySetPublisher interface {
+	JSONWebKeySet() token.JSONWebKeySet
+}
+
+func (server *Server) getJWKS(ctx *gin.Context) {
+	keySet := token.JSONWebKeySet{Keys: []token.JSONWebKey{}}
+	if publisher, ok := server.tokenMaker.(keySetPublisher); ok {
+		keySet = publisher.JSONWebKeySet()
+	}
+
+	data, err := json.Marshal(keySet)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16]))
+
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	ctx.Header("ETag", etag)
+
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/jwk-set+json", data)
+}
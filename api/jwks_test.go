@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetJWKSAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	_, activePrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	previousPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	config := util.Config{
+		TokenType:            token.TypePasetoPublic,
+		TokenKeyID:           "k2",
+		TokenPrivateKey:      hex.EncodeToString(activePrivateKey),
+		TokenPreviousKeys:    fmt.Sprintf("k1:%s", hex.EncodeToString(previousPublicKey)),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Header().Get("Cache-Control"), "max-age=")
+	require.NotEmpty(t, recorder.Header().Get("ETag"))
+
+	var keySet token.JSONWebKeySet
+	err = json.Unmarshal(recorder.Body.Bytes(), &keySet)
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 2)
+	require.Equal(t, "k2", keySet.Keys[0].KeyID)
+	require.Equal(t, "k1", keySet.Keys[1].KeyID)
+
+	// the published keys verify the tokens of the server
+	verifier, err := token.NewJWKSVerifier(keySet)
+	require.NoError(t, err)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(util.RandomOwner(), util.CustomerRole, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(accessToken)
+	require.NoError(t, err)
+
+	// a matching ETag is answered without a body
+	etag := recorder.Header().Get("ETag")
+	recorder = httptest.NewRecorder()
+	request.Header.Set("If-None-Match", etag)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Empty(t, recorder.Body.Bytes())
+}
+
+func TestGetJWKSSymmetricKeyAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"keys":[]}`, recorder.Body.String())
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	db "master_class/db/sqlc"
+	"master_class/token"
 	"master_class/util"
 	"os"
 	"testing"
@@ -13,13 +14,19 @@ import (
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		FXRates:              "EUR/USD:1.25",
+		FXSpread:             "0.01",
 	}
 
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
 
+	server.revocations = token.NewMemoryRevocationStore()
+	server.setupRouter()
+
 	return server
 }
 
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxRateUploadSize bounds the body of a rate upload
+const maxRateUploadSize = 1 << 20
+
+type listRatesRequest struct {
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// listRates returns the rate of every pair effective now, or at the time given in the query
+func (server *Server) listRates(ctx *gin.Context) {
+	var req listRatesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	at := req.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	rates, err := server.store.ListExchangeRates(ctx, at)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rates)
+}
+
+type rateUpload struct {
+	FromCurrency string      `json:"from_currency" binding:"required,currency"`
+	ToCurrency   string      `json:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+	Rate         json.Number `json:"rate" binding:"required"`
+	// ValidFrom defaults to the time of the upload
+	ValidFrom time.Time `json:"valid_from"`
+	// Source defaults to the admin uploading the rate
+	Source string `json:"source"`
+}
+
+type uploadRatesRequest struct {
+	Rates []rateUpload `json:"rates" binding:"required,min=1,max=1000,dive"`
+}
+
+// adminUploadRates saves a batch of rates sent as JSON, or as CSV with a header row
+// naming the from_currency, to_currency and rate columns, and optionally valid_from and source
+func (server *Server) adminUploadRates(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxRateUploadSize)
+
+	var req uploadRatesRequest
+	var err error
+
+	switch ctx.ContentType() {
+	case binding.MIMEJSON:
+		err = ctx.ShouldBindJSON(&req)
+	case "text/csv":
+		req.Rates, err = parseRatesCSV(ctx.Request.Body)
+		if err == nil {
+			err = binding.Validator.ValidateStruct(&req)
+		}
+	default:
+		err := fmt.Errorf("unsupported content type %q: rates must be sent as %s or text/csv", ctx.ContentType(), binding.MIMEJSON)
+		ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(err))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	now := time.Now()
+
+	args := make([]db.CreateExchangeRateParams, len(req.Rates))
+	for i, upload := range req.Rates {
+		arg, err := newCreateExchangeRateParams(upload, authPayload.Username, now)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("rate %d: %w", i+1, err)))
+			return
+		}
+
+		args[i] = arg
+	}
+
+	rates, err := server.store.CreateExchangeRatesTx(ctx, args)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rates)
+}
+
+func newCreateExchangeRateParams(upload rateUpload, username string, now time.Time) (db.CreateExchangeRateParams, error) {
+	rate, err := util.ParseDecimal(upload.Rate.String())
+	if err != nil {
+		return db.CreateExchangeRateParams{}, err
+	}
+
+	// round to the precision of the column before checking the rate is usable
+	rate.SetString(rate.FloatString(10))
+	if rate.Sign() == 0 {
+		return db.CreateExchangeRateParams{}, errors.New("rate must be positive")
+	}
+
+	arg := db.CreateExchangeRateParams{
+		FromCurrency: upload.FromCurrency,
+		ToCurrency:   upload.ToCurrency,
+		Rate:         rate.FloatString(10),
+		ValidFrom:    upload.ValidFrom,
+		Source:       upload.Source,
+	}
+
+	if arg.ValidFrom.IsZero() {
+		arg.ValidFrom = now
+	}
+
+	if arg.Source == "" {
+		arg.Source = "admin:" + username
+	}
+
+	return arg, nil
+}
+
+// parseRatesCSV decodes rates from CSV. The header row names the columns, in any order.
+func parseRatesCSV(r io.Reader) ([]rateUpload, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "from_currency", "to_currency", "rate", "valid_from", "source":
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+
+		columns[name] = i
+	}
+
+	for _, name := range []string{"from_currency", "to_currency", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rates []rateUpload
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		upload := rateUpload{
+			FromCurrency: field(record, "from_currency"),
+			ToCurrency:   field(record, "to_currency"),
+			Rate:         json.Number(field(record, "rate")),
+			Source:       field(record, "source"),
+		}
+
+		if validFrom := field(record, "valid_from"); validFrom != "" {
+			upload.ValidFrom, err = time.Parse(time.RFC3339, validFrom)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid valid_from: %w", line, err)
+			}
+		}
+
+		rates = append(rates, upload)
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type EqGetExchangeRateParamsMatcher struct {
+	fromCurrency string
+	toCurrency   string
+}
+
+func (e EqGetExchangeRateParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.GetExchangeRateParams)
+	if !ok {
+		return false
+	}
+
+	return arg.FromCurrency == e.fromCurrency && arg.ToCurrency == e.toCurrency && !arg.At.IsZero()
+}
+
+func (e EqGetExchangeRateParamsMatcher) String() string {
+	return fmt.Sprintf("matches pair %s/%s", e.fromCurrency, e.toCurrency)
+}
+
+// EqGetExchangeRateParams matches the lookup of a pair at any time
+func EqGetExchangeRateParams(fromCurrency string, toCurrency string) gomock.Matcher {
+	return EqGetExchangeRateParamsMatcher{fromCurrency: fromCurrency, toCurrency: toCurrency}
+}
+
+type listRatesTestCases struct {
+	name          string
+	query         string
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type uploadRatesTestCases struct {
+	name          string
+	contentType   string
+	body          string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestListRatesApi(t *testing.T) {
+	user := util.RandomOwner()
+
+	testCases := getListRatesTestCases()
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/rates"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminUploadRatesApi(t *testing.T) {
+	admin := util.RandomOwner()
+
+	testCases := getUploadRatesTestCases(admin)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUserWithRole(store, admin, util.AdminRole)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/rates", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", tc.contentType)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func getListRatesTestCases() []listRatesTestCases {
+	rates := []db.ExchangeRate{
+		{ID: 1, FromCurrency: util.EUR, ToCurrency: util.USD, Rate: "1.0800000000", Source: "feed"},
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return []listRatesTestCases{
+		{
+			name:  "OK",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExchangeRates(gomock.Any(), gomock.Any()).
+					Return(rates, nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"rate":"1.0800000000"`)
+			},
+		},
+		{
+			name:  "AtTime",
+			query: "?at=" + at.Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExchangeRates(gomock.Any(), gomock.Eq(at)).
+					Return(rates, nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidTime",
+			query: "?at=yesterday",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExchangeRates(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExchangeRates(gomock.Any(), gomock.Any()).
+					Return(nil, sql.ErrConnDone).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+func getUploadRatesTestCases(admin string) []uploadRatesTestCases {
+	validFrom := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	asAdmin := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+	}
+
+	expectNoUpload := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CreateExchangeRatesTx(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	return []uploadRatesTestCases{
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"rates": [{"from_currency": "EUR", "to_currency": "USD", "rate": 1.08, "valid_from": "2024-01-02T00:00:00Z", "source": "ecb"}]}`,
+			setupAuth:   asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateExchangeRatesTx(gomock.Any(), gomock.Eq([]db.CreateExchangeRateParams{
+						{FromCurrency: util.EUR, ToCurrency: util.USD, Rate: "1.0800000000", ValidFrom: validFrom, Source: "ecb"},
+					})).
+					Return([]db.ExchangeRate{{ID: 1}}, nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:        "CSV",
+			contentType: "text/csv",
+			body:        "to_currency,from_currency,rate,valid_from\nUSD,EUR,1.08,2024-01-02T00:00:00Z\nCAD,USD,\"1.36\",2024-01-02T00:00:00Z\n",
+			setupAuth:   asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateExchangeRatesTx(gomock.Any(), gomock.Eq([]db.CreateExchangeRateParams{
+						{FromCurrency: util.EUR, ToCurrency: util.USD, Rate: "1.0800000000", ValidFrom: validFrom, Source: "admin:" + admin},
+						{FromCurrency: util.USD, ToCurrency: util.CAD, Rate: "1.3600000000", ValidFrom: validFrom, Source: "admin:" + admin},
+					})).
+					Return([]db.ExchangeRate{{ID: 1}, {ID: 2}}, nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:        "CSVMissingColumn",
+			contentType: "text/csv",
+			body:        "from_currency,to_currency\nEUR,USD\n",
+			setupAuth:   asAdmin,
+			buildStubs:  expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "rate")
+			},
+		},
+		{
+			name:        "CSVInvalidValidFrom",
+			contentType: "text/csv",
+			body:        "from_currency,to_currency,rate,valid_from\nEUR,USD,1.08,tomorrow\n",
+			setupAuth:   asAdmin,
+			buildStubs:  expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "line 2")
+			},
+		},
+		{
+			name:        "SameCurrency",
+			contentType: "application/json",
+			body:        `{"rates": [{"from_currency": "EUR", "to_currency": "EUR", "rate": 1}]}`,
+			setupAuth:   asAdmin,
+			buildStubs:  expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:        "ZeroRate",
+			contentType: "application/json",
+			body:        `{"rates": [{"from_currency": "EUR", "to_currency": "USD", "rate": "0.00000000001"}]}`,
+			setupAuth:   asAdmin,
+			buildStubs:  expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "rate 1")
+			},
+		},
+		{
+			name:        "EmptyUpload",
+			contentType: "application/json",
+			body:        `{"rates": []}`,
+			setupAuth:   asAdmin,
+			buildStubs:  expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:        "UnsupportedContentType",
+			contentType: "text/plain",
+			body:        "EUR/USD 1.08",
+			setupAuth:   asAdmin,
+			buildStubs:  expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+			},
+		},
+		{
+			name:        "NotAdmin",
+			contentType: "application/json",
+			body:        `{"rates": [{"from_currency": "EUR", "to_currency": "USD", "rate": 1.08}]}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: expectNoUpload,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:        "InternalError",
+			contentType: "application/json",
+			body:        `{"rates": [{"from_currency": "EUR", "to_currency": "USD", "rate": 1.08}]}`,
+			setupAuth:   asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateExchangeRatesTx(gomock.Any(), gomock.Any()).
+					Return(nil, sql.ErrConnDone).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
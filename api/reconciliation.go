@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	db "master_class/db/sqlc"
+	"master_class/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errRepairNotConfirmed = errors.New("repair writes adjustment entries, set confirm to true to go ahead")
+
+type balanceDriftResponse struct {
+	AccountID    int64      `json:"account_id"`
+	Balance      util.Money `json:"balance"`
+	EntriesTotal util.Money `json:"entries_total"`
+	Drift        util.Money `json:"drift"`
+	Currency     string     `json:"currency"`
+	// AdjustmentEntryID is set once a repair has made up the drift
+	AdjustmentEntryID *int64 `json:"adjustment_entry_id,omitempty"`
+}
+
+type unbalancedTransferResponse struct {
+	TransferID    int64 `json:"transfer_id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	EntryCount    int32 `json:"entry_count"`
+	DebitCount    int32 `json:"debit_count"`
+	CreditCount   int32 `json:"credit_count"`
+}
+
+type reconciliationResponse struct {
+	AccountsChecked     int64                        `json:"accounts_checked"`
+	TransfersChecked    int64                        `json:"transfers_checked"`
+	BalanceDrifts       []balanceDriftResponse       `json:"balance_drifts"`
+	UnbalancedTransfers []unbalancedTransferResponse `json:"unbalanced_transfers"`
+}
+
+func newReconciliationResponse(report db.ReconciliationReport) reconciliationResponse {
+	rsp := reconciliationResponse{
+		AccountsChecked:     report.AccountsChecked,
+		TransfersChecked:    report.TransfersChecked,
+		BalanceDrifts:       make([]balanceDriftResponse, len(report.BalanceDrifts)),
+		UnbalancedTransfers: make([]unbalancedTransferResponse, len(report.UnbalancedTransfers)),
+	}
+
+	for i, drift := range report.BalanceDrifts {
+		rsp.BalanceDrifts[i] = balanceDriftResponse{
+			AccountID:    drift.AccountID,
+			Balance:      util.NewMoney(drift.Balance, drift.Currency),
+			EntriesTotal: util.NewMoney(drift.EntriesTotal, drift.Currency),
+			Drift:        util.NewMoney(drift.Drift(), drift.Currency),
+			Currency:     drift.Currency,
+		}
+
+		if drift.AdjustmentEntryID != 0 {
+			rsp.BalanceDrifts[i].AdjustmentEntryID = &report.BalanceDrifts[i].AdjustmentEntryID
+		}
+	}
+
+	for i, transfer := range report.UnbalancedTransfers {
+		rsp.UnbalancedTransfers[i] = unbalancedTransferResponse{
+			TransferID:    transfer.TransferID,
+			FromAccountID: transfer.FromAccountID,
+			ToAccountID:   transfer.ToAccountID,
+			EntryCount:    transfer.EntryCount,
+			DebitCount:    transfer.DebitCount,
+			CreditCount:   transfer.CreditCount,
+		}
+	}
+
+	return rsp
+}
+
+type adminReconcileRequest struct {
+	BatchSize int32 `form:"batch_size" binding:"omitempty,min=1,max=10000"`
+}
+
+// adminReconcile reports the drift between the balances and the entries of the ledger, without changing anything
+func (server *Server) adminReconcile(ctx *gin.Context) {
+	var req adminReconcileRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	report, err := server.store.Reconcile(ctx, db.ReconcileParams{
+		BatchSize: req.BatchSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newReconciliationResponse(report))
+}
+
+type adminRepairLedgerRequest struct {
+	BatchSize int32 `json:"batch_size" binding:"omitempty,min=1,max=10000"`
+	Confirm   bool  `json:"confirm"`
+}
+
+// adminRepairLedger reconciles the ledger and makes up each balance drift with an adjustment entry.
+// It refuses to write anything unless the request confirms it.
+func (server *Server) adminRepairLedger(ctx *gin.Context) {
+	var req adminRepairLedgerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.Confirm {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errRepairNotConfirmed))
+		return
+	}
+
+	report, err := server.store.Reconcile(ctx, db.ReconcileParams{
+		BatchSize: req.BatchSize,
+		Repair:    true,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newReconciliationResponse(report))
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type reconciliationTestCases struct {
+	name          string
+	method        string
+	url           string
+	body          string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestAdminReconciliationApi(t *testing.T) {
+	admin := util.RandomOwner()
+
+	testCases := getReconciliationTestCases(admin)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUserWithRole(store, admin, util.AdminRole)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func getReconciliationTestCases(admin string) []reconciliationTestCases {
+	asAdmin := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+	}
+
+	report := db.ReconciliationReport{
+		AccountsChecked:  10,
+		TransfersChecked: 20,
+		BalanceDrifts: []db.BalanceDrift{
+			{AccountID: 1, Currency: util.USD, Balance: 1000, EntriesTotal: 750},
+		},
+		UnbalancedTransfers: []db.UnbalancedTransfer{
+			{
+				TransferID:    7,
+				FromAccountID: 1,
+				ToAccountID:   2,
+				EntryCount:    1,
+				DebitCount:    1,
+			},
+		},
+	}
+
+	repaired := report
+	repaired.BalanceDrifts = []db.BalanceDrift{report.BalanceDrifts[0]}
+	repaired.BalanceDrifts[0].AdjustmentEntryID = 42
+
+	expectReconcile := func(store *mockdb.MockStore, arg db.ReconcileParams, report db.ReconciliationReport, err error) {
+		store.EXPECT().
+			Reconcile(gomock.Any(), gomock.Eq(arg)).
+			Times(1).
+			Return(report, err)
+	}
+
+	expectNoReconcile := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			Reconcile(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	requireReport := func(t *testing.T, recorder *httptest.ResponseRecorder, report db.ReconciliationReport) {
+		expected, err := json.Marshal(newReconciliationResponse(report))
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), recorder.Body.String())
+	}
+
+	return []reconciliationTestCases{
+		{
+			name:      "Report",
+			method:    http.MethodGet,
+			url:       "/admin/reconciliation",
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReconcile(store, db.ReconcileParams{}, report, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireReport(t, recorder, report)
+				require.Contains(t, recorder.Body.String(), `"drift":"2.50"`)
+				require.NotContains(t, recorder.Body.String(), "adjustment_entry_id")
+			},
+		},
+		{
+			name:      "ReportBatchSize",
+			method:    http.MethodGet,
+			url:       "/admin/reconciliation?batch_size=100",
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReconcile(store, db.ReconcileParams{BatchSize: 100}, report, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "ReportInvalidBatchSize",
+			method:     http.MethodGet,
+			url:        "/admin/reconciliation?batch_size=20000",
+			setupAuth:  asAdmin,
+			buildStubs: expectNoReconcile,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "ReportInternalError",
+			method:    http.MethodGet,
+			url:       "/admin/reconciliation",
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReconcile(store, db.ReconcileParams{}, db.ReconciliationReport{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:   "ReportNotAdmin",
+			method: http.MethodGet,
+			url:    "/admin/reconciliation",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: expectNoReconcile,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "Repair",
+			method:    http.MethodPost,
+			url:       "/admin/reconciliation/repair",
+			body:      `{"confirm": true}`,
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReconcile(store, db.ReconcileParams{Repair: true}, repaired, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireReport(t, recorder, repaired)
+				require.Contains(t, recorder.Body.String(), `"adjustment_entry_id":42`)
+			},
+		},
+		{
+			name:       "RepairNotConfirmed",
+			method:     http.MethodPost,
+			url:        "/admin/reconciliation/repair",
+			body:       `{"batch_size": 100}`,
+			setupAuth:  asAdmin,
+			buildStubs: expectNoReconcile,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "confirm")
+			},
+		},
+		{
+			name:       "RepairInvalidBatchSize",
+			method:     http.MethodPost,
+			url:        "/admin/reconciliation/repair",
+			body:       `{"confirm": true, "batch_size": -1}`,
+			setupAuth:  asAdmin,
+			buildStubs: expectNoReconcile,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "RepairNotAdmin",
+			method: http.MethodPost,
+			url:    "/admin/reconciliation/repair",
+			body:   `{"confirm": true}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: expectNoReconcile,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+}
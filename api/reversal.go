@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errReversalNotAllowed = errors.New("only the owner of the destination account can reverse the transfer")
+
+type reverseTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// Amount is a decimal in the currency of the destination account, such as "12.34".
+	// It refunds all that is left of the transfer when empty.
+	Amount util.Decimal `json:"amount"`
+}
+
+// reverseTransfer refunds a transfer received by the authenticated user, in whole or in part.
+// The refund is a new transfer back to the source account, linked to the reversed one.
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri reverseTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the body is optional, a full refund needs none
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	toAccount, err := server.store.GetAccount(ctx, transfer.ToAccountID.Int64)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errReversalNotAllowed))
+		return
+	}
+
+	arg := db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+	}
+
+	if req.Amount != "" {
+		amount, valid := positiveAmount(ctx, req.Amount, toAccount.Currency)
+		if !valid {
+			return
+		}
+
+		arg.Amount = amount.Amount
+	}
+
+	key, valid := idempotencyKey(ctx)
+	if !valid {
+		return
+	}
+
+	if key != "" {
+		requestHash, err := requestFingerprint("POST /transfers/:id/reverse", struct {
+			ID int64 `json:"id"`
+			reverseTransferRequest
+		}{transfer.ID, req})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if server.replayIdempotentRequest(ctx, authPayload.Username, key, requestHash, renderTransferTxResult) {
+			return
+		}
+
+		arg.Idempotency = &db.TransferIdempotency{
+			Username:    authPayload.Username,
+			Key:         key,
+			RequestHash: requestHash,
+			StatusCode:  http.StatusOK,
+		}
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) {
+			// a concurrent retry won the race, answer with its outcome
+			if !server.replayIdempotentRequest(ctx, arg.Idempotency.Username, arg.Idempotency.Key, arg.Idempotency.RequestHash, renderTransferTxResult) {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			}
+			return
+		}
+
+		var fundsErr *db.InsufficientFundsError
+		if errors.As(err, &fundsErr) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(fundsErr))
+			return
+		}
+
+		if errors.Is(err, db.ErrReversalNotReversible) ||
+			errors.Is(err, db.ErrRefundExceedsTransfer) ||
+			errors.Is(err, db.ErrConvertedTooSmall) ||
+			errors.Is(err, util.ErrMoneyOverflow) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferTxResponse(result))
+}
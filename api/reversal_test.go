@@ -0,0 +1,286 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type reverseTransferTestCases struct {
+	name          string
+	transferID    int64
+	body          string
+	key           string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestReverseTransferApi(t *testing.T) {
+	account_sender := randomAccount(nil)
+
+	currency := account_sender.Currency
+	account_receiver := randomAccount(&currency)
+
+	testCases := getReverseTransferTestCases(t, account_sender, account_receiver)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d/reverse", tc.transferID)
+			request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			if tc.key != "" {
+				request.Header.Set(idempotencyKeyHeader, tc.key)
+			}
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func getReverseTransferTestCases(t *testing.T, account_sender db.Account, account_receiver db.Account) []reverseTransferTestCases {
+	original := randomTransferTxResult(account_sender, account_receiver, 1000, 1000).Transfer
+
+	reversal := randomTransferTxResult(account_receiver, account_sender, 250, 250)
+	reversal.Transfer.ReversalOf = sql.NullInt64{Int64: original.ID, Valid: true}
+
+	asReceiver := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_receiver.Owner, util.CustomerRole, time.Minute)
+	}
+
+	expectTransfer := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetTransfer(gomock.Any(), gomock.Eq(original.ID)).
+			Times(1).
+			Return(original, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account_receiver.ID)).
+			Times(1).
+			Return(account_receiver, nil)
+	}
+
+	expectReversal := func(store *mockdb.MockStore, arg db.ReverseTransferTxParams, err error) {
+		expectTransfer(store)
+		store.EXPECT().
+			ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+			Times(1).
+			Return(reversal, err)
+	}
+
+	expectNoReversal := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ReverseTransferTx(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	key := util.RandomString(16)
+	requestHash, err := requestFingerprint("POST /transfers/:id/reverse", struct {
+		ID int64 `json:"id"`
+		reverseTransferRequest
+	}{original.ID, reverseTransferRequest{Amount: "2.50"}})
+	require.NoError(t, err)
+
+	return []reverseTransferTestCases{
+		{
+			name:       "FullRefund",
+			transferID: original.ID,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), fmt.Sprintf(`"reversal_of":%d`, original.ID))
+			},
+		},
+		{
+			name:       "PartialRefund",
+			transferID: original.ID,
+			body:       `{"amount": "2.50"}`,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID, Amount: 250}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"amount":"2.50"`)
+			},
+		},
+		{
+			name:       "IdempotencyKey",
+			transferID: original.ID,
+			body:       `{"amount": "2.50"}`,
+			key:        key,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: account_receiver.Owner, Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				expectReversal(store, db.ReverseTransferTxParams{
+					TransferID: original.ID,
+					Amount:     250,
+					Idempotency: &db.TransferIdempotency{
+						Username:    account_receiver.Owner,
+						Key:         key,
+						RequestHash: requestHash,
+						StatusCode:  http.StatusOK,
+					},
+				}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: original.ID,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(original.ID)).
+					Times(1).
+					Return(db.Transfer{}, sql.ErrNoRows)
+				expectNoReversal(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "SenderCannotReverse",
+			transferID: original.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectTransfer(store)
+				expectNoReversal(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "NegativeAmount",
+			transferID: original.ID,
+			body:       `{"amount": "-1.00"}`,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectTransfer(store)
+				expectNoReversal(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "TooManyDecimals",
+			transferID: original.ID,
+			body:       `{"amount": "1.234"}`,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectTransfer(store)
+				expectNoReversal(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "RefundExceedsTransfer",
+			transferID: original.ID,
+			body:       `{"amount": "20.00"}`,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID, Amount: 2000}, db.ErrRefundExceedsTransfer)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:       "ReversalNotReversible",
+			transferID: original.ID,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID}, db.ErrReversalNotReversible)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:       "InsufficientFunds",
+			transferID: original.ID,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID}, &db.InsufficientFundsError{AccountID: account_receiver.ID})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:       "CreditedOverflow",
+			transferID: original.ID,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID}, util.ErrMoneyOverflow)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:       "InternalError",
+			transferID: original.ID,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectReversal(store, db.ReverseTransferTxParams{TransferID: original.ID}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			setupAuth:  asReceiver,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+}
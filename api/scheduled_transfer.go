@@ -0,0 +1,212 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errExecuteAtNotInFuture        = errors.New("execute_at must be in the future")
+	errScheduledTransferNotOwned   = errors.New("scheduled transfer doesn't belong to the authenticated user")
+	errScheduledTransferNotPending = errors.New("only pending scheduled transfers can be cancelled")
+)
+
+type scheduledTransferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        util.Money `json:"amount"`
+	Currency      string     `json:"currency"`
+	ExecuteAt     time.Time  `json:"execute_at"`
+	Status        string     `json:"status"`
+	TransferID    *int64     `json:"transfer_id,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	ExecutedAt    *time.Time `json:"executed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func newScheduledTransferResponse(scheduled db.ScheduledTransfer) scheduledTransferResponse {
+	rsp := scheduledTransferResponse{
+		ID:            scheduled.ID,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        util.NewMoney(scheduled.Amount, scheduled.Currency),
+		Currency:      scheduled.Currency,
+		ExecuteAt:     scheduled.ExecuteAt,
+		Status:        scheduled.Status,
+		FailureReason: scheduled.FailureReason.String,
+		CreatedAt:     scheduled.CreatedAt,
+	}
+
+	if scheduled.TransferID.Valid {
+		rsp.TransferID = &scheduled.TransferID.Int64
+	}
+
+	if scheduled.ExecutedAt.Valid {
+		rsp.ExecutedAt = &scheduled.ExecutedAt.Time
+	}
+
+	return rsp
+}
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	// Amount is a decimal in the currency, such as "12.34"
+	Amount    util.Decimal `json:"amount" binding:"required"`
+	Currency  string       `json:"currency" binding:"required,currency"`
+	ExecuteAt time.Time    `json:"execute_at" binding:"required"`
+}
+
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	amount, valid := positiveAmount(ctx, req.Amount, req.Currency)
+	if !valid {
+		return
+	}
+
+	if !req.ExecuteAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errExecuteAtNotInFuture))
+		return
+	}
+
+	fromAccount, valid := server.validateAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	_, valid = server.validateAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	scheduled, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        amount.Amount,
+		Currency:      amount.Currency,
+		ExecuteAt:     req.ExecuteAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newScheduledTransferResponse(scheduled))
+}
+
+type scheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownedScheduledTransfer loads the scheduled transfer of the URI,
+// responding with an error unless it belongs to the authenticated user
+func (server *Server) ownedScheduledTransfer(ctx *gin.Context) (db.ScheduledTransfer, bool) {
+	var req scheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	scheduled, err := server.store.GetScheduledTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduled, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduled, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduled.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errScheduledTransferNotOwned))
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduled, valid := server.ownedScheduledTransfer(ctx)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=20"`
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferResponse, len(scheduledTransfers))
+	for i, scheduled := range scheduledTransfers {
+		rsp[i] = newScheduledTransferResponse(scheduled)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// cancelScheduledTransfer cancels a transfer that has not been executed yet.
+// The row is kept, so the owner can still see what was cancelled.
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	scheduled, valid := server.ownedScheduledTransfer(ctx)
+	if !valid {
+		return
+	}
+
+	scheduled, err := server.store.CancelScheduledTransfer(ctx, scheduled.ID)
+	if err != nil {
+		// the transfer was already executed or cancelled
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errScheduledTransferNotPending))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduled))
+}
@@ -0,0 +1,421 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type createScheduledTransferTestCases struct {
+	name          string
+	body          gin.H
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type scheduledTransferTestCases struct {
+	name          string
+	method        string
+	scheduledID   int64
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestCreateScheduledTransferApi(t *testing.T) {
+	account_sender := randomAccount(nil)
+
+	currency := account_sender.Currency
+	account_receiver := randomAccount(&currency)
+
+	testCases := getCreateScheduledTransferTestCases(account_sender, account_receiver)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestScheduledTransferApi(t *testing.T) {
+	scheduled := randomScheduledTransfer()
+
+	testCases := getScheduledTransferTestCases(scheduled)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", tc.scheduledID)
+			request, err := http.NewRequest(tc.method, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListScheduledTransfersApi(t *testing.T) {
+	scheduled := randomScheduledTransfer()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListScheduledTransfers(gomock.Any(), gomock.Eq(db.ListScheduledTransfersParams{
+			Owner:  scheduled.Owner,
+			Limit:  5,
+			Offset: 5,
+		})).
+		Times(1).
+		Return([]db.ScheduledTransfer{scheduled}, nil)
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/scheduled_transfers?page_id=2&page_size=5", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, scheduled.Owner, util.CustomerRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	requireBodyMatchScheduledTransfers(t, recorder.Body, []db.ScheduledTransfer{scheduled})
+}
+
+func getCreateScheduledTransferTestCases(account_sender db.Account, account_receiver db.Account) []createScheduledTransferTestCases {
+	executeAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	body := func(amount string, executeAt time.Time) gin.H {
+		return gin.H{
+			"from_account_id": account_sender.ID,
+			"to_account_id":   account_receiver.ID,
+			"amount":          amount,
+			"currency":        account_sender.Currency,
+			"execute_at":      executeAt,
+		}
+	}
+
+	expectAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_sender.ID).
+			Return(account_sender, nil).
+			Times(1)
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_receiver.ID).
+			Return(account_receiver, nil).
+			Times(1)
+	}
+
+	return []createScheduledTransferTestCases{
+		{
+			name: "OK",
+			body: body("12.50", executeAt),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Eq(db.CreateScheduledTransferParams{
+						Owner:         account_sender.Owner,
+						FromAccountID: account_sender.ID,
+						ToAccountID:   account_receiver.ID,
+						Amount:        1250,
+						Currency:      account_sender.Currency,
+						ExecuteAt:     executeAt,
+					})).
+					Times(1).
+					Return(db.ScheduledTransfer{
+						ID:            1,
+						Owner:         account_sender.Owner,
+						FromAccountID: account_sender.ID,
+						ToAccountID:   account_receiver.ID,
+						Amount:        1250,
+						Currency:      account_sender.Currency,
+						ExecuteAt:     executeAt,
+						Status:        db.ScheduledTransferPending,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"amount":"12.50"`)
+				require.Contains(t, recorder.Body.String(), `"status":"pending"`)
+				require.NotContains(t, recorder.Body.String(), "transfer_id")
+			},
+		},
+		{
+			name: "ExecuteAtInPast",
+			body: body("12.50", time.Now().Add(-time.Minute)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAmount",
+			body: body("-1.00", executeAt),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "SameAccount",
+			body: gin.H{
+				"from_account_id": account_sender.ID,
+				"to_account_id":   account_sender.ID,
+				"amount":          "1.00",
+				"currency":        account_sender.Currency,
+				"execute_at":      executeAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotOwned",
+			body: gin.H{
+				"from_account_id": account_receiver.ID,
+				"to_account_id":   account_sender.ID,
+				"amount":          "1.00",
+				"currency":        account_sender.Currency,
+				"execute_at":      executeAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), account_receiver.ID).
+					Return(account_receiver, nil).
+					Times(1)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body("12.50", executeAt),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+func getScheduledTransferTestCases(scheduled db.ScheduledTransfer) []scheduledTransferTestCases {
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, scheduled.Owner, util.CustomerRole, time.Minute)
+	}
+
+	expectGet := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+			Times(1).
+			Return(scheduled, nil)
+	}
+
+	cancelled := scheduled
+	cancelled.Status = db.ScheduledTransferCancelled
+
+	return []scheduledTransferTestCases{
+		{
+			name:        "Get",
+			method:      http.MethodGet,
+			scheduledID: scheduled.ID,
+			setupAuth:   asOwner,
+			buildStubs:  expectGet,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchScheduledTransfer(t, recorder.Body, scheduled)
+			},
+		},
+		{
+			name:        "GetNotFound",
+			method:      http.MethodGet,
+			scheduledID: scheduled.ID,
+			setupAuth:   asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:        "GetNotOwned",
+			method:      http.MethodGet,
+			scheduledID: scheduled.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: expectGet,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:        "Cancel",
+			method:      http.MethodDelete,
+			scheduledID: scheduled.ID,
+			setupAuth:   asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				store.EXPECT().
+					CancelScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).
+					Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchScheduledTransfer(t, recorder.Body, cancelled)
+			},
+		},
+		{
+			name:        "CancelNotPending",
+			method:      http.MethodDelete,
+			scheduledID: scheduled.ID,
+			setupAuth:   asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				store.EXPECT().
+					CancelScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:        "CancelNotOwned",
+			method:      http.MethodDelete,
+			scheduledID: scheduled.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				store.EXPECT().
+					CancelScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:        "InvalidID",
+			method:      http.MethodDelete,
+			scheduledID: 0,
+			setupAuth:   asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+}
+
+func randomScheduledTransfer() db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:            int64(util.RandomInt(1, 1000)),
+		Owner:         util.RandomOwner(),
+		FromAccountID: int64(util.RandomInt(1, 1000)),
+		ToAccountID:   int64(util.RandomInt(1001, 2000)),
+		Amount:        util.RandomMoney() + 1,
+		Currency:      util.RandomCurrency(),
+		ExecuteAt:     time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+		Status:        db.ScheduledTransferPending,
+	}
+}
+
+func requireBodyMatchScheduledTransfer(t *testing.T, body *bytes.Buffer, scheduled db.ScheduledTransfer) {
+	expected, err := json.Marshal(newScheduledTransferResponse(scheduled))
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), body.String())
+}
+
+func requireBodyMatchScheduledTransfers(t *testing.T, body *bytes.Buffer, scheduledTransfers []db.ScheduledTransfer) {
+	rsp := make([]scheduledTransferResponse, len(scheduledTransfers))
+	for i, scheduled := range scheduledTransfers {
+		rsp[i] = newScheduledTransferResponse(scheduled)
+	}
+
+	expected, err := json.Marshal(rsp)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), body.String())
+}
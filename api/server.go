@@ -5,6 +5,7 @@ import (
 	db "master_class/db/sqlc"
 	"master_class/token"
 	"master_class/util"
+	"math/big"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -12,42 +13,111 @@ import (
 )
 
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations token.RevocationStore
+	rates       util.RateProvider
+	fxSpread    string
+	router      *gin.Engine
 }
 
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+
+	// rates uploaded to the database take precedence over the configured ones
+	configuredRates, err := util.NewRateProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate provider: %w", err)
+	}
+
+	fxSpread, err := util.ParseDecimal(config.FXSpread)
+	if err != nil || fxSpread.Cmp(big.NewRat(1, 1)) >= 0 {
+		return nil, fmt.Errorf("invalid FX spread %q: must be a decimal between 0 and 1", config.FXSpread)
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: db.NewPostgresRevocationStore(store),
+		rates:       db.NewExchangeRateProvider(store, configuredRates),
+		fxSpread:    fxSpread.FloatString(6),
 	}
-	router := gin.Default()
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
 	}
 
+	server.setupRouter()
+
+	return server, nil
+}
+
+func (server *Server) setupRouter() {
+	router := gin.Default()
+
 	router.POST("/users", server.createUser)
-	router.POST("/users/:username/password", server.changePassword)
+	router.POST("/users/login", server.loginUser)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/.well-known/jwks.json", server.getJWKS)
+	router.GET("/currencies", server.listCurrencies)
 
-	router.POST("/accounts", server.createAccount)
-	router.GET("/accounts/:id", server.getAccount)
-	router.PATCH("/accounts/:id", server.updateAccount)
-	router.DELETE("/accounts/:id", server.deleteAccount)
-	router.GET("/accounts", server.listAccounts)
+	authRoutes := router.Group("/", authMiddleware(server.tokenMaker, server.revocations, server.store))
 
-	router.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/:username/password", server.changePassword)
+	authRoutes.POST("/users/:username/sessions/revoke_all", server.revokeAllSessions)
 
-	server.router = router
+	authRoutes.POST("/accounts", server.createAccount)
+	authRoutes.GET("/accounts/:id", server.getAccount)
+	authRoutes.PATCH("/accounts/:id", server.updateAccount)
+	authRoutes.DELETE("/accounts/:id", server.deleteAccount)
+	authRoutes.GET("/accounts/:id/transfers", server.listAccountTransfers)
+	authRoutes.GET("/accounts/:id/entries", server.listAccountEntries)
+	authRoutes.GET("/accounts/:id/statement", server.getAccountStatement)
+	authRoutes.GET("/accounts", server.listAccounts)
 
-	return server, nil
+	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
+
+	authRoutes.POST("/transfer_batches", server.createTransferBatch)
+	authRoutes.GET("/transfer_batches/:id", server.getTransferBatch)
+
+	authRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", server.listScheduledTransfers)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.cancelScheduledTransfer)
+
+	authRoutes.POST("/standing_orders", server.createStandingOrder)
+	authRoutes.GET("/standing_orders/:id", server.getStandingOrder)
+	authRoutes.GET("/standing_orders", server.listStandingOrders)
+	authRoutes.PATCH("/standing_orders/:id", server.updateStandingOrder)
+	authRoutes.DELETE("/standing_orders/:id", server.cancelStandingOrder)
+	authRoutes.POST("/standing_orders/:id/pause", server.pauseStandingOrder)
+	authRoutes.POST("/standing_orders/:id/resume", server.resumeStandingOrder)
+	authRoutes.GET("/standing_orders/:id/runs", server.listStandingOrderRuns)
+
+	authRoutes.GET("/rates", server.listRates)
+
+	// tellers look up the accounts of any customer, changes are left to admins
+	backOfficeRoutes := authRoutes.Group("/admin", roleMiddleware(util.AdminRole, util.TellerRole))
+
+	backOfficeRoutes.GET("/accounts/:id", server.adminGetAccount)
+	backOfficeRoutes.GET("/accounts", server.adminListAccounts)
+
+	adminRoutes := authRoutes.Group("/admin", roleMiddleware(util.AdminRole))
+
+	adminRoutes.PUT("/users/:username/role", server.adminUpdateUserRole)
+	adminRoutes.PUT("/accounts/:id/overdraft", server.adminUpdateAccountOverdraft)
+	adminRoutes.POST("/rates", server.adminUploadRates)
+	adminRoutes.GET("/reconciliation", server.adminReconcile)
+	adminRoutes.POST("/reconciliation/repair", server.adminRepairLedger)
+
+	server.router = router
 }
 
 func (server *Server) Start(address string) error {
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type logoutUserTestCases struct {
+	name          string
+	createToken   func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload)
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore, payload *token.Payload)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type revokeAllSessionsTestCases struct {
+	name          string
+	username      string
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestLogoutUserAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := getLogoutUserTestCases(username)
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, payload := tc.createToken(t, server.tokenMaker)
+			tc.buildStubs(store, payload)
+			expectAuthUser(store)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(logoutUserRequest{RefreshToken: refreshToken})
+			require.NoError(t, err)
+
+			url := "/users/logout"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+
+			if recorder.Code == http.StatusOK {
+				revoked, err := server.revocations.IsRevoked(request.Context(), payload)
+				require.NoError(t, err)
+				require.True(t, revoked)
+			}
+		})
+	}
+}
+
+func TestLogoutUserRevokesAccessToken(t *testing.T) {
+	username := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	refreshToken, _, err := server.tokenMaker.CreateToken(username, util.CustomerRole, token.RefreshToken, time.Hour)
+	require.NoError(t, err)
+
+	store.EXPECT().
+		BlockSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.Session{Username: username, IsBlocked: true}, nil)
+
+	store.EXPECT().
+		ListAccountsByOwner(gomock.Any(), gomock.Any()).
+		Times(0)
+	expectAuthUser(store)
+
+	data, err := json.Marshal(logoutUserRequest{RefreshToken: refreshToken})
+	require.NoError(t, err)
+
+	logoutRequest, err := http.NewRequest(http.MethodPost, "/users/logout", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, logoutRequest, server.tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+	accessHeader := logoutRequest.Header.Get(authorizationHeaderKey)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, logoutRequest)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	listRequest, err := http.NewRequest(http.MethodGet, "/accounts?page_id=1&page_size=5", nil)
+	require.NoError(t, err)
+	listRequest.Header.Set(authorizationHeaderKey, accessHeader)
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, listRequest)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRevokeAllSessionsAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := getRevokeAllSessionsTestCases(username)
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/%s/sessions/revoke_all", tc.username)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+
+			if recorder.Code == http.StatusOK {
+				// the token used for the call was issued before the revocation
+				recorder = httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			}
+		})
+	}
+}
+
+func getLogoutUserTestCases(username string) []logoutUserTestCases {
+	validToken := func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+		refreshToken, payload, err := tokenMaker.CreateToken(username, util.CustomerRole, token.RefreshToken, time.Hour)
+		require.NoError(t, err)
+
+		return refreshToken, payload
+	}
+
+	return []logoutUserTestCases{
+		{
+			name:        "OK",
+			createToken: validToken,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{ID: payload.ID, Username: username, IsBlocked: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:        "NoAuthorization",
+			createToken: validToken,
+			setupAuth:   func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRefreshToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return "invalid-token", nil
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:        "RefreshTokenOfAnotherUser",
+			createToken: validToken,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:        "SessionNotFound",
+			createToken: validToken,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:        "InternalError",
+			createToken: validToken,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+func getRevokeAllSessionsTestCases(username string) []revokeAllSessionsTestCases {
+	return []revokeAllSessionsTestCases{
+		{
+			name:     "OK",
+			username: username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			username:  username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "AnotherUser",
+			username: username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
@@ -0,0 +1,484 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errStartAtNotInFuture        = errors.New("start_at must be in the future")
+	errStandingOrderNotOwned     = errors.New("standing order doesn't belong to the authenticated user")
+	errStandingOrderNoOccurrence = errors.New("standing order ends before its first occurrence")
+)
+
+// standingOrderStatusError is returned when the status of a standing order doesn't allow an action
+type standingOrderStatusError struct {
+	action string
+	status string
+}
+
+func (e *standingOrderStatusError) Error() string {
+	return fmt.Sprintf("cannot %s a %s standing order", e.action, e.status)
+}
+
+func isStandingOrderEnded(order *db.StandingOrder) bool {
+	return order.Status == db.StandingOrderCompleted || order.Status == db.StandingOrderCancelled
+}
+
+type standingOrderResponse struct {
+	ID                      int64      `json:"id"`
+	FromAccountID           int64      `json:"from_account_id"`
+	ToAccountID             int64      `json:"to_account_id"`
+	Amount                  util.Money `json:"amount"`
+	Currency                string     `json:"currency"`
+	Frequency               string     `json:"frequency"`
+	Interval                int32      `json:"interval"`
+	DayOfMonth              *int32     `json:"day_of_month,omitempty"`
+	StartAt                 time.Time  `json:"start_at"`
+	EndAt                   *time.Time `json:"end_at,omitempty"`
+	MaxOccurrences          *int32     `json:"max_occurrences,omitempty"`
+	InsufficientFundsPolicy string     `json:"insufficient_funds_policy"`
+	Status                  string     `json:"status"`
+	Occurrences             int32      `json:"occurrences"`
+	RetryCount              int32      `json:"retry_count"`
+	NextRunAt               *time.Time `json:"next_run_at,omitempty"`
+	CreatedAt               time.Time  `json:"created_at"`
+}
+
+func newStandingOrderResponse(order db.StandingOrder) standingOrderResponse {
+	rsp := standingOrderResponse{
+		ID:                      order.ID,
+		FromAccountID:           order.FromAccountID,
+		ToAccountID:             order.ToAccountID,
+		Amount:                  util.NewMoney(order.Amount, order.Currency),
+		Currency:                order.Currency,
+		Frequency:               order.Frequency,
+		Interval:                order.IntervalCount,
+		StartAt:                 order.StartAt,
+		InsufficientFundsPolicy: order.InsufficientFundsPolicy,
+		Status:                  order.Status,
+		Occurrences:             order.Occurrences,
+		RetryCount:              order.RetryCount,
+		CreatedAt:               order.CreatedAt,
+	}
+
+	if order.DayOfMonth.Valid {
+		rsp.DayOfMonth = &order.DayOfMonth.Int32
+	}
+
+	if order.EndAt.Valid {
+		rsp.EndAt = &order.EndAt.Time
+	}
+
+	if order.MaxOccurrences.Valid {
+		rsp.MaxOccurrences = &order.MaxOccurrences.Int32
+	}
+
+	if order.NextRunAt.Valid {
+		rsp.NextRunAt = &order.NextRunAt.Time
+	}
+
+	return rsp
+}
+
+type standingOrderRunResponse struct {
+	ID            int64     `json:"id"`
+	Occurrence    int32     `json:"occurrence"`
+	ScheduledAt   time.Time `json:"scheduled_at"`
+	Status        string    `json:"status"`
+	TransferID    *int64    `json:"transfer_id,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newStandingOrderRunResponse(run db.StandingOrderRun) standingOrderRunResponse {
+	rsp := standingOrderRunResponse{
+		ID:            run.ID,
+		Occurrence:    run.Occurrence,
+		ScheduledAt:   run.ScheduledAt,
+		Status:        run.Status,
+		FailureReason: run.FailureReason.String,
+		CreatedAt:     run.CreatedAt,
+	}
+
+	if run.TransferID.Valid {
+		rsp.TransferID = &run.TransferID.Int64
+	}
+
+	return rsp
+}
+
+type createStandingOrderRequest struct {
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	// Amount is a decimal in the currency, such as "12.34"
+	Amount    util.Decimal `json:"amount" binding:"required"`
+	Currency  string       `json:"currency" binding:"required,currency"`
+	Frequency string       `json:"frequency" binding:"required,oneof=daily weekly monthly"`
+	// Interval is the number of days, weeks or months between occurrences, 1 by default
+	Interval int32 `json:"interval" binding:"omitempty,min=1,max=366"`
+	// DayOfMonth pins monthly occurrences to a day, the day of StartAt by default
+	DayOfMonth              int32      `json:"day_of_month" binding:"omitempty,min=1,max=31"`
+	StartAt                 time.Time  `json:"start_at" binding:"required"`
+	EndAt                   *time.Time `json:"end_at"`
+	MaxOccurrences          int32      `json:"max_occurrences" binding:"omitempty,min=1"`
+	InsufficientFundsPolicy string     `json:"insufficient_funds_policy" binding:"omitempty,oneof=skip retry suspend"`
+}
+
+func (server *Server) createStandingOrder(ctx *gin.Context) {
+	var req createStandingOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	amount, valid := positiveAmount(ctx, req.Amount, req.Currency)
+	if !valid {
+		return
+	}
+
+	if !req.StartAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errStartAtNotInFuture))
+		return
+	}
+
+	arg := db.CreateStandingOrderParams{
+		FromAccountID:           req.FromAccountID,
+		ToAccountID:             req.ToAccountID,
+		Amount:                  amount.Amount,
+		Currency:                amount.Currency,
+		Frequency:               req.Frequency,
+		IntervalCount:           req.Interval,
+		DayOfMonth:              sql.NullInt32{Int32: req.DayOfMonth, Valid: req.DayOfMonth != 0},
+		StartAt:                 req.StartAt,
+		MaxOccurrences:          sql.NullInt32{Int32: req.MaxOccurrences, Valid: req.MaxOccurrences != 0},
+		InsufficientFundsPolicy: req.InsufficientFundsPolicy,
+	}
+
+	if arg.IntervalCount == 0 {
+		arg.IntervalCount = 1
+	}
+
+	if req.EndAt != nil {
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+
+	if arg.InsufficientFundsPolicy == "" {
+		arg.InsufficientFundsPolicy = db.SkipOnInsufficientFunds
+	}
+
+	order := db.StandingOrder{
+		Frequency:      arg.Frequency,
+		IntervalCount:  arg.IntervalCount,
+		DayOfMonth:     arg.DayOfMonth,
+		StartAt:        arg.StartAt,
+		EndAt:          arg.EndAt,
+		MaxOccurrences: arg.MaxOccurrences,
+	}
+
+	recurrence := order.Recurrence()
+	if err := recurrence.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	firstRunAt, ok := recurrence.Next(0)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errStandingOrderNoOccurrence))
+		return
+	}
+
+	arg.NextRunAt = sql.NullTime{Time: firstRunAt, Valid: true}
+
+	fromAccount, valid := server.validateAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	_, valid = server.validateAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	arg.Owner = authPayload.Username
+
+	order, err := server.store.CreateStandingOrder(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newStandingOrderResponse(order))
+}
+
+type standingOrderRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownedStandingOrder loads the standing order of the URI,
+// responding with an error unless it belongs to the authenticated user
+func (server *Server) ownedStandingOrder(ctx *gin.Context) (db.StandingOrder, bool) {
+	var req standingOrderRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.StandingOrder{}, false
+	}
+
+	order, err := server.store.GetStandingOrder(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return order, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return order, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if order.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errStandingOrderNotOwned))
+		return order, false
+	}
+
+	return order, true
+}
+
+// changeStandingOrder applies update to the standing order, which is locked meanwhile,
+// and responds with the updated order
+func (server *Server) changeStandingOrder(ctx *gin.Context, id int64, update func(order *db.StandingOrder) error) {
+	order, err := server.store.UpdateStandingOrderTx(ctx, id, update)
+	if err != nil {
+		var statusErr *standingOrderStatusError
+		if errors.As(err, &statusErr) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newStandingOrderResponse(order))
+}
+
+func (server *Server) getStandingOrder(ctx *gin.Context) {
+	order, valid := server.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newStandingOrderResponse(order))
+}
+
+type listStandingOrdersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=20"`
+}
+
+func (server *Server) listStandingOrders(ctx *gin.Context) {
+	var req listStandingOrdersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	orders, err := server.store.ListStandingOrders(ctx, db.ListStandingOrdersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]standingOrderResponse, len(orders))
+	for i, order := range orders {
+		rsp[i] = newStandingOrderResponse(order)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateStandingOrderRequest struct {
+	// Amount is a decimal in the currency of the order, such as "12.34"
+	Amount                  util.Decimal `json:"amount"`
+	EndAt                   *time.Time   `json:"end_at"`
+	MaxOccurrences          int32        `json:"max_occurrences" binding:"omitempty,min=1"`
+	InsufficientFundsPolicy string       `json:"insufficient_funds_policy" binding:"omitempty,oneof=skip retry suspend"`
+}
+
+// updateStandingOrder changes the amount, the end or the insufficient funds policy of the order.
+// The recurrence itself cannot change, as the occurrences already run are counted against it.
+func (server *Server) updateStandingOrder(ctx *gin.Context) {
+	order, valid := server.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	var req updateStandingOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var amount util.Money
+	if req.Amount != "" {
+		var valid bool
+		amount, valid = positiveAmount(ctx, req.Amount, order.Currency)
+		if !valid {
+			return
+		}
+	}
+
+	apply := func(order *db.StandingOrder) {
+		if req.Amount != "" {
+			order.Amount = amount.Amount
+		}
+
+		if req.EndAt != nil {
+			order.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+		}
+
+		if req.MaxOccurrences != 0 {
+			order.MaxOccurrences = sql.NullInt32{Int32: req.MaxOccurrences, Valid: true}
+		}
+
+		if req.InsufficientFundsPolicy != "" {
+			order.InsufficientFundsPolicy = req.InsufficientFundsPolicy
+		}
+	}
+
+	// the recurrence of the order cannot change meanwhile, so it can be checked beforehand
+	apply(&order)
+	if err := order.Recurrence().Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.changeStandingOrder(ctx, order.ID, func(order *db.StandingOrder) error {
+		if isStandingOrderEnded(order) {
+			return &standingOrderStatusError{action: "update", status: order.Status}
+		}
+
+		apply(order)
+
+		// an earlier end may leave no occurrence to run
+		order.ScheduleFrom(time.Time{})
+		return nil
+	})
+}
+
+// pauseStandingOrder stops the runs of an active order until it is resumed
+func (server *Server) pauseStandingOrder(ctx *gin.Context) {
+	order, valid := server.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	server.changeStandingOrder(ctx, order.ID, func(order *db.StandingOrder) error {
+		if order.Status != db.StandingOrderActive {
+			return &standingOrderStatusError{action: "pause", status: order.Status}
+		}
+
+		order.Status = db.StandingOrderPaused
+		return nil
+	})
+}
+
+// resumeStandingOrder reactivates a paused or suspended order. The occurrences
+// that fell while it was not active are skipped, including the one it was suspended on.
+func (server *Server) resumeStandingOrder(ctx *gin.Context) {
+	order, valid := server.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	server.changeStandingOrder(ctx, order.ID, func(order *db.StandingOrder) error {
+		if order.Status != db.StandingOrderPaused && order.Status != db.StandingOrderSuspended {
+			return &standingOrderStatusError{action: "resume", status: order.Status}
+		}
+
+		order.Status = db.StandingOrderActive
+		order.RetryCount = 0
+		order.ScheduleFrom(time.Now())
+		return nil
+	})
+}
+
+// cancelStandingOrder ends the order for good. The row is kept with its run history.
+func (server *Server) cancelStandingOrder(ctx *gin.Context) {
+	order, valid := server.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	server.changeStandingOrder(ctx, order.ID, func(order *db.StandingOrder) error {
+		if isStandingOrderEnded(order) {
+			return &standingOrderStatusError{action: "cancel", status: order.Status}
+		}
+
+		order.Status = db.StandingOrderCancelled
+		order.RetryCount = 0
+		order.NextRunAt = sql.NullTime{}
+		return nil
+	})
+}
+
+type listStandingOrderRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=20"`
+}
+
+// listStandingOrderRuns returns the run history of the order, latest first
+func (server *Server) listStandingOrderRuns(ctx *gin.Context) {
+	order, valid := server.ownedStandingOrder(ctx)
+	if !valid {
+		return
+	}
+
+	var req listStandingOrderRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	runs, err := server.store.ListStandingOrderRuns(ctx, db.ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           req.PageSize,
+		Offset:          (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]standingOrderRunResponse, len(runs))
+	for i, run := range runs {
+		rsp[i] = newStandingOrderRunResponse(run)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,729 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type createStandingOrderTestCases struct {
+	name          string
+	body          gin.H
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type standingOrderTestCases struct {
+	name          string
+	method        string
+	path          string
+	body          gin.H
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestCreateStandingOrderApi(t *testing.T) {
+	account_sender := randomAccount(nil)
+
+	currency := account_sender.Currency
+	account_receiver := randomAccount(&currency)
+
+	testCases := getCreateStandingOrderTestCases(account_sender, account_receiver)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/standing_orders", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStandingOrderApi(t *testing.T) {
+	order := randomStandingOrder()
+
+	testCases := getStandingOrderTestCases(order)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body []byte
+			if tc.body != nil {
+				var err error
+				body, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListStandingOrdersApi(t *testing.T) {
+	order := randomStandingOrder()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListStandingOrders(gomock.Any(), gomock.Eq(db.ListStandingOrdersParams{
+			Owner:  order.Owner,
+			Limit:  5,
+			Offset: 5,
+		})).
+		Times(1).
+		Return([]db.StandingOrder{order}, nil)
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/standing_orders?page_id=2&page_size=5", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, order.Owner, util.CustomerRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	expected, err := json.Marshal([]standingOrderResponse{newStandingOrderResponse(order)})
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), recorder.Body.String())
+}
+
+func TestListStandingOrderRunsApi(t *testing.T) {
+	order := randomStandingOrder()
+	runs := []db.StandingOrderRun{
+		{
+			ID:              2,
+			StandingOrderID: order.ID,
+			Occurrence:      1,
+			ScheduledAt:     order.StartAt.AddDate(0, 0, 14),
+			Status:          db.StandingOrderRunSkipped,
+			FailureReason:   sql.NullString{String: "insufficient funds", Valid: true},
+		},
+		{
+			ID:              1,
+			StandingOrderID: order.ID,
+			Occurrence:      0,
+			ScheduledAt:     order.StartAt,
+			Status:          db.StandingOrderRunSucceeded,
+			TransferID:      sql.NullInt64{Int64: 7, Valid: true},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+		Times(1).
+		Return(order, nil)
+	store.EXPECT().
+		ListStandingOrderRuns(gomock.Any(), gomock.Eq(db.ListStandingOrderRunsParams{
+			StandingOrderID: order.ID,
+			Limit:           5,
+			Offset:          0,
+		})).
+		Times(1).
+		Return(runs, nil)
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/standing_orders/%d/runs?page_id=1&page_size=5", order.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, order.Owner, util.CustomerRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []standingOrderRunResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 2)
+	require.Equal(t, db.StandingOrderRunSkipped, rsp[0].Status)
+	require.Equal(t, "insufficient funds", rsp[0].FailureReason)
+	require.Nil(t, rsp[0].TransferID)
+	require.Equal(t, int64(7), *rsp[1].TransferID)
+}
+
+func getCreateStandingOrderTestCases(account_sender db.Account, account_receiver db.Account) []createStandingOrderTestCases {
+	startAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	endAt := startAt.AddDate(0, 3, 0)
+
+	body := func(fields gin.H) gin.H {
+		body := gin.H{
+			"from_account_id": account_sender.ID,
+			"to_account_id":   account_receiver.ID,
+			"amount":          "100.00",
+			"currency":        account_sender.Currency,
+			"frequency":       util.WeeklyFrequency,
+			"interval":        2,
+			"start_at":        startAt,
+			"end_at":          endAt,
+		}
+
+		for key, value := range fields {
+			body[key] = value
+		}
+
+		return body
+	}
+
+	expectAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_sender.ID).
+			Return(account_sender, nil).
+			Times(1)
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_receiver.ID).
+			Return(account_receiver, nil).
+			Times(1)
+	}
+
+	expectNoCreate := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CreateStandingOrder(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	return []createStandingOrderTestCases{
+		{
+			name: "OK",
+			body: body(gin.H{"insufficient_funds_policy": db.RetryOnInsufficientFunds}),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+
+				arg := db.CreateStandingOrderParams{
+					Owner:                   account_sender.Owner,
+					FromAccountID:           account_sender.ID,
+					ToAccountID:             account_receiver.ID,
+					Amount:                  10000,
+					Currency:                account_sender.Currency,
+					Frequency:               util.WeeklyFrequency,
+					IntervalCount:           2,
+					StartAt:                 startAt,
+					EndAt:                   sql.NullTime{Time: endAt, Valid: true},
+					InsufficientFundsPolicy: db.RetryOnInsufficientFunds,
+					NextRunAt:               sql.NullTime{Time: startAt, Valid: true},
+				}
+
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.StandingOrder{
+						ID:                      1,
+						Owner:                   arg.Owner,
+						FromAccountID:           arg.FromAccountID,
+						ToAccountID:             arg.ToAccountID,
+						Amount:                  arg.Amount,
+						Currency:                arg.Currency,
+						Frequency:               arg.Frequency,
+						IntervalCount:           arg.IntervalCount,
+						StartAt:                 arg.StartAt,
+						EndAt:                   arg.EndAt,
+						InsufficientFundsPolicy: arg.InsufficientFundsPolicy,
+						Status:                  db.StandingOrderActive,
+						NextRunAt:               arg.NextRunAt,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"amount":"100.00"`)
+				require.Contains(t, recorder.Body.String(), `"status":"active"`)
+				require.NotContains(t, recorder.Body.String(), "max_occurrences")
+			},
+		},
+		{
+			name: "MonthlyDefaults",
+			body: gin.H{
+				"from_account_id": account_sender.ID,
+				"to_account_id":   account_receiver.ID,
+				"amount":          "5",
+				"currency":        account_sender.Currency,
+				"frequency":       util.MonthlyFrequency,
+				"day_of_month":    31,
+				"start_at":        startAt,
+				"max_occurrences": 12,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+
+				order := db.StandingOrder{
+					Frequency:      util.MonthlyFrequency,
+					IntervalCount:  1,
+					DayOfMonth:     sql.NullInt32{Int32: 31, Valid: true},
+					StartAt:        startAt,
+					MaxOccurrences: sql.NullInt32{Int32: 12, Valid: true},
+				}
+				firstRunAt := order.Recurrence().Occurrence(0)
+
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Eq(db.CreateStandingOrderParams{
+						Owner:                   account_sender.Owner,
+						FromAccountID:           account_sender.ID,
+						ToAccountID:             account_receiver.ID,
+						Amount:                  500,
+						Currency:                account_sender.Currency,
+						Frequency:               order.Frequency,
+						IntervalCount:           order.IntervalCount,
+						DayOfMonth:              order.DayOfMonth,
+						StartAt:                 startAt,
+						MaxOccurrences:          order.MaxOccurrences,
+						InsufficientFundsPolicy: db.SkipOnInsufficientFunds,
+						NextRunAt:               sql.NullTime{Time: firstRunAt, Valid: true},
+					})).
+					Times(1).
+					Return(db.StandingOrder{ID: 1, Amount: 500, Currency: account_sender.Currency}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:       "StartAtInPast",
+			body:       body(gin.H{"start_at": time.Now().Add(-time.Minute)}),
+			buildStubs: expectNoCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidFrequency",
+			body:       body(gin.H{"frequency": "yearly"}),
+			buildStubs: expectNoCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "DayOfMonthNotMonthly",
+			body:       body(gin.H{"day_of_month": 15}),
+			buildStubs: expectNoCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidPolicy",
+			body:       body(gin.H{"insufficient_funds_policy": "ignore"}),
+			buildStubs: expectNoCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "EndsBeforeStart",
+			body:       body(gin.H{"end_at": startAt.Add(-time.Hour)}),
+			buildStubs: expectNoCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoOccurrenceBeforeEnd",
+			// the first of the month after the start falls past the end
+			body: body(gin.H{
+				"frequency":    util.MonthlyFrequency,
+				"interval":     1,
+				"day_of_month": 1,
+				"start_at":     time.Date(startAt.Year()+1, time.March, 2, 0, 0, 0, 0, time.UTC),
+				"end_at":       time.Date(startAt.Year()+1, time.March, 20, 0, 0, 0, 0, time.UTC),
+			}),
+			buildStubs: expectNoCreate,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotOwned",
+			body: body(gin.H{
+				"from_account_id": account_receiver.ID,
+				"to_account_id":   account_sender.ID,
+			}),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), account_receiver.ID).
+					Return(account_receiver, nil).
+					Times(1)
+				expectNoCreate(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body(nil),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+func getStandingOrderTestCases(order db.StandingOrder) []standingOrderTestCases {
+	path := fmt.Sprintf("/standing_orders/%d", order.ID)
+
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, order.Owner, util.CustomerRole, time.Minute)
+	}
+
+	asOther := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+	}
+
+	expectGet := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+			Times(1).
+			Return(order, nil)
+	}
+
+	// expectUpdate applies the update to current, as the store does to the locked row
+	expectUpdate := func(store *mockdb.MockStore, current db.StandingOrder) {
+		expectGet(store)
+		store.EXPECT().
+			UpdateStandingOrderTx(gomock.Any(), gomock.Eq(order.ID), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, _ int64, update func(order *db.StandingOrder) error) (db.StandingOrder, error) {
+				err := update(&current)
+				return current, err
+			})
+	}
+
+	expectNoUpdate := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			UpdateStandingOrderTx(gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	// the amount is decoded as the decimal string it is sent as
+	type standingOrderBody struct {
+		Amount                  string     `json:"amount"`
+		InsufficientFundsPolicy string     `json:"insufficient_funds_policy"`
+		Status                  string     `json:"status"`
+		Occurrences             int32      `json:"occurrences"`
+		NextRunAt               *time.Time `json:"next_run_at"`
+	}
+
+	requireStatus := func(t *testing.T, recorder *httptest.ResponseRecorder, status string) standingOrderBody {
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var rsp standingOrderBody
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+		require.Equal(t, status, rsp.Status)
+		return rsp
+	}
+
+	completed := order
+	completed.Status = db.StandingOrderCompleted
+	completed.NextRunAt = sql.NullTime{}
+
+	// paused for a while, so the first occurrences were missed
+	paused := order
+	paused.StartAt = time.Now().AddDate(0, 0, -20).UTC().Truncate(time.Second)
+	paused.Status = db.StandingOrderPaused
+	paused.Occurrences = 0
+	paused.NextRunAt = sql.NullTime{Time: paused.StartAt, Valid: true}
+
+	return []standingOrderTestCases{
+		{
+			name:       "Get",
+			method:     http.MethodGet,
+			path:       path,
+			setupAuth:  asOwner,
+			buildStubs: expectGet,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				expected, err := json.Marshal(newStandingOrderResponse(order))
+				require.NoError(t, err)
+				require.JSONEq(t, string(expected), recorder.Body.String())
+			},
+		},
+		{
+			name:      "GetNotFound",
+			method:    http.MethodGet,
+			path:      path,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "GetNotOwned",
+			method:     http.MethodGet,
+			path:       path,
+			setupAuth:  asOther,
+			buildStubs: expectGet,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "Update",
+			method:    http.MethodPatch,
+			path:      path,
+			body:      gin.H{"amount": "42.10", "insufficient_funds_policy": db.SuspendOnInsufficientFunds},
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, order)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireStatus(t, recorder, db.StandingOrderActive)
+				require.Equal(t, "42.10", rsp.Amount)
+				require.Equal(t, db.SuspendOnInsufficientFunds, rsp.InsufficientFundsPolicy)
+				require.Equal(t, order.NextRunAt.Time, *rsp.NextRunAt)
+			},
+		},
+		{
+			name:      "UpdateEndCompletes",
+			method:    http.MethodPatch,
+			path:      path,
+			body:      gin.H{"max_occurrences": order.Occurrences},
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, order)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireStatus(t, recorder, db.StandingOrderCompleted)
+				require.Nil(t, rsp.NextRunAt)
+			},
+		},
+		{
+			name:      "UpdateEndBeforeStart",
+			method:    http.MethodPatch,
+			path:      path,
+			body:      gin.H{"end_at": order.StartAt.Add(-time.Hour)},
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				expectNoUpdate(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "UpdateEnded",
+			method:    http.MethodPatch,
+			path:      path,
+			body:      gin.H{"amount": "1.00"},
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, completed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "Pause",
+			method:    http.MethodPost,
+			path:      path + "/pause",
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, order)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireStatus(t, recorder, db.StandingOrderPaused)
+			},
+		},
+		{
+			name:      "PauseNotActive",
+			method:    http.MethodPost,
+			path:      path + "/pause",
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, paused)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "PauseNotOwned",
+			method:    http.MethodPost,
+			path:      path + "/pause",
+			setupAuth: asOther,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				expectNoUpdate(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "ResumeSkipsMissedOccurrences",
+			method:    http.MethodPost,
+			path:      path + "/resume",
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, paused)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireStatus(t, recorder, db.StandingOrderActive)
+
+				// every two weeks from 20 days ago, the next one is 8 days from now
+				require.Equal(t, int32(2), rsp.Occurrences)
+				require.Equal(t, paused.StartAt.AddDate(0, 0, 28), *rsp.NextRunAt)
+			},
+		},
+		{
+			name:      "ResumeActive",
+			method:    http.MethodPost,
+			path:      path + "/resume",
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, order)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "Cancel",
+			method:    http.MethodDelete,
+			path:      path,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, order)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				rsp := requireStatus(t, recorder, db.StandingOrderCancelled)
+				require.Nil(t, rsp.NextRunAt)
+			},
+		},
+		{
+			name:      "CancelEnded",
+			method:    http.MethodDelete,
+			path:      path,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectUpdate(store, completed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "CancelInternalError",
+			method:    http.MethodDelete,
+			path:      path,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				store.EXPECT().
+					UpdateStandingOrderTx(gomock.Any(), gomock.Eq(order.ID), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			method:    http.MethodDelete,
+			path:      "/standing_orders/0",
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+}
+
+func randomStandingOrder() db.StandingOrder {
+	startAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	return db.StandingOrder{
+		ID:                      int64(util.RandomInt(1, 1000)),
+		Owner:                   util.RandomOwner(),
+		FromAccountID:           int64(util.RandomInt(1, 1000)),
+		ToAccountID:             int64(util.RandomInt(1001, 2000)),
+		Amount:                  util.RandomMoney() + 1,
+		Currency:                util.RandomCurrency(),
+		Frequency:               util.WeeklyFrequency,
+		IntervalCount:           2,
+		StartAt:                 startAt,
+		InsufficientFundsPolicy: db.SkipOnInsufficientFunds,
+		Status:                  db.StandingOrderActive,
+		Occurrences:             3,
+		NextRunAt:               sql.NullTime{Time: startAt.AddDate(0, 0, 42), Valid: true},
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	db "master_class/db/sqlc"
+	"master_class/exporter"
+	"master_class/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxStatementPeriod bounds a statement, which is returned in one piece
+	maxStatementPeriod = 366 * 24 * time.Hour
+	// statementFormatJSON is the format of the statement resource itself, the others are exports
+	statementFormatJSON = "json"
+)
+
+var (
+	errStatementPeriodMissing = errors.New("from and to are required")
+	errStatementPeriodTooLong = errors.New("statement period must not exceed 366 days")
+	errUnknownStatementFormat = errors.New("unknown statement format")
+	errStatementNotAcceptable = errors.New("no acceptable statement format")
+)
+
+type statementRequest struct {
+	// From is included and To excluded, the balances are the ones at these instants
+	From time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To   time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Format overrides the Accept header, it is json or the name of an exporter
+	Format string `form:"format"`
+}
+
+type statementEntryResponse struct {
+	entryResponse
+	// RunningBalance is the balance of the account right after the entry
+	RunningBalance        util.Money `json:"running_balance"`
+	CounterpartyAccountID *int64     `json:"counterparty_account_id,omitempty"`
+}
+
+type statementResponse struct {
+	AccountID      int64                    `json:"account_id"`
+	Currency       string                   `json:"currency"`
+	From           time.Time                `json:"from"`
+	To             time.Time                `json:"to"`
+	OpeningBalance util.Money               `json:"opening_balance"`
+	ClosingBalance util.Money               `json:"closing_balance"`
+	Entries        []statementEntryResponse `json:"entries"`
+}
+
+// newStatementResponse lays out the entries of the period, opening is the balance before the first of them
+func newStatementResponse(account db.Account, from time.Time, to time.Time, opening int64, rows []db.ListAccountStatementEntriesRow) statementResponse {
+	rsp := statementResponse{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: util.NewMoney(opening, account.Currency),
+		ClosingBalance: util.NewMoney(opening, account.Currency),
+		Entries:        make([]statementEntryResponse, len(rows)),
+	}
+
+	for i, row := range rows {
+		rsp.Entries[i] = statementEntryResponse{
+			entryResponse:  newEntryResponse(row.Entry, account.Currency),
+			RunningBalance: util.NewMoney(row.RunningBalance, account.Currency),
+		}
+
+		if row.Entry.TransferID.Valid {
+			rsp.Entries[i].CounterpartyAccountID = &rows[i].CounterpartyAccountID
+		}
+	}
+
+	if len(rows) > 0 {
+		rsp.ClosingBalance = rsp.Entries[len(rows)-1].RunningBalance
+	}
+
+	return rsp
+}
+
+// getAccountStatement returns the balances of the account at the start and the end of a period,
+// and its entries in between with the balance after each of them
+func (server *Server) getAccountStatement(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req statementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.From.IsZero() || req.To.IsZero() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errStatementPeriodMissing))
+		return
+	}
+
+	if !req.From.Before(req.To) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidTimeRange))
+		return
+	}
+
+	if req.To.Sub(req.From) > maxStatementPeriod {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errStatementPeriodTooLong))
+		return
+	}
+
+	encoder, valid := statementEncoder(ctx, req.Format)
+	if !valid {
+		return
+	}
+
+	account, valid := server.ownedAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	// the running balances are computed along with the entries, so that both come from the same snapshot
+	rows, err := server.store.ListAccountStatementEntries(ctx, db.ListAccountStatementEntriesParams{
+		AccountID: account.ID,
+		FromTime:  req.From,
+		ToTime:    req.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var opening int64
+	if len(rows) > 0 {
+		opening = rows[0].RunningBalance - rows[0].Entry.Amount
+	} else {
+		opening, err = server.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+			AccountID: account.ID,
+			At:        req.From,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	if encoder == nil {
+		ctx.JSON(http.StatusOK, newStatementResponse(account, req.From, req.To, opening, rows))
+		return
+	}
+
+	// the file is encoded in full before anything is sent, so that a failure still gets a proper status
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, newExportStatement(account, req.From, req.To, opening, rows)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s.%s", account.ID, req.From.UTC().Format("20060102"), encoder.Extension())
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, encoder.ContentType(), buf.Bytes())
+}
+
+// statementEncoder picks the exporter of the format named in the query, or else negotiated from the Accept header.
+// A nil encoder stands for the JSON statement.
+func statementEncoder(ctx *gin.Context, format string) (exporter.Encoder, bool) {
+	if format == statementFormatJSON {
+		return nil, true
+	}
+
+	if format != "" {
+		encoder, ok := exporter.Lookup(format)
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("%w %q", errUnknownStatementFormat, format)))
+			return nil, false
+		}
+
+		return encoder, true
+	}
+
+	// JSON comes first so that clients without preference keep getting it
+	offered := []string{gin.MIMEJSON}
+	byContentType := map[string]exporter.Encoder{}
+	for _, name := range exporter.Formats() {
+		encoder, _ := exporter.Lookup(name)
+		offered = append(offered, encoder.ContentType())
+		byContentType[encoder.ContentType()] = encoder
+	}
+
+	contentType := ctx.NegotiateFormat(offered...)
+	if contentType == "" {
+		ctx.JSON(http.StatusNotAcceptable, errorResponse(errStatementNotAcceptable))
+		return nil, false
+	}
+
+	return byContentType[contentType], true
+}
+
+// newExportStatement converts a statement for the exporters, the same way newStatementResponse does for JSON
+func newExportStatement(account db.Account, from time.Time, to time.Time, opening int64, rows []db.ListAccountStatementEntriesRow) exporter.Statement {
+	statement := exporter.Statement{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Entries:        make([]exporter.Entry, len(rows)),
+		CreatedAt:      time.Now(),
+	}
+
+	for i, row := range rows {
+		statement.Entries[i] = exporter.Entry{
+			ID:                    row.Entry.ID,
+			Amount:                row.Entry.Amount,
+			RunningBalance:        row.RunningBalance,
+			BookedAt:              row.Entry.CreatedAt,
+			TransferID:            row.Entry.TransferID.Int64,
+			CounterpartyAccountID: row.CounterpartyAccountID,
+		}
+	}
+
+	if len(rows) > 0 {
+		statement.ClosingBalance = rows[len(rows)-1].RunningBalance
+	}
+
+	return statement
+}
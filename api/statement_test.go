@@ -0,0 +1,352 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetAccountStatementApi(t *testing.T) {
+	currency := util.USD
+	account := randomAccount(&currency)
+
+	runAccountHistoryTestCases(t, "statement", getAccountStatementTestCases(account))
+}
+
+// statementQuery builds the query of a statement of the given period
+func statementQuery(from time.Time, to time.Time) url.Values {
+	query := url.Values{}
+	query.Set("from", from.Format(time.RFC3339))
+	query.Set("to", to.Format(time.RFC3339))
+
+	return query
+}
+
+func getAccountStatementTestCases(account db.Account) []accountHistoryTestCases {
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	accountID := sql.NullInt64{Int64: account.ID, Valid: true}
+	rows := []db.ListAccountStatementEntriesRow{
+		{
+			Entry: db.Entry{
+				ID:         1,
+				AccountID:  accountID,
+				TransferID: sql.NullInt64{Int64: 9, Valid: true},
+				Amount:     500,
+				CreatedAt:  from.Add(time.Hour),
+			},
+			RunningBalance:        1500,
+			CounterpartyAccountID: 77,
+		},
+		{
+			Entry:          db.Entry{ID: 2, AccountID: accountID, Amount: -200, CreatedAt: from.Add(2 * time.Hour)},
+			RunningBalance: 1300,
+		},
+	}
+
+	arg := db.ListAccountStatementEntriesParams{
+		AccountID: account.ID,
+		FromTime:  from,
+		ToTime:    to,
+	}
+
+	expectStatement := func(store *mockdb.MockStore, rows []db.ListAccountStatementEntriesRow, err error) {
+		expectHistoryAccount(store, account)
+		store.EXPECT().
+			ListAccountStatementEntries(gomock.Any(), gomock.Eq(arg)).
+			Times(1).
+			Return(rows, err)
+	}
+
+	expectNoBalance := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	expectNoStatement := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ListAccountStatementEntries(gomock.Any(), gomock.Any()).
+			Times(0)
+		expectNoBalance(store)
+	}
+
+	withoutAccount := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Any()).
+			Times(0)
+		expectNoStatement(store)
+	}
+
+	accepting := func(accept string) func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		return func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			asOwner(t, request, tokenMaker)
+			request.Header.Set("Accept", accept)
+		}
+	}
+
+	withFormat := func(format string) url.Values {
+		query := statementQuery(from, to)
+		query.Set("format", format)
+
+		return query
+	}
+
+	okStatement := func(store *mockdb.MockStore) {
+		expectStatement(store, rows, nil)
+		expectNoBalance(store)
+	}
+
+	exported := func(contentType string, extension string, body string) func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		return func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+			require.Equal(t,
+				fmt.Sprintf(`attachment; filename="statement-%d-20240301.%s"`, account.ID, extension),
+				recorder.Header().Get("Content-Disposition"))
+			require.Contains(t, recorder.Body.String(), body)
+		}
+	}
+
+	badRequest := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+
+	return []accountHistoryTestCases{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     statementQuery(from, to),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectStatement(store, rows, nil)
+				expectNoBalance(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				expected, err := json.Marshal(newStatementResponse(account, from, to, 1000, rows))
+				require.NoError(t, err)
+				require.JSONEq(t, string(expected), recorder.Body.String())
+
+				var rsp struct {
+					OpeningBalance string `json:"opening_balance"`
+					ClosingBalance string `json:"closing_balance"`
+					Entries        []struct {
+						Amount         string `json:"amount"`
+						RunningBalance string `json:"running_balance"`
+					} `json:"entries"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, "10.00", rsp.OpeningBalance)
+				require.Equal(t, "13.00", rsp.ClosingBalance)
+				require.Len(t, rsp.Entries, 2)
+				require.Equal(t, "-2.00", rsp.Entries[1].Amount)
+				require.Equal(t, "13.00", rsp.Entries[1].RunningBalance)
+				require.Contains(t, recorder.Body.String(), `"counterparty_account_id":77`)
+			},
+		},
+		{
+			name:       "FormatJSON",
+			accountID:  account.ID,
+			query:      withFormat("json"),
+			setupAuth:  accepting("text/csv"),
+			buildStubs: okStatement,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+			},
+		},
+		{
+			name:          "FormatCSV",
+			accountID:     account.ID,
+			query:         withFormat("csv"),
+			setupAuth:     asOwner,
+			buildStubs:    okStatement,
+			checkResponse: exported("text/csv", "csv", "1,2024-03-01T01:00:00Z,5.00,USD,15.00,9,77,Transfer 9 from account 77\n"),
+		},
+		{
+			name:          "FormatCAMT053",
+			accountID:     account.ID,
+			query:         withFormat("camt053"),
+			setupAuth:     asOwner,
+			buildStubs:    okStatement,
+			checkResponse: exported("application/xml", "xml", "<Amt Ccy=\"USD\">10.00</Amt>"),
+		},
+		{
+			name:          "AcceptCSV",
+			accountID:     account.ID,
+			query:         statementQuery(from, to),
+			setupAuth:     accepting("text/csv"),
+			buildStubs:    okStatement,
+			checkResponse: exported("text/csv", "csv", "2,2024-03-01T02:00:00Z,-2.00,USD,13.00,,,Adjustment\n"),
+		},
+		{
+			name:          "AcceptOFX",
+			accountID:     account.ID,
+			query:         statementQuery(from, to),
+			setupAuth:     accepting("application/x-ofx"),
+			buildStubs:    okStatement,
+			checkResponse: exported("application/x-ofx", "ofx", "<BALAMT>13.00</BALAMT>"),
+		},
+		{
+			name:          "UnknownFormat",
+			accountID:     account.ID,
+			query:         withFormat("pdf"),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:       "NotAcceptable",
+			accountID:  account.ID,
+			query:      statementQuery(from, to),
+			setupAuth:  accepting("application/pdf"),
+			buildStubs: withoutAccount,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotAcceptable, recorder.Code)
+			},
+		},
+		{
+			name:      "NoEntries",
+			accountID: account.ID,
+			query:     statementQuery(from, to),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectStatement(store, []db.ListAccountStatementEntriesRow{}, nil)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Eq(db.GetAccountBalanceAtParams{AccountID: account.ID, At: from})).
+					Times(1).
+					Return(int64(750), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"opening_balance":"7.50"`)
+				require.Contains(t, recorder.Body.String(), `"closing_balance":"7.50"`)
+				require.Contains(t, recorder.Body.String(), `"entries":[]`)
+			},
+		},
+		{
+			name:      "MissingFrom",
+			accountID: account.ID,
+			query: url.Values{
+				"to": []string{to.Format(time.RFC3339)},
+			},
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:      "InvalidTime",
+			accountID: account.ID,
+			query: url.Values{
+				"from": []string{"March"},
+				"to":   []string{to.Format(time.RFC3339)},
+			},
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidTimeRange",
+			accountID:     account.ID,
+			query:         statementQuery(to, from),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "PeriodTooLong",
+			accountID:     account.ID,
+			query:         statementQuery(from, from.AddDate(2, 0, 0)),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:          "InvalidID",
+			accountID:     0,
+			query:         statementQuery(from, to),
+			setupAuth:     asOwner,
+			buildStubs:    withoutAccount,
+			checkResponse: badRequest,
+		},
+		{
+			name:      "NotOwned",
+			accountID: account.ID,
+			query:     statementQuery(from, to),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectHistoryAccount(store, account)
+				expectNoStatement(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			query:     statementQuery(from, to),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				expectNoStatement(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     statementQuery(from, to),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectStatement(store, nil, sql.ErrConnDone)
+				expectNoBalance(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "BalanceInternalError",
+			accountID: account.ID,
+			query:     statementQuery(from, to),
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectStatement(store, []db.ListAccountStatementEntriesRow{}, nil)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type renewAccessTokenTestCases struct {
+	name          string
+	createToken   func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload)
+	buildStubs    func(store *mockdb.MockStore, payload *token.Payload)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := getRenewAccessTokenTestCases(username)
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, payload := tc.createToken(t, server.tokenMaker)
+			tc.buildStubs(store, payload)
+
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(renewAccessTokenRequest{RefreshToken: refreshToken})
+			require.NoError(t, err)
+
+			url := "/tokens/renew_access"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func getRenewAccessTokenTestCases(username string) []renewAccessTokenTestCases {
+	validToken := func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+		refreshToken, payload, err := tokenMaker.CreateToken(username, util.CustomerRole, token.RefreshToken, time.Hour)
+		require.NoError(t, err)
+
+		return refreshToken, payload
+	}
+
+	return []renewAccessTokenTestCases{
+		{
+			name:        "OK",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{
+						ID:        payload.ID,
+						Username:  username,
+						ExpiresAt: payload.ExpiredAt,
+					}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, Role: util.CustomerRole}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotZero(t, rsp.AccessTokenExpiresAt)
+			},
+		},
+		{
+			name: "InvalidToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				return "invalid-token", nil
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				accessToken, payload, err := tokenMaker.CreateToken(username, util.CustomerRole, token.AccessToken, time.Hour)
+				require.NoError(t, err)
+
+				return accessToken, payload
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) (string, *token.Payload) {
+				refreshToken, payload, err := tokenMaker.CreateToken(username, util.CustomerRole, token.RefreshToken, -time.Minute)
+				require.NoError(t, err)
+
+				return refreshToken, payload
+			},
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:        "SessionNotFound",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:        "BlockedSession",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{
+						ID:        payload.ID,
+						Username:  username,
+						IsBlocked: true,
+						ExpiresAt: payload.ExpiredAt,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:        "MismatchedSessionUser",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{
+						ID:        payload.ID,
+						Username:  "unauthorized_user",
+						ExpiresAt: payload.ExpiredAt,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:        "ExpiredSession",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{
+						ID:        payload.ID,
+						Username:  username,
+						ExpiresAt: time.Now().Add(-time.Minute),
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:        "InternalError",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:        "PasswordChangedAfterIssue",
+			createToken: validToken,
+			buildStubs: func(store *mockdb.MockStore, payload *token.Payload) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{
+						ID:        payload.ID,
+						Username:  username,
+						ExpiresAt: payload.ExpiredAt,
+					}, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, Role: util.CustomerRole, PasswordChangedAt: time.Now().Add(time.Second)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+}
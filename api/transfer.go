@@ -2,18 +2,147 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+var errAmountNotPositive = errors.New("amount must be positive")
+
+// positiveAmount scales the amount of a request to minor units of the currency,
+// responding with 400 when it does not fit the currency or is not positive
+func positiveAmount(ctx *gin.Context, amount util.Decimal, currency string) (util.Money, bool) {
+	money, err := amount.Money(currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return util.Money{}, false
+	}
+
+	if !money.IsPositive() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errAmountNotPositive))
+		return util.Money{}, false
+	}
+
+	return money, true
+}
+
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1"`
+	// Amount is a decimal in the currency, such as "12.34"
+	Amount   util.Decimal `json:"amount" binding:"required"`
+	Currency string       `json:"currency" binding:"required,currency"`
+	// ToCurrency asks for a conversion when the destination account holds another currency
+	ToCurrency string `json:"to_currency" binding:"omitempty,currency"`
+}
+
+type transferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        util.Money `json:"amount"`
+	Currency      string     `json:"currency"`
+	ToAmount      util.Money `json:"to_amount"`
+	ToCurrency    string     `json:"to_currency"`
+	Rate          string     `json:"rate"`
+	Spread        string     `json:"spread"`
+	ReversalOf    *int64     `json:"reversal_of,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// newTransferResponse scales the amounts of the transfer with the currencies of its accounts
+func newTransferResponse(transfer db.Transfer, fromCurrency string, toCurrency string) transferResponse {
+	rsp := transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID.Int64,
+		ToAccountID:   transfer.ToAccountID.Int64,
+		Amount:        util.NewMoney(transfer.Amount, fromCurrency),
+		Currency:      fromCurrency,
+		ToAmount:      util.NewMoney(transfer.ToAmount, toCurrency),
+		ToCurrency:    toCurrency,
+		Rate:          transfer.Rate,
+		Spread:        transfer.Spread,
+		CreatedAt:     transfer.CreatedAt,
+	}
+
+	if transfer.ReversalOf.Valid {
+		rsp.ReversalOf = &transfer.ReversalOf.Int64
+	}
+
+	return rsp
+}
+
+type entryResponse struct {
+	ID         int64      `json:"id"`
+	AccountID  int64      `json:"account_id"`
+	Amount     util.Money `json:"amount"`
+	Currency   string     `json:"currency"`
+	TransferID *int64     `json:"transfer_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry, currency string) entryResponse {
+	rsp := entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID.Int64,
+		Amount:    util.NewMoney(entry.Amount, currency),
+		Currency:  currency,
+		CreatedAt: entry.CreatedAt,
+	}
+
+	if entry.TransferID.Valid {
+		rsp.TransferID = &entry.TransferID.Int64
+	}
+
+	return rsp
+}
+
+// counterpartyAccountResponse shows the account on the other side of a transfer,
+// without the owner and balance that belong to another user
+type counterpartyAccountResponse struct {
+	ID       int64  `json:"id"`
+	Currency string `json:"currency"`
+}
+
+type transferTxResponse struct {
+	Transfer    transferResponse            `json:"transfer"`
+	FromAccount accountResponse             `json:"from_account"`
+	ToAccount   counterpartyAccountResponse `json:"to_account"`
+	FromEntry   entryResponse               `json:"from_entry"`
+	ToEntry     entryResponse               `json:"to_entry"`
+}
+
+func newTransferTxResponse(result db.TransferTxResult) transferTxResponse {
+	fromCurrency := result.FromAccount.Currency
+	toCurrency := result.ToAccount.Currency
+
+	return transferTxResponse{
+		Transfer:    newTransferResponse(result.Transfer, fromCurrency, toCurrency),
+		FromAccount: newAccountResponse(result.FromAccount),
+		ToAccount: counterpartyAccountResponse{
+			ID:       result.ToAccount.ID,
+			Currency: toCurrency,
+		},
+		FromEntry: newEntryResponse(result.FromEntry, fromCurrency),
+		ToEntry:   newEntryResponse(result.ToEntry, toCurrency),
+	}
+}
+
+// renderTransferTxResult renders a transfer result saved under an idempotency key
+func renderTransferTxResult(data json.RawMessage) (any, error) {
+	var result db.TransferTxResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return newTransferTxResponse(result), nil
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -23,46 +152,143 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	if !server.validateAccount(ctx, req.FromAccountID, req.Currency) {
+	amount, valid := positiveAmount(ctx, req.Amount, req.Currency)
+	if !valid {
 		return
 	}
 
-	if !server.validateAccount(ctx, req.ToAccountID, req.Currency) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	key, valid := idempotencyKey(ctx)
+	if !valid {
+		return
+	}
+
+	var idempotency *db.TransferIdempotency
+	if key != "" {
+		requestHash, err := requestFingerprint("POST /transfers", req)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if server.replayIdempotentRequest(ctx, authPayload.Username, key, requestHash, renderTransferTxResult) {
+			return
+		}
+
+		idempotency = &db.TransferIdempotency{
+			Username:    authPayload.Username,
+			Key:         key,
+			RequestHash: requestHash,
+			StatusCode:  http.StatusOK,
+		}
+	}
+
+	fromAccount, valid := server.validateAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	toCurrency := req.Currency
+	if req.ToCurrency != "" {
+		toCurrency = req.ToCurrency
+	}
+
+	_, valid = server.validateAccount(ctx, req.ToAccountID, toCurrency)
+	if !valid {
 		return
 	}
 
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+		Amount:        amount.Amount,
+		Idempotency:   idempotency,
+	}
+
+	if toCurrency != req.Currency {
+		rate, err := server.rates.Rate(ctx, req.Currency, toCurrency)
+		if err != nil {
+			if errors.Is(err, util.ErrRateNotFound) {
+				ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+				return
+			}
+
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		arg.FX = &db.TransferFX{
+			Rate:   rate.FloatString(10),
+			Spread: server.fxSpread,
+		}
 	}
 
 	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) {
+			// a concurrent retry won the race, answer with its outcome
+			if !server.replayIdempotentRequest(ctx, idempotency.Username, idempotency.Key, idempotency.RequestHash, renderTransferTxResult) {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			}
+			return
+		}
+
+		var fundsErr *db.InsufficientFundsError
+		if errors.As(err, &fundsErr) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(fundsErr))
+			return
+		}
+
+		if errors.Is(err, db.ErrConvertedTooSmall) || errors.Is(err, util.ErrMoneyOverflow) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrCurrencyMismatch) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, newTransferTxResponse(result))
 }
 
-func (server *Server) validateAccount(ctx *gin.Context, accountID int64, currency string) bool {
+func (server *Server) validateAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, status, err := server.checkAccount(ctx, accountID, currency)
+	if err != nil {
+		ctx.JSON(status, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
+}
+
+// checkAccount fetches the account and checks that it holds currency, like validateAccount
+// but leaving the response to the caller. A failure comes with the status to answer it with.
+func (server *Server) checkAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, int, error) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return false
+			return account, http.StatusNotFound, err
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return false
+		return account, http.StatusInternalServerError, err
 	}
 
 	if account.Currency != currency {
 		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return false
+		return account, http.StatusBadRequest, err
 	}
 
-	return true
+	return account, http.StatusOK, nil
 }
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	db "master_class/db/sqlc"
+	"master_class/importer"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxTransferBatchSize bounds the uploaded file, in bytes
+	maxTransferBatchSize = 1 << 20
+	// maxTransferBatchLines bounds the transfers of a batch, which run in one transaction
+	maxTransferBatchLines = 1000
+)
+
+var (
+	errTransferBatchEmpty    = errors.New("transfer batch has no lines")
+	errTransferBatchTooLarge = fmt.Errorf("transfer batch must not exceed %d lines", maxTransferBatchLines)
+	errTransferBatchNotOwned = errors.New("transfer batch doesn't belong to the authenticated user")
+	errSameAccount           = errors.New("from and to accounts must differ")
+)
+
+type createTransferBatchRequest struct {
+	// Mode is atomic, the default, or best_effort
+	Mode string `form:"mode" binding:"omitempty,oneof=atomic best_effort"`
+}
+
+// transferBatchFingerprint is what an idempotency key of a batch is bound to
+type transferBatchFingerprint struct {
+	Mode string `json:"mode"`
+	File []byte `json:"file"`
+}
+
+type transferBatchLineResponse struct {
+	LineNumber    int32      `json:"line_number"`
+	Reference     string     `json:"reference,omitempty"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        util.Money `json:"amount"`
+	Currency      string     `json:"currency"`
+	Status        string     `json:"status"`
+	TransferID    *int64     `json:"transfer_id,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+}
+
+type transferBatchResponse struct {
+	ID        int64                       `json:"id"`
+	Mode      string                      `json:"mode"`
+	Status    string                      `json:"status"`
+	Succeeded int                         `json:"succeeded"`
+	Failed    int                         `json:"failed"`
+	Skipped   int                         `json:"skipped"`
+	Lines     []transferBatchLineResponse `json:"lines"`
+	CreatedAt time.Time                   `json:"created_at"`
+}
+
+func newTransferBatchResponse(batch db.TransferBatch, lines []db.TransferBatchLine) transferBatchResponse {
+	rsp := transferBatchResponse{
+		ID:        batch.ID,
+		Mode:      batch.Mode,
+		Status:    batch.Status,
+		Lines:     make([]transferBatchLineResponse, len(lines)),
+		CreatedAt: batch.CreatedAt,
+	}
+
+	for i, line := range lines {
+		rsp.Lines[i] = transferBatchLineResponse{
+			LineNumber:    line.LineNumber,
+			Reference:     line.Reference,
+			FromAccountID: line.FromAccountID,
+			ToAccountID:   line.ToAccountID,
+			Amount:        util.NewMoney(line.Amount, line.Currency),
+			Currency:      line.Currency,
+			Status:        line.Status,
+			FailureReason: line.FailureReason.String,
+		}
+
+		if line.TransferID.Valid {
+			rsp.Lines[i].TransferID = &lines[i].TransferID.Int64
+		}
+
+		switch line.Status {
+		case db.TransferBatchLineSucceeded:
+			rsp.Succeeded++
+		case db.TransferBatchLineFailed:
+			rsp.Failed++
+		case db.TransferBatchLineSkipped:
+			rsp.Skipped++
+		}
+	}
+
+	return rsp
+}
+
+// renderTransferBatchTxResult renders a batch result saved under an idempotency key
+func renderTransferBatchTxResult(data json.RawMessage) (any, error) {
+	var result db.TransferBatchTxResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return newTransferBatchResponse(result.Batch, result.Lines), nil
+}
+
+// createTransferBatch makes the transfers listed in a CSV or pain.001 file, sent as the body with its
+// media type. The file is rejected when it is malformed, while a line whose accounts fail validation
+// is recorded as failed. An atomic batch then makes all its transfers or none, a best effort batch
+// makes the transfers of the other lines. The batch reports the outcome of each line either way.
+// An Idempotency-Key header binds the batch to the file and mode, so a retry gets the same outcome.
+func (server *Server) createTransferBatch(ctx *gin.Context) {
+	var req createTransferBatchRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = db.TransferBatchAtomic
+	}
+
+	// the file is read whole, as the idempotency key is bound to it
+	file, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxTransferBatchSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	lines, err := importer.Parse(ctx.GetHeader("Content-Type"), bytes.NewReader(file))
+	if err != nil {
+		if errors.Is(err, importer.ErrUnsupportedContentType) {
+			ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if len(lines) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errTransferBatchEmpty))
+		return
+	}
+
+	if len(lines) > maxTransferBatchLines {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errTransferBatchTooLarge))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	key, valid := idempotencyKey(ctx)
+	if !valid {
+		return
+	}
+
+	var idempotency *db.TransferIdempotency
+	if key != "" {
+		requestHash, err := requestFingerprint("POST /transfer_batches", transferBatchFingerprint{Mode: req.Mode, File: file})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if server.replayIdempotentRequest(ctx, authPayload.Username, key, requestHash, renderTransferBatchTxResult) {
+			return
+		}
+
+		idempotency = &db.TransferIdempotency{
+			Username:    authPayload.Username,
+			Key:         key,
+			RequestHash: requestHash,
+			StatusCode:  http.StatusCreated,
+		}
+	}
+
+	arg := db.CreateTransferBatchTxParams{
+		Owner:       authPayload.Username,
+		Mode:        req.Mode,
+		Lines:       make([]db.TransferBatchLineParams, len(lines)),
+		Idempotency: idempotency,
+	}
+
+	for i, line := range lines {
+		var valid bool
+		arg.Lines[i], valid = server.validateTransferBatchLine(ctx, authPayload.Username, line)
+		if !valid {
+			return
+		}
+	}
+
+	result, err := server.store.CreateTransferBatchTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrDuplicateIdempotencyKey) {
+			// a concurrent retry won the race, answer with its outcome
+			if !server.replayIdempotentRequest(ctx, idempotency.Username, idempotency.Key, idempotency.RequestHash, renderTransferBatchTxResult) {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			}
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newTransferBatchResponse(result.Batch, result.Lines))
+}
+
+// validateTransferBatchLine checks a line of a batch the way createTransfer checks a transfer.
+// A malformed amount rejects the whole file, while the failures of the account checks are kept
+// as the failure reason of the line. It responds with an error and returns false when the file
+// is rejected or the accounts cannot be checked.
+func (server *Server) validateTransferBatchLine(ctx *gin.Context, owner string, line importer.Line) (db.TransferBatchLineParams, bool) {
+	amount, err := util.ParseMoney(line.Amount, line.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("line %d: %w", line.Number, err)))
+		return db.TransferBatchLineParams{}, false
+	}
+
+	if !amount.IsPositive() {
+		ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("line %d: %w", line.Number, errAmountNotPositive)))
+		return db.TransferBatchLineParams{}, false
+	}
+
+	params := db.TransferBatchLineParams{
+		LineNumber:    int32(line.Number),
+		Reference:     line.Reference,
+		FromAccountID: line.FromAccountID,
+		ToAccountID:   line.ToAccountID,
+		Amount:        amount.Amount,
+		Currency:      amount.Currency,
+	}
+
+	if line.FromAccountID == line.ToAccountID {
+		params.FailureReason = errSameAccount.Error()
+		return params, true
+	}
+
+	for _, accountID := range []int64{line.FromAccountID, line.ToAccountID} {
+		account, status, err := server.checkAccount(ctx, accountID, amount.Currency)
+		switch {
+		case status == http.StatusInternalServerError:
+			ctx.JSON(status, errorResponse(err))
+			return params, false
+		case status == http.StatusNotFound:
+			params.FailureReason = fmt.Sprintf("account [%d] not found", accountID)
+		case err != nil:
+			params.FailureReason = err.Error()
+		case accountID == line.FromAccountID && account.Owner != owner:
+			params.FailureReason = "from account doesn't belong to the authenticated user"
+		}
+
+		if params.FailureReason != "" {
+			return params, true
+		}
+	}
+
+	return params, true
+}
+
+type transferBatchRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getTransferBatch(ctx *gin.Context) {
+	var req transferBatchRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	batch, err := server.store.GetTransferBatch(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if batch.Owner != authPayload.Username {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTransferBatchNotOwned))
+		return
+	}
+
+	lines, err := server.store.ListTransferBatchLines(ctx, batch.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferBatchResponse(batch, lines))
+}
@@ -0,0 +1,803 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"master_class/token"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type createTransferBatchTestCases struct {
+	name          string
+	query         string
+	contentType   string
+	body          string
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type transferBatchTestCases struct {
+	name          string
+	batchID       int64
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type createTransferBatchIdempotencyTestCases struct {
+	name          string
+	key           string
+	query         string
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestCreateTransferBatchApi(t *testing.T) {
+	sender := randomAccount(nil)
+
+	currency := sender.Currency
+	receiver1 := randomAccount(&currency)
+	receiver1.ID = sender.ID + 1
+	receiver2 := randomAccount(&currency)
+	receiver2.ID = sender.ID + 2
+
+	testCases := getCreateTransferBatchTestCases(sender, receiver1, receiver2)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/transfer_batches"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", tc.contentType)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, sender.Owner, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestTransferBatchApi(t *testing.T) {
+	batch, lines := randomTransferBatch()
+
+	testCases := getTransferBatchTestCases(batch, lines)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfer_batches/%d", tc.batchID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// batchCSV builds a CSV file from lines of from_account_id, to_account_id, amount and currency
+func TestCreateTransferBatchIdempotencyApi(t *testing.T) {
+	sender := randomAccount(nil)
+
+	currency := sender.Currency
+	receiver := randomAccount(&currency)
+	receiver.ID = sender.ID + 1
+
+	file := batchCSV(fmt.Sprintf("%d,%d,10.00,%s", sender.ID, receiver.ID, currency))
+	testCases := getCreateTransferBatchIdempotencyTestCases(t, sender, receiver, file)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/transfer_batches"+tc.query, strings.NewReader(file))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "text/csv")
+			request.Header.Set(idempotencyKeyHeader, tc.key)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, sender.Owner, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func batchCSV(lines ...string) string {
+	return "from_account_id,to_account_id,amount,currency\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// batchPain001 builds a pain.001 file paying each creditor account the amount from the debtor account
+func batchPain001(debtor int64, currency string, amount string, creditors ...int64) string {
+	var transactions strings.Builder
+	for i, creditor := range creditors {
+		fmt.Fprintf(&transactions,
+			`<CdtTrfTxInf><PmtId><EndToEndId>E2E-%d</EndToEndId></PmtId>`+
+				`<Amt><InstdAmt Ccy="%s">%s</InstdAmt></Amt>`+
+				`<CdtrAcct><Id><Othr><Id>%d</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>`,
+			i+1, currency, amount, creditor)
+	}
+
+	return fmt.Sprintf(
+		`<?xml version="1.0" encoding="UTF-8"?>`+
+			`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"><CstmrCdtTrfInitn>`+
+			`<GrpHdr><MsgId>PAYROLL</MsgId><NbOfTxs>%d</NbOfTxs></GrpHdr>`+
+			`<PmtInf><DbtrAcct><Id><Othr><Id>%d</Id></Othr></Id></DbtrAcct>%s</PmtInf>`+
+			`</CstmrCdtTrfInitn></Document>`,
+		len(creditors), debtor, transactions.String())
+}
+
+func getCreateTransferBatchTestCases(sender db.Account, receiver1 db.Account, receiver2 db.Account) []createTransferBatchTestCases {
+	currency := sender.Currency
+	csvFile := batchCSV(
+		fmt.Sprintf("%d,%d,10.00,%s", sender.ID, receiver1.ID, currency),
+		fmt.Sprintf("%d,%d,2.50,%s", sender.ID, receiver2.ID, currency),
+	)
+
+	expectAccount := func(store *mockdb.MockStore, account db.Account, times int) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+			Times(times).
+			Return(account, nil)
+	}
+
+	expectAccounts := func(store *mockdb.MockStore) {
+		expectAccount(store, sender, 2)
+		expectAccount(store, receiver1, 1)
+		expectAccount(store, receiver2, 1)
+	}
+
+	batchLine := func(number int32, receiver db.Account, amount int64, reference string) db.TransferBatchLineParams {
+		return db.TransferBatchLineParams{
+			LineNumber:    number,
+			Reference:     reference,
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        amount,
+			Currency:      currency,
+		}
+	}
+
+	// expectBatch checks the lines handed to the store and answers with the batch it would record
+	expectBatch := func(store *mockdb.MockStore, mode string, lines []db.TransferBatchLineParams) {
+		store.EXPECT().
+			CreateTransferBatchTx(gomock.Any(), gomock.Eq(db.CreateTransferBatchTxParams{
+				Owner: sender.Owner,
+				Mode:  mode,
+				Lines: lines,
+			})).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.CreateTransferBatchTxParams) (db.TransferBatchTxResult, error) {
+				result := db.TransferBatchTxResult{
+					Batch: db.TransferBatch{ID: 1, Owner: arg.Owner, Mode: arg.Mode, Status: db.TransferBatchSucceeded},
+					Lines: make([]db.TransferBatchLine, len(arg.Lines)),
+				}
+
+				for i, line := range arg.Lines {
+					result.Lines[i] = db.TransferBatchLine{
+						BatchID:       1,
+						LineNumber:    line.LineNumber,
+						Reference:     line.Reference,
+						FromAccountID: line.FromAccountID,
+						ToAccountID:   line.ToAccountID,
+						Amount:        line.Amount,
+						Currency:      line.Currency,
+						Status:        db.TransferBatchLineSucceeded,
+						TransferID:    sql.NullInt64{Int64: int64(100 + i), Valid: true},
+					}
+
+					if line.FailureReason != "" {
+						result.Batch.Status = db.TransferBatchFailed
+						result.Lines[i].Status = db.TransferBatchLineFailed
+						result.Lines[i].TransferID = sql.NullInt64{}
+						result.Lines[i].FailureReason = sql.NullString{String: line.FailureReason, Valid: true}
+					}
+				}
+
+				return result, nil
+			})
+	}
+
+	expectNoBatch := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CreateTransferBatchTx(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	withoutAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Any()).
+			Times(0)
+		expectNoBatch(store)
+	}
+
+	// failedLine expects the second line to be recorded as failed for the reason
+	failedLine := func(reason string, stubs func(store *mockdb.MockStore)) func(store *mockdb.MockStore) {
+		return func(store *mockdb.MockStore) {
+			stubs(store)
+
+			failed := batchLine(2, receiver2, 250, "")
+			failed.FailureReason = reason
+			expectBatch(store, db.TransferBatchAtomic, []db.TransferBatchLineParams{
+				batchLine(1, receiver1, 1000, ""),
+				failed,
+			})
+		}
+	}
+
+	statusIs := func(status int) func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		return func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			require.Equal(t, status, recorder.Code)
+		}
+	}
+
+	return []createTransferBatchTestCases{
+		{
+			name:        "CSV",
+			contentType: "text/csv",
+			body:        csvFile,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				expectBatch(store, db.TransferBatchAtomic, []db.TransferBatchLineParams{
+					batchLine(1, receiver1, 1000, ""),
+					batchLine(2, receiver2, 250, ""),
+				})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp struct {
+					Mode      string `json:"mode"`
+					Status    string `json:"status"`
+					Succeeded int    `json:"succeeded"`
+					Lines     []struct {
+						Amount     string `json:"amount"`
+						TransferID int64  `json:"transfer_id"`
+					} `json:"lines"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.TransferBatchAtomic, rsp.Mode)
+				require.Equal(t, db.TransferBatchSucceeded, rsp.Status)
+				require.Equal(t, 2, rsp.Succeeded)
+				require.Len(t, rsp.Lines, 2)
+				require.Equal(t, "2.50", rsp.Lines[1].Amount)
+				require.Equal(t, int64(101), rsp.Lines[1].TransferID)
+			},
+		},
+		{
+			name:        "Pain001BestEffort",
+			query:       "?mode=best_effort",
+			contentType: "application/xml; charset=utf-8",
+			body:        batchPain001(sender.ID, currency, "10.00", receiver1.ID, receiver2.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccount(store, sender, 2)
+				expectAccount(store, receiver1, 1)
+				expectAccount(store, receiver2, 1)
+				expectBatch(store, db.TransferBatchBestEffort, []db.TransferBatchLineParams{
+					batchLine(1, receiver1, 1000, "E2E-1"),
+					batchLine(2, receiver2, 1000, "E2E-2"),
+				})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"mode":"best_effort"`)
+				require.Contains(t, recorder.Body.String(), `"reference":"E2E-2"`)
+			},
+		},
+		{
+			name:        "AccountNotFound",
+			contentType: "text/csv",
+			body:        csvFile,
+			buildStubs: failedLine(fmt.Sprintf("account [%d] not found", receiver2.ID), func(store *mockdb.MockStore) {
+				expectAccount(store, sender, 2)
+				expectAccount(store, receiver1, 1)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(receiver2.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			}),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"status":"failed"`)
+				require.Contains(t, recorder.Body.String(), "not found")
+				require.Contains(t, recorder.Body.String(), `"failed":1`)
+			},
+		},
+		{
+			name:        "CurrencyMismatch",
+			contentType: "text/csv",
+			body:        csvFile,
+			buildStubs: failedLine(
+				fmt.Sprintf("account [%d] currency mismatch: %s vs %s", receiver2.ID, util.PickOtherCurrency(currency), currency),
+				func(store *mockdb.MockStore) {
+					other := receiver2
+					other.Currency = util.PickOtherCurrency(currency)
+
+					expectAccount(store, sender, 2)
+					expectAccount(store, receiver1, 1)
+					expectAccount(store, other, 1)
+				}),
+			checkResponse: statusIs(http.StatusCreated),
+		},
+		{
+			name:        "FromAccountNotOwned",
+			contentType: "text/csv",
+			body: batchCSV(
+				fmt.Sprintf("%d,%d,10.00,%s", sender.ID, receiver1.ID, currency),
+				fmt.Sprintf("%d,%d,2.50,%s", receiver1.ID, receiver2.ID, currency),
+			),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccount(store, sender, 1)
+				expectAccount(store, receiver1, 2)
+
+				failed := batchLine(2, receiver2, 250, "")
+				failed.FromAccountID = receiver1.ID
+				failed.FailureReason = "from account doesn't belong to the authenticated user"
+				expectBatch(store, db.TransferBatchAtomic, []db.TransferBatchLineParams{
+					batchLine(1, receiver1, 1000, ""),
+					failed,
+				})
+			},
+			checkResponse: statusIs(http.StatusCreated),
+		},
+		{
+			name:        "SameAccount",
+			contentType: "text/csv",
+			body: batchCSV(
+				fmt.Sprintf("%d,%d,10.00,%s", sender.ID, receiver1.ID, currency),
+				fmt.Sprintf("%d,%d,2.50,%s", receiver2.ID, receiver2.ID, currency),
+			),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccount(store, sender, 1)
+				expectAccount(store, receiver1, 1)
+
+				failed := batchLine(2, receiver2, 250, "")
+				failed.FromAccountID = receiver2.ID
+				failed.FailureReason = errSameAccount.Error()
+				expectBatch(store, db.TransferBatchAtomic, []db.TransferBatchLineParams{
+					batchLine(1, receiver1, 1000, ""),
+					failed,
+				})
+			},
+			checkResponse: statusIs(http.StatusCreated),
+		},
+		{
+			name:          "InvalidMode",
+			query:         "?mode=some",
+			contentType:   "text/csv",
+			body:          csvFile,
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusBadRequest),
+		},
+		{
+			name:          "UnsupportedContentType",
+			contentType:   "application/json",
+			body:          `{"lines":[]}`,
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusUnsupportedMediaType),
+		},
+		{
+			name:          "MalformedFile",
+			contentType:   "text/csv",
+			body:          batchCSV("1,2,3"),
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusBadRequest),
+		},
+		{
+			name:          "Empty",
+			contentType:   "text/csv",
+			body:          "from_account_id,to_account_id,amount,currency\n",
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusBadRequest),
+		},
+		{
+			name:          "TooManyLines",
+			contentType:   "text/csv",
+			body:          batchCSV(strings.Repeat("1,2,1.00,USD\n", maxTransferBatchLines) + "1,2,1.00,USD"),
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusBadRequest),
+		},
+		{
+			name:          "TooLarge",
+			contentType:   "text/csv",
+			body:          batchCSV(strings.Repeat("1,2,1.00,USD\n", maxTransferBatchSize/10)),
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusRequestEntityTooLarge),
+		},
+		{
+			name:        "InvalidAmount",
+			contentType: "text/csv",
+			body: batchCSV(
+				fmt.Sprintf("%d,%d,10.00,%s", sender.ID, receiver1.ID, currency),
+				fmt.Sprintf("%d,%d,2.505,%s", sender.ID, receiver2.ID, currency),
+			),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccount(store, sender, 1)
+				expectAccount(store, receiver1, 1)
+				expectNoBatch(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "line 2")
+			},
+		},
+		{
+			name:          "AmountNotPositive",
+			contentType:   "text/csv",
+			body:          batchCSV(fmt.Sprintf("%d,%d,-1.00,%s", sender.ID, receiver1.ID, currency)),
+			buildStubs:    withoutAccounts,
+			checkResponse: statusIs(http.StatusBadRequest),
+		},
+		{
+			name:        "GetAccountInternalError",
+			contentType: "text/csv",
+			body:        csvFile,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+				expectNoBatch(store)
+			},
+			checkResponse: statusIs(http.StatusInternalServerError),
+		},
+		{
+			name:        "InternalError",
+			contentType: "text/csv",
+			body:        csvFile,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store)
+				store.EXPECT().
+					CreateTransferBatchTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferBatchTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: statusIs(http.StatusInternalServerError),
+		},
+	}
+}
+
+func getCreateTransferBatchIdempotencyTestCases(t *testing.T, sender db.Account, receiver db.Account, file string) []createTransferBatchIdempotencyTestCases {
+	key := util.RandomString(16)
+
+	requestHash, err := requestFingerprint("POST /transfer_batches", transferBatchFingerprint{
+		Mode: db.TransferBatchAtomic,
+		File: []byte(file),
+	})
+	require.NoError(t, err)
+
+	idempotency := &db.TransferIdempotency{
+		Username:    sender.Owner,
+		Key:         key,
+		RequestHash: requestHash,
+		StatusCode:  http.StatusCreated,
+	}
+
+	savedResult := db.TransferBatchTxResult{
+		Batch: db.TransferBatch{ID: 1, Owner: sender.Owner, Mode: db.TransferBatchAtomic, Status: db.TransferBatchSucceeded},
+		Lines: []db.TransferBatchLine{
+			{
+				BatchID:       1,
+				LineNumber:    1,
+				FromAccountID: sender.ID,
+				ToAccountID:   receiver.ID,
+				Amount:        1000,
+				Currency:      sender.Currency,
+				Status:        db.TransferBatchLineSucceeded,
+				TransferID:    sql.NullInt64{Int64: 100, Valid: true},
+			},
+		},
+	}
+	savedResponse, err := json.Marshal(savedResult)
+	require.NoError(t, err)
+
+	replayedResponse, err := json.Marshal(newTransferBatchResponse(savedResult.Batch, savedResult.Lines))
+	require.NoError(t, err)
+	savedRecord := db.IdempotencyKey{
+		Username:    sender.Owner,
+		Key:         key,
+		RequestHash: requestHash,
+		Response:    savedResponse,
+		StatusCode:  http.StatusCreated,
+	}
+
+	expectAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(sender.ID)).
+			Times(1).
+			Return(sender, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(receiver.ID)).
+			Times(1).
+			Return(receiver, nil)
+	}
+
+	return []createTransferBatchIdempotencyTestCases{
+		{
+			name: "FirstRequest",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: sender.Owner, Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				expectAccounts(store)
+				store.EXPECT().
+					CreateTransferBatchTx(gomock.Any(), gomock.Eq(db.CreateTransferBatchTxParams{
+						Owner: sender.Owner,
+						Mode:  db.TransferBatchAtomic,
+						Lines: []db.TransferBatchLineParams{
+							{
+								LineNumber:    1,
+								FromAccountID: sender.ID,
+								ToAccountID:   receiver.ID,
+								Amount:        1000,
+								Currency:      sender.Currency,
+							},
+						},
+						Idempotency: idempotency,
+					})).
+					Times(1).
+					Return(savedResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+				require.Empty(t, recorder.Header().Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name: "Replay",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(savedRecord, nil)
+				store.EXPECT().
+					CreateTransferBatchTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// the replay is answered with the status of the first response
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+				require.JSONEq(t, string(replayedResponse), recorder.Body.String())
+			},
+		},
+		{
+			name:  "DifferentMode",
+			key:   key,
+			query: "?mode=best_effort",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(savedRecord, nil)
+				store.EXPECT().
+					CreateTransferBatchTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentRetry",
+			key:  key,
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Return(savedRecord, nil),
+				)
+				expectAccounts(store)
+				store.EXPECT().
+					CreateTransferBatchTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferBatchTxResult{}, db.ErrDuplicateIdempotencyKey)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.JSONEq(t, string(replayedResponse), recorder.Body.String())
+			},
+		},
+		{
+			name: "KeyTooLong",
+			key:  util.RandomString(maxIdempotencyKeyLength + 1),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateTransferBatchTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+}
+
+func getTransferBatchTestCases(batch db.TransferBatch, lines []db.TransferBatchLine) []transferBatchTestCases {
+	asOwner := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, batch.Owner, util.CustomerRole, time.Minute)
+	}
+
+	expectGet := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetTransferBatch(gomock.Any(), gomock.Eq(batch.ID)).
+			Times(1).
+			Return(batch, nil)
+	}
+
+	expectNoLines := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ListTransferBatchLines(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	return []transferBatchTestCases{
+		{
+			name:      "OK",
+			batchID:   batch.ID,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				store.EXPECT().
+					ListTransferBatchLines(gomock.Any(), gomock.Eq(batch.ID)).
+					Times(1).
+					Return(lines, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				expected, err := json.Marshal(newTransferBatchResponse(batch, lines))
+				require.NoError(t, err)
+				require.JSONEq(t, string(expected), recorder.Body.String())
+				require.Contains(t, recorder.Body.String(), `"succeeded":1`)
+				require.Contains(t, recorder.Body.String(), `"failed":1`)
+			},
+		},
+		{
+			name:      "NotFound",
+			batchID:   batch.ID,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransferBatch(gomock.Any(), gomock.Eq(batch.ID)).
+					Times(1).
+					Return(db.TransferBatch{}, sql.ErrNoRows)
+				expectNoLines(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "NotOwned",
+			batchID: batch.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				expectNoLines(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			batchID:   0,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransferBatch(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectNoLines(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			batchID:   batch.ID,
+			setupAuth: asOwner,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGet(store)
+				store.EXPECT().
+					ListTransferBatchLines(gomock.Any(), gomock.Eq(batch.ID)).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+// randomTransferBatch returns a best effort batch whose second line failed
+func randomTransferBatch() (db.TransferBatch, []db.TransferBatchLine) {
+	batch := db.TransferBatch{
+		ID:     int64(util.RandomInt(1, 1000)),
+		Owner:  util.RandomOwner(),
+		Mode:   db.TransferBatchBestEffort,
+		Status: db.TransferBatchPartiallySucceeded,
+	}
+
+	currency := util.RandomCurrency()
+	lines := []db.TransferBatchLine{
+		{
+			BatchID:       batch.ID,
+			LineNumber:    1,
+			FromAccountID: int64(util.RandomInt(1, 1000)),
+			ToAccountID:   int64(util.RandomInt(1001, 2000)),
+			Amount:        util.RandomMoney(),
+			Currency:      currency,
+			Status:        db.TransferBatchLineSucceeded,
+			TransferID:    sql.NullInt64{Int64: int64(util.RandomInt(1, 1000)), Valid: true},
+		},
+		{
+			BatchID:       batch.ID,
+			LineNumber:    2,
+			FromAccountID: int64(util.RandomInt(1, 1000)),
+			ToAccountID:   int64(util.RandomInt(1001, 2000)),
+			Amount:        util.RandomMoney(),
+			Currency:      currency,
+			Status:        db.TransferBatchLineFailed,
+			FailureReason: sql.NullString{String: "insufficient funds", Valid: true},
+		},
+	}
+
+	return batch, lines
+}
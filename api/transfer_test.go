@@ -1,15 +1,19 @@
 package api
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	mockdb "master_class/db/mock"
 	db "master_class/db/sqlc"
+	"master_class/token"
 	"master_class/util"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -19,8 +23,9 @@ type createTransferTestCases struct {
 	name                string
 	account_sender_id   int64
 	account_receiver_id int64
-	amount              int64
+	amount              string
 	currency            string
+	setupAuth           func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	buildStubs          func(store *mockdb.MockStore)
 	checkResponse       func(t *testing.T, recorder *httptest.ResponseRecorder)
 }
@@ -42,15 +47,17 @@ func TestCreateTransferApi(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
 			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
 			url := "/transfers"
-			body := fmt.Sprintf(`{"from_account_id": %d, "to_account_id": %d, "amount": %d, "currency": "%s"}`, tc.account_sender_id, tc.account_receiver_id, tc.amount, tc.currency)
+			body := fmt.Sprintf(`{"from_account_id": %d, "to_account_id": %d, "amount": "%s", "currency": "%s"}`, tc.account_sender_id, tc.account_receiver_id, tc.amount, tc.currency)
 			request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -63,8 +70,11 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 			name:                "OK",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              100,
+			amount:              "1.00",
 			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), account_sender.ID).
@@ -80,19 +90,34 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 						ToAccountID:   account_receiver.ID,
 						Amount:        100,
 					})).
-					Return(db.TransferTxResult{}, nil).
+					Return(randomTransferTxResult(account_sender, account_receiver, 100, 100), nil).
 					Times(1)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp map[string]map[string]any
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "1.00", rsp["transfer"]["amount"])
+				require.Equal(t, "-1.00", rsp["from_entry"]["amount"])
+
+				// the recipient's owner and balance are not disclosed
+				require.Equal(t, map[string]any{
+					"id":       float64(account_receiver.ID),
+					"currency": account_receiver.Currency,
+				}, rsp["to_account"])
 			},
 		},
 		{
 			name:                "FromAccountNotFound",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              100,
+			amount:              "1.00",
 			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), account_sender.ID).
@@ -107,8 +132,11 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 			name:                "ToAccountNotFound",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              100,
+			amount:              "1.00",
 			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), account_sender.ID).
@@ -127,8 +155,11 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 			name:                "TransferTxFailed",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              100,
+			amount:              "1.00",
 			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), account_sender.ID).
@@ -151,12 +182,47 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name:                "InsufficientFunds",
+			account_sender_id:   account_sender.ID,
+			account_receiver_id: account_receiver.ID,
+			amount:              "1.00",
+			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), account_sender.ID).
+					Return(account_sender, nil).
+					Times(1)
+				store.EXPECT().
+					GetAccount(gomock.Any(), account_receiver.ID).
+					Return(account_receiver, nil).
+					Times(1)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResult{}, &db.InsufficientFundsError{
+						AccountID: account_sender.ID,
+						Balance:   50,
+						Amount:    100,
+					}).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "insufficient funds")
+			},
+		},
 		{
 			name:                "InvalidCurrency",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              100,
+			amount:              "1.00",
 			currency:            util.PickOtherCurrency(account_sender.Currency),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), account_sender.ID).
@@ -171,8 +237,48 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 			name:                "WrongBalance",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              0,
+			amount:              "0.00",
 			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:                "TooManyDecimals",
+			account_sender_id:   account_sender.ID,
+			account_receiver_id: account_receiver.ID,
+			amount:              "1.001",
+			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "decimal places")
+			},
+		},
+		{
+			name:                "InvalidAmount",
+			account_sender_id:   account_sender.ID,
+			account_receiver_id: account_receiver.ID,
+			amount:              "1e3",
+			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Any()).
@@ -186,8 +292,11 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 			name:                "GetAccountFailed",
 			account_sender_id:   account_sender.ID,
 			account_receiver_id: account_receiver.ID,
-			amount:              100,
+			amount:              "1.00",
 			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Any()).
@@ -198,5 +307,557 @@ func getCreateTransferTestCases(account_sender db.Account, account_receiver db.A
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name:                "NoAuthorization",
+			account_sender_id:   account_sender.ID,
+			account_receiver_id: account_receiver.ID,
+			amount:              "1.00",
+			currency:            account_sender.Currency,
+			setupAuth:           func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:                "UnauthorizedUser",
+			account_sender_id:   account_sender.ID,
+			account_receiver_id: account_receiver.ID,
+			amount:              "1.00",
+			currency:            account_sender.Currency,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account_receiver.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), account_sender.ID).
+					Return(account_sender, nil).
+					Times(1)
+				store.EXPECT().
+					GetAccount(gomock.Any(), account_receiver.ID).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+}
+
+type createTransferIdempotencyTestCases struct {
+	name          string
+	key           string
+	request       transferRequest
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestCreateTransferIdempotencyApi(t *testing.T) {
+	account_sender := randomAccount(nil)
+
+	currency := account_sender.Currency
+	account_receiver := randomAccount(&currency)
+
+	testCases := getCreateTransferIdempotencyTestCases(t, account_sender, account_receiver)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.request)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeader, tc.key)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func getCreateTransferIdempotencyTestCases(t *testing.T, account_sender db.Account, account_receiver db.Account) []createTransferIdempotencyTestCases {
+	key := util.RandomString(16)
+
+	request := transferRequest{
+		FromAccountID: account_sender.ID,
+		ToAccountID:   account_receiver.ID,
+		Amount:        "1.00",
+		Currency:      account_sender.Currency,
+	}
+
+	requestHash, err := requestFingerprint("POST /transfers", request)
+	require.NoError(t, err)
+
+	idempotency := &db.TransferIdempotency{
+		Username:    account_sender.Owner,
+		Key:         key,
+		RequestHash: requestHash,
+		StatusCode:  http.StatusOK,
 	}
+
+	savedResult := randomTransferTxResult(account_sender, account_receiver, 100, 100)
+	savedResponse, err := json.Marshal(savedResult)
+	require.NoError(t, err)
+
+	replayedResponse, err := json.Marshal(newTransferTxResponse(savedResult))
+	require.NoError(t, err)
+	savedRecord := db.IdempotencyKey{
+		Username:    account_sender.Owner,
+		Key:         key,
+		RequestHash: requestHash,
+		Response:    savedResponse,
+		StatusCode:  http.StatusOK,
+	}
+
+	expectAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_sender.ID).
+			Return(account_sender, nil).
+			Times(1)
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_receiver.ID).
+			Return(account_receiver, nil).
+			Times(1)
+	}
+
+	return []createTransferIdempotencyTestCases{
+		{
+			name:    "FirstRequest",
+			key:     key,
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: account_sender.Owner, Key: key})).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				expectAccounts(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account_sender.ID,
+						ToAccountID:   account_receiver.ID,
+						Amount:        100,
+						Idempotency:   idempotency,
+					})).
+					Times(1).
+					Return(savedResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name:    "Replay",
+			key:     key,
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(savedRecord, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+				require.JSONEq(t, string(replayedResponse), recorder.Body.String())
+			},
+		},
+		{
+			name: "DifferentRequest",
+			key:  key,
+			request: transferRequest{
+				FromAccountID: account_sender.ID,
+				ToAccountID:   account_receiver.ID,
+				Amount:        "2.00",
+				Currency:      account_sender.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(savedRecord, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "ConcurrentRetry",
+			key:     key,
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Any()).
+						Return(savedRecord, nil),
+				)
+				expectAccounts(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrDuplicateIdempotencyKey)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(replayedResponse), recorder.Body.String())
+			},
+		},
+		{
+			name:    "KeyTooLong",
+			key:     util.RandomString(maxIdempotencyKeyLength + 1),
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:    "GetIdempotencyKeyFailed",
+			key:     key,
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+type createTransferFXTestCases struct {
+	name          string
+	request       transferRequest
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+func TestCreateTransferFXApi(t *testing.T) {
+	euro := util.EUR
+	account_sender := randomAccount(&euro)
+
+	dollar := util.USD
+	account_receiver := randomAccount(&dollar)
+
+	canadian := util.CAD
+	account_canadian := randomAccount(&canadian)
+
+	testCases := getCreateTransferFXTestCases(account_sender, account_receiver, account_canadian)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.request)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account_sender.Owner, util.CustomerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func getCreateTransferFXTestCases(account_sender db.Account, account_receiver db.Account, account_canadian db.Account) []createTransferFXTestCases {
+	request := transferRequest{
+		FromAccountID: account_sender.ID,
+		ToAccountID:   account_receiver.ID,
+		Amount:        "10.00",
+		Currency:      util.EUR,
+		ToCurrency:    util.USD,
+	}
+
+	expectAccounts := func(store *mockdb.MockStore, receiver db.Account) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), account_sender.ID).
+			Return(account_sender, nil).
+			Times(1)
+		store.EXPECT().
+			GetAccount(gomock.Any(), receiver.ID).
+			Return(receiver, nil).
+			Times(1)
+	}
+
+	// without stored rates the configured ones are used
+	expectNoStoredRates := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetExchangeRate(gomock.Any(), gomock.Any()).
+			Return(db.ExchangeRate{}, sql.ErrNoRows).
+			AnyTimes()
+	}
+
+	return []createTransferFXTestCases{
+		{
+			name:    "OK",
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				expectNoStoredRates(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account_sender.ID,
+						ToAccountID:   account_receiver.ID,
+						Amount:        1000,
+						FX: &db.TransferFX{
+							Rate:   "1.2500000000",
+							Spread: "0.010000",
+						},
+					})).
+					Return(randomTransferTxResult(account_sender, account_receiver, 1000, 1237), nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "StoredRate",
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				store.EXPECT().
+					GetExchangeRate(gomock.Any(), EqGetExchangeRateParams(util.EUR, util.USD)).
+					Return(db.ExchangeRate{FromCurrency: util.EUR, ToCurrency: util.USD, Rate: "1.1000000000"}, nil).
+					Times(1)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account_sender.ID,
+						ToAccountID:   account_receiver.ID,
+						Amount:        1000,
+						FX: &db.TransferFX{
+							Rate:   "1.1000000000",
+							Spread: "0.010000",
+						},
+					})).
+					Return(randomTransferTxResult(account_sender, account_receiver, 1000, 1237), nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "StoredInverseRate",
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				store.EXPECT().
+					GetExchangeRate(gomock.Any(), EqGetExchangeRateParams(util.EUR, util.USD)).
+					Return(db.ExchangeRate{}, sql.ErrNoRows).
+					Times(1)
+				store.EXPECT().
+					GetExchangeRate(gomock.Any(), EqGetExchangeRateParams(util.USD, util.EUR)).
+					Return(db.ExchangeRate{FromCurrency: util.USD, ToCurrency: util.EUR, Rate: "0.8000000000"}, nil).
+					Times(1)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: account_sender.ID,
+						ToAccountID:   account_receiver.ID,
+						Amount:        1000,
+						FX: &db.TransferFX{
+							Rate:   "1.2500000000",
+							Spread: "0.010000",
+						},
+					})).
+					Return(randomTransferTxResult(account_sender, account_receiver, 1000, 1237), nil).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingToCurrency",
+			request: transferRequest{
+				FromAccountID: account_sender.ID,
+				ToAccountID:   account_receiver.ID,
+				Amount:        "10.00",
+				Currency:      util.EUR,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RateNotFound",
+			request: transferRequest{
+				FromAccountID: account_sender.ID,
+				ToAccountID:   account_canadian.ID,
+				Amount:        "10.00",
+				Currency:      util.EUR,
+				ToCurrency:    util.CAD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_canadian)
+				expectNoStoredRates(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "exchange rate is not available")
+			},
+		},
+		{
+			name:    "ConvertedTooSmall",
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				expectNoStoredRates(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResult{}, db.ErrConvertedTooSmall).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:    "ConvertedOverflow",
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				expectNoStoredRates(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResult{}, util.ErrMoneyOverflow).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:    "CurrencyChanged",
+			request: request,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account_receiver)
+				expectNoStoredRates(store)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Return(db.TransferTxResult{}, db.ErrCurrencyMismatch).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+}
+
+// randomTransferTxResult builds the result of a transfer of amount, credited as toAmount
+func randomTransferTxResult(fromAccount db.Account, toAccount db.Account, amount int64, toAmount int64) db.TransferTxResult {
+	fromAccountID := sql.NullInt64{Int64: fromAccount.ID, Valid: true}
+	toAccountID := sql.NullInt64{Int64: toAccount.ID, Valid: true}
+
+	fromAccount.Balance -= amount
+	toAccount.Balance += toAmount
+
+	return db.TransferTxResult{
+		Transfer: db.Transfer{
+			ID:            int64(util.RandomInt(1, 1000)),
+			FromAccountID: fromAccountID,
+			ToAccountID:   toAccountID,
+			Amount:        amount,
+			ToAmount:      toAmount,
+			Rate:          "1.0000000000",
+			Spread:        "0.000000",
+		},
+		FromAccount: fromAccount,
+		ToAccount:   toAccount,
+		FromEntry:   db.Entry{ID: int64(util.RandomInt(1, 1000)), AccountID: fromAccountID, Amount: -amount},
+		ToEntry:     db.Entry{ID: int64(util.RandomInt(1, 1000)), AccountID: toAccountID, Amount: toAmount},
+	}
+}
+
+func TestCreateTransferAmountNotStringApi(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	account := randomAccount(nil)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Any()).
+		Times(0)
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	body := fmt.Sprintf(`{"from_account_id": %d, "to_account_id": %d, "amount": 1.5, "currency": "%s"}`, account.ID, account.ID+1, account.Currency)
+	request, err := http.NewRequest(http.MethodPost, "/transfers", strings.NewReader(body))
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "must be a string")
 }
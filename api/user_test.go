@@ -2,16 +2,19 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	mockdb "master_class/db/mock"
 	db "master_class/db/sqlc"
+	"master_class/token"
 	"master_class/util"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -46,6 +49,33 @@ func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher
 	return EqCreateUserParamsMatcher{arg: arg, password: password}
 }
 
+type EqUpdateUserPasswordParamsMatcher struct {
+	username string
+	password string
+}
+
+func (e EqUpdateUserPasswordParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.UpdateUserPasswordParams)
+	if !ok {
+		return false
+	}
+
+	if util.CheckPasswordHash(e.password, arg.HashedPassword) != nil {
+		return false
+	}
+
+	changedFor := time.Since(arg.PasswordChangedAt)
+	return arg.Username == e.username && changedFor >= 0 && changedFor < time.Second
+}
+
+func (e EqUpdateUserPasswordParamsMatcher) String() string {
+	return fmt.Sprintf("matches username %v and password %v changed now", e.username, e.password)
+}
+
+func EqUpdateUserPasswordParams(username string, password string) gomock.Matcher {
+	return EqUpdateUserPasswordParamsMatcher{username: username, password: password}
+}
+
 type createUserTestCases struct {
 	name          string
 	request       createUserRequest
@@ -55,7 +85,25 @@ type createUserTestCases struct {
 
 type changeUserPasswordTestCases struct {
 	name          string
+	username      string
 	request       changeUserPasswordRequest
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type loginUserTestCases struct {
+	name          string
+	request       loginUserRequest
+	buildStubs    func(store *mockdb.MockStore)
+	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+}
+
+type updateUserRoleTestCases struct {
+	name          string
+	username      string
+	request       updateUserRoleRequest
+	setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	buildStubs    func(store *mockdb.MockStore)
 	checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 }
@@ -91,9 +139,45 @@ func TestCreateUserAPI(t *testing.T) {
 }
 
 func TestChangeUserPasswordAPI(t *testing.T) {
+	user, password := randomUser()
+
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := getChangeUserPasswordTestCases(user, password)
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.request)
+			require.NoError(t, err)
+
+			url := "/users/" + tc.username + "/password"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminUpdateUserRoleAPI(t *testing.T) {
+	admin := util.RandomOwner()
 	user, _ := randomUser()
 
-	testCases := getChangeUserPasswordTestCases(user)
+	testCases := getUpdateUserRoleTestCases(admin, user)
 
 	for i := range testCases {
 		tc := testCases[i]
@@ -110,7 +194,42 @@ func TestChangeUserPasswordAPI(t *testing.T) {
 			data, err := json.Marshal(tc.request)
 			require.NoError(t, err)
 
-			url := "/users/" + tc.request.Username + "/password"
+			url := "/admin/users/" + tc.username + "/role"
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser()
+
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := getLoginUserTestCases(user, password)
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.request)
+			require.NoError(t, err)
+
+			url := "/users/login"
 			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
 			require.NoError(t, err)
 
@@ -183,19 +302,27 @@ func getCreateUserTestCases(user db.User, password string) []createUserTestCases
 	}
 }
 
-func getChangeUserPasswordTestCases(user db.User) []changeUserPasswordTestCases {
+func getChangeUserPasswordTestCases(user db.User, password string) []changeUserPasswordTestCases {
 	userRequest := changeUserPasswordRequest{
-		Username: user.Username,
-		Password: util.RandomString(6),
+		CurrentPassword: password,
+		Password:        util.RandomString(6),
 	}
 
 	return []changeUserPasswordTestCases{
 		{
-			name:    "ValidRequest",
-			request: userRequest,
+			name:     "ValidRequest",
+			username: user.Username,
+			request:  userRequest,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					AnyTimes().
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), EqUpdateUserPasswordParams(user.Username, userRequest.Password)).
 					Times(1).
 					Return(user, nil)
 			},
@@ -204,20 +331,103 @@ func getChangeUserPasswordTestCases(user db.User) []changeUserPasswordTestCases
 			},
 		},
 		{
-			name: "ValidationError",
+			name:     "ValidationError",
+			username: user.Username,
 			request: changeUserPasswordRequest{
-				Username: user.Username,
-				Password: "short",
+				CurrentPassword: password,
+				Password:        "short",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					AnyTimes().
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
-			buildStubs: func(store *mockdb.MockStore) {},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
 		{
-			name:    "InternalError",
-			request: userRequest,
+			name:     "InvalidUsername",
+			username: "invalid-user%23",
+			request:  userRequest,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					AnyTimes().
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "WrongCurrentPassword",
+			username: user.Username,
+			request: changeUserPasswordRequest{
+				CurrentPassword: password + "wrong",
+				Password:        util.RandomString(6),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					AnyTimes().
+					Return(user, nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "AnotherUser",
+			username: user.Username,
+			request:  userRequest,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq("unauthorized_user")).
+					Times(1).
+					Return(db.User{Username: "unauthorized_user", Role: util.CustomerRole}, nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: user.Username,
+			request:  userRequest,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					AnyTimes().
+					Return(user, nil)
 				store.EXPECT().
 					UpdateUserPassword(gomock.Any(), gomock.Any()).
 					Times(1).
@@ -227,6 +437,256 @@ func getChangeUserPasswordTestCases(user db.User) []changeUserPasswordTestCases
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name:      "NoAuthorization",
+			username:  user.Username,
+			request:   userRequest,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+}
+func getLoginUserTestCases(user db.User, password string) []loginUserTestCases {
+	loginRequest := loginUserRequest{
+		Username: user.Username,
+		Password: password,
+	}
+
+	return []loginUserTestCases{
+		{
+			name:    "OK",
+			request: loginRequest,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{
+							ID:        arg.ID,
+							Username:  arg.Username,
+							ExpiresAt: arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotZero(t, rsp.SessionID)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotZero(t, rsp.AccessTokenExpiresAt)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.NotZero(t, rsp.RefreshTokenExpiresAt)
+				require.Equal(t, user.Username, rsp.User.Username)
+
+				requireBodyMatchUser(t, recorder.Body.String(), user)
+				require.NotContains(t, recorder.Body.String(), "hashed_password")
+			},
+		},
+		{
+			name:    "UserNotFound",
+			request: loginRequest,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			request: loginUserRequest{
+				Username: user.Username,
+				Password: password + "wrong",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:    "InternalError",
+			request: loginRequest,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "ValidationError",
+			request: loginUserRequest{
+				Username: "invalid-user#",
+				Password: "short",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:    "CreateSessionFailed",
+			request: loginRequest,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+}
+
+func getUpdateUserRoleTestCases(admin string, user db.User) []updateUserRoleTestCases {
+	asAdmin := func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+	}
+
+	teller := user
+	teller.Role = util.TellerRole
+
+	return []updateUserRoleTestCases{
+		{
+			name:      "OK",
+			username:  user.Username,
+			request:   updateUserRoleRequest{Role: util.TellerRole},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Eq(db.UpdateUserRoleParams{
+						Username: user.Username,
+						Role:     util.TellerRole,
+					})).
+					Times(1).
+					Return(teller, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body.String(), teller)
+			},
+		},
+		{
+			name:      "InvalidRole",
+			username:  user.Username,
+			request:   updateUserRoleRequest{Role: "manager"},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "OwnRole",
+			username:  admin,
+			request:   updateUserRoleRequest{Role: util.CustomerRole},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "UserNotFound",
+			username:  user.Username,
+			request:   updateUserRoleRequest{Role: util.TellerRole},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "TellerForbidden",
+			username: user.Username,
+			request:  updateUserRoleRequest{Role: util.AdminRole},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.TellerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.TellerRole)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			username:  user.Username,
+			request:   updateUserRoleRequest{Role: util.TellerRole},
+			setupAuth: asAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAuthUserWithRole(store, admin, util.AdminRole)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 	}
 }
 
@@ -235,6 +695,7 @@ func randomUser() (db.User, string) {
 		Username: util.RandomOwner(),
 		FullName: util.RandomOwner(),
 		Email:    util.RandomEmail(),
+		Role:     util.CustomerRole,
 	}, util.RandomString(6)
 }
 
@@ -242,6 +703,7 @@ func requireBodyMatchUser(t *testing.T, body string, user db.User) {
 	require.Contains(t, body, user.Username)
 	require.Contains(t, body, user.FullName)
 	require.Contains(t, body, user.Email)
+	require.Contains(t, body, user.Role)
 	require.Contains(t, body, user.PasswordChangedAt.String())
 	require.Contains(t, body, user.CreatedAt.String())
 	require.NotContains(t, body, "\"Password\":")
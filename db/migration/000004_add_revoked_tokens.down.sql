@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "user_token_revocations";
+DROP TABLE IF EXISTS "revoked_tokens";
+DROP INDEX IF EXISTS "sessions_username_idx";
@@ -0,0 +1,7 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "spread";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "rate";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_amount";
+
+COMMENT ON COLUMN "transfers"."amount" IS 'Must be positive';
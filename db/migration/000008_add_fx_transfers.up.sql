@@ -0,0 +1,17 @@
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "rate" numeric(20,10) NOT NULL DEFAULT 1;
+
+ALTER TABLE "transfers" ADD COLUMN "spread" numeric(10,6) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'Must be positive, in the currency of the source account';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'Amount credited, in the currency of the destination account';
+
+COMMENT ON COLUMN "transfers"."rate" IS 'Units of the destination currency bought by one unit of the source currency';
+
+COMMENT ON COLUMN "transfers"."spread" IS 'Fraction of the converted amount kept by the bank';
@@ -0,0 +1,19 @@
+CREATE TABLE "exchange_rates" (
+  "id" bigserial PRIMARY KEY,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" numeric(20,10) NOT NULL,
+  "valid_from" timestamptz NOT NULL,
+  "source" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "exchange_rates_positive_rate" CHECK ("rate" > 0),
+  CONSTRAINT "exchange_rates_distinct_currencies" CHECK ("from_currency" <> "to_currency")
+);
+
+CREATE UNIQUE INDEX ON "exchange_rates" ("from_currency", "to_currency", "valid_from");
+
+COMMENT ON COLUMN "exchange_rates"."rate" IS 'Units of the to currency bought by one unit of the from currency';
+
+COMMENT ON COLUMN "exchange_rates"."valid_from" IS 'The rate applies from this time until a later rate of the pair takes over';
+
+COMMENT ON COLUMN "exchange_rates"."source" IS 'Where the rate comes from, such as a feed or the admin who uploaded it';
@@ -0,0 +1,38 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "execute_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "failure_reason" varchar,
+  "executed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "scheduled_transfers_positive_amount" CHECK ("amount" > 0),
+  CONSTRAINT "scheduled_transfers_valid_status" CHECK ("status" IN ('pending', 'succeeded', 'failed', 'cancelled'))
+);
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+CREATE INDEX ON "scheduled_transfers" ("execute_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "scheduled_transfers"."amount" IS 'Must be positive, in the currency of the source account';
+
+COMMENT ON COLUMN "scheduled_transfers"."currency" IS 'Currency of the source account when the transfer was scheduled';
+
+COMMENT ON COLUMN "scheduled_transfers"."status" IS 'pending until executed, then succeeded or failed; cancelled by the owner';
+
+COMMENT ON COLUMN "scheduled_transfers"."transfer_id" IS 'Transfer made by a successful execution';
+
+COMMENT ON COLUMN "scheduled_transfers"."failure_reason" IS 'Why the execution failed';
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
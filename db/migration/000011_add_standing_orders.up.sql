@@ -0,0 +1,77 @@
+CREATE TABLE "standing_orders" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "frequency" varchar NOT NULL,
+  "interval_count" int NOT NULL DEFAULT 1,
+  "day_of_month" int,
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "max_occurrences" int,
+  "insufficient_funds_policy" varchar NOT NULL DEFAULT 'skip',
+  "status" varchar NOT NULL DEFAULT 'active',
+  "occurrences" int NOT NULL DEFAULT 0,
+  "retry_count" int NOT NULL DEFAULT 0,
+  "next_run_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "standing_orders_positive_amount" CHECK ("amount" > 0),
+  CONSTRAINT "standing_orders_valid_frequency" CHECK ("frequency" IN ('daily', 'weekly', 'monthly')),
+  CONSTRAINT "standing_orders_positive_interval" CHECK ("interval_count" > 0),
+  CONSTRAINT "standing_orders_valid_day_of_month" CHECK ("day_of_month" BETWEEN 1 AND 31),
+  CONSTRAINT "standing_orders_positive_max_occurrences" CHECK ("max_occurrences" > 0),
+  CONSTRAINT "standing_orders_valid_policy" CHECK ("insufficient_funds_policy" IN ('skip', 'retry', 'suspend')),
+  CONSTRAINT "standing_orders_valid_status" CHECK ("status" IN ('active', 'paused', 'suspended', 'completed', 'cancelled'))
+);
+
+CREATE TABLE "standing_order_runs" (
+  "id" bigserial PRIMARY KEY,
+  "standing_order_id" bigint NOT NULL,
+  "occurrence" int NOT NULL,
+  "scheduled_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL,
+  "transfer_id" bigint,
+  "failure_reason" varchar,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "standing_order_runs_valid_status" CHECK ("status" IN ('succeeded', 'skipped', 'retrying', 'suspended', 'failed'))
+);
+
+CREATE INDEX ON "standing_orders" ("owner");
+
+CREATE INDEX ON "standing_orders" ("next_run_at") WHERE "status" = 'active';
+
+CREATE INDEX ON "standing_order_runs" ("standing_order_id");
+
+COMMENT ON COLUMN "standing_orders"."amount" IS 'Must be positive, in the currency of the source account';
+
+COMMENT ON COLUMN "standing_orders"."currency" IS 'Currency of the source account when the order was created';
+
+COMMENT ON COLUMN "standing_orders"."interval_count" IS 'Number of days, weeks or months between occurrences';
+
+COMMENT ON COLUMN "standing_orders"."day_of_month" IS 'Day of monthly occurrences, the day of start_at when null';
+
+COMMENT ON COLUMN "standing_orders"."insufficient_funds_policy" IS 'skip the occurrence, retry it later, or suspend the order';
+
+COMMENT ON COLUMN "standing_orders"."status" IS 'active, paused by the owner, suspended for lack of funds, completed or cancelled';
+
+COMMENT ON COLUMN "standing_orders"."occurrences" IS 'Number of occurrences done, skipped or given up; index of the next one';
+
+COMMENT ON COLUMN "standing_orders"."retry_count" IS 'Retries of the next occurrence for lack of funds';
+
+COMMENT ON COLUMN "standing_orders"."next_run_at" IS 'When the next occurrence or retry runs, null once the order ended';
+
+COMMENT ON COLUMN "standing_order_runs"."occurrence" IS 'Index of the occurrence, counting from 0';
+
+COMMENT ON COLUMN "standing_order_runs"."status" IS 'succeeded, skipped, retrying, suspended or failed';
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("standing_order_id") REFERENCES "standing_orders" ("id");
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,3 @@
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "transfer_id";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reversal_of";
@@ -0,0 +1,25 @@
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+-- entries were written in the transaction of their transfer, so they share its timestamp
+UPDATE "entries" AS e
+SET "transfer_id" = t."id"
+FROM "transfers" AS t
+WHERE e."created_at" = t."created_at"
+  AND (
+    (e."account_id" = t."from_account_id" AND e."amount" = -t."amount") OR
+    (e."account_id" = t."to_account_id" AND e."amount" = t."to_amount")
+  );
+
+CREATE INDEX ON "transfers" ("reversal_of");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'Transfer refunded, in whole or in part, by this one';
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'Transfer that wrote the entry';
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
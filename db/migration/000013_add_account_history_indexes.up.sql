@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS "entries_account_id_idx";
+
+DROP INDEX IF EXISTS "transfers_from_account_id_idx";
+
+DROP INDEX IF EXISTS "transfers_to_account_id_idx";
+
+CREATE INDEX ON "entries" ("account_id", "id");
+
+CREATE INDEX ON "transfers" ("from_account_id", "id");
+
+CREATE INDEX ON "transfers" ("to_account_id", "id");
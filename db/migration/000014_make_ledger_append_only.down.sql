@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS "transfers_no_truncate" ON "transfers";
+
+DROP TRIGGER IF EXISTS "transfers_append_only" ON "transfers";
+
+DROP TRIGGER IF EXISTS "entries_no_truncate" ON "entries";
+
+DROP TRIGGER IF EXISTS "entries_append_only" ON "entries";
+
+DROP FUNCTION IF EXISTS "reject_ledger_mutation"();
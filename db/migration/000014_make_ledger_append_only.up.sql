@@ -0,0 +1,23 @@
+CREATE FUNCTION "reject_ledger_mutation"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION '% is append-only, % is not allowed', TG_TABLE_NAME, TG_OP
+    USING ERRCODE = 'restrict_violation',
+          HINT = 'correct the ledger with compensating entries';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "entries_append_only"
+BEFORE UPDATE OR DELETE ON "entries"
+FOR EACH ROW EXECUTE FUNCTION "reject_ledger_mutation"();
+
+CREATE TRIGGER "entries_no_truncate"
+BEFORE TRUNCATE ON "entries"
+FOR EACH STATEMENT EXECUTE FUNCTION "reject_ledger_mutation"();
+
+CREATE TRIGGER "transfers_append_only"
+BEFORE UPDATE OR DELETE ON "transfers"
+FOR EACH ROW EXECUTE FUNCTION "reject_ledger_mutation"();
+
+CREATE TRIGGER "transfers_no_truncate"
+BEFORE TRUNCATE ON "transfers"
+FOR EACH STATEMENT EXECUTE FUNCTION "reject_ledger_mutation"();
@@ -0,0 +1,53 @@
+CREATE TABLE "transfer_batches" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "mode" varchar NOT NULL,
+  "status" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "transfer_batches_valid_mode" CHECK ("mode" IN ('atomic', 'best_effort')),
+  CONSTRAINT "transfer_batches_valid_status" CHECK ("status" IN ('succeeded', 'partially_succeeded', 'failed'))
+);
+
+CREATE TABLE "transfer_batch_lines" (
+  "batch_id" bigint NOT NULL,
+  "line_number" int NOT NULL,
+  "reference" varchar NOT NULL DEFAULT '',
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "status" varchar NOT NULL,
+  "transfer_id" bigint,
+  "failure_reason" varchar,
+  PRIMARY KEY ("batch_id", "line_number"),
+  CONSTRAINT "transfer_batch_lines_positive_amount" CHECK ("amount" > 0),
+  CONSTRAINT "transfer_batch_lines_valid_status" CHECK ("status" IN ('succeeded', 'failed', 'skipped'))
+);
+
+CREATE INDEX ON "transfer_batches" ("owner");
+
+COMMENT ON COLUMN "transfer_batches"."mode" IS 'atomic runs all the lines or none, best_effort runs each line on its own';
+
+COMMENT ON COLUMN "transfer_batches"."status" IS 'succeeded when all the lines did, failed when none did, partially_succeeded otherwise';
+
+COMMENT ON COLUMN "transfer_batch_lines"."line_number" IS 'Position of the line in the uploaded file, counting from 1';
+
+COMMENT ON COLUMN "transfer_batch_lines"."reference" IS 'Reference of the line given in the file, such as an end to end id';
+
+COMMENT ON COLUMN "transfer_batch_lines"."from_account_id" IS 'As given in the file, the account may not exist';
+
+COMMENT ON COLUMN "transfer_batch_lines"."to_account_id" IS 'As given in the file, the account may not exist';
+
+COMMENT ON COLUMN "transfer_batch_lines"."amount" IS 'Must be positive, in the currency of the line';
+
+COMMENT ON COLUMN "transfer_batch_lines"."status" IS 'succeeded or failed, skipped when an atomic batch failed on another line';
+
+COMMENT ON COLUMN "transfer_batch_lines"."transfer_id" IS 'Transfer made for a successful line';
+
+COMMENT ON COLUMN "transfer_batch_lines"."failure_reason" IS 'Why the line failed validation or execution';
+
+ALTER TABLE "transfer_batches" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_batch_lines" ADD FOREIGN KEY ("batch_id") REFERENCES "transfer_batches" ("id");
+
+ALTER TABLE "transfer_batch_lines" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,3 @@
+ALTER TABLE "scheduled_transfers" DROP COLUMN IF EXISTS "retry_at";
+
+ALTER TABLE "scheduled_transfers" DROP COLUMN IF EXISTS "attempts";
@@ -0,0 +1,7 @@
+ALTER TABLE "scheduled_transfers" ADD COLUMN "attempts" int NOT NULL DEFAULT 0;
+
+ALTER TABLE "scheduled_transfers" ADD COLUMN "retry_at" timestamptz;
+
+COMMENT ON COLUMN "scheduled_transfers"."attempts" IS 'Executions that failed on the store, the transfer fails once they run out';
+
+COMMENT ON COLUMN "scheduled_transfers"."retry_at" IS 'When the execution is retried after a failed attempt, null until one failed';
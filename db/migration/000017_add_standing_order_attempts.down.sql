@@ -0,0 +1 @@
+ALTER TABLE "standing_orders" DROP COLUMN IF EXISTS "attempts";
@@ -0,0 +1,3 @@
+ALTER TABLE "standing_orders" ADD COLUMN "attempts" int NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "standing_orders"."attempts" IS 'Runs of the next occurrence that failed on the store, it is given up once they run out';
@@ -0,0 +1,3 @@
+ALTER TABLE "idempotency_keys" ADD COLUMN "status_code" int NOT NULL DEFAULT 200;
+
+COMMENT ON COLUMN "idempotency_keys"."status_code" IS 'HTTP status the response was first sent with, replayed with it';
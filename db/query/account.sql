@@ -16,11 +16,18 @@ SELECT * FROM accounts WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE;
 -- name: ListAccounts :many
 SELECT * FROM accounts ORDER BY id LIMIT $1 OFFSET $2;
 
--- name: UpdateAccountBalance :one
-UPDATE accounts SET balance = $2 WHERE id = $1 RETURNING *;
+-- name: ListAccountsByOwner :many
+SELECT * FROM accounts WHERE owner = $1 ORDER BY id LIMIT $2 OFFSET $3;
 
 -- name: UpdateAccount :one
-UPDATE accounts SET owner = $2, currency = $3 WHERE id = $1 RETURNING *;
+UPDATE accounts SET currency = $2
+WHERE id = $1
+AND balance = 0
+AND NOT EXISTS (SELECT 1 FROM entries WHERE entries.account_id = accounts.id)
+RETURNING *;
+
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts SET overdraft_limit = $2 WHERE id = $1 RETURNING *;
 
 -- name: AddAccountBalance :one
 UPDATE accounts SET balance = balance + sqlc.arg(amount) WHERE id = sqlc.arg(id) RETURNING *;
@@ -1,19 +1,61 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 ) RETURNING *;
 
 -- name: GetEntry :one
 SELECT * FROM entries WHERE id = $1 LIMIT 1;
 
--- name: ListEntries :many
-SELECT * FROM entries ORDER BY id LIMIT $1 OFFSET $2;
+-- name: AccountHasEntries :one
+SELECT EXISTS (SELECT 1 FROM entries WHERE account_id = $1)::boolean AS has_entries;
+
+-- name: GetAccountBalanceAt :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS balance
+FROM entries
+WHERE account_id = sqlc.arg(account_id)::bigint
+AND created_at < sqlc.arg(at)::timestamptz;
 
--- name: UpdateEntry :one
-UPDATE entries SET amount = $2 WHERE id = $1 RETURNING *;
+-- name: ListAccountEntries :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)::bigint
+AND (
+    sqlc.arg(direction)::text = 'all' OR
+    (sqlc.arg(direction)::text = 'in' AND amount > 0) OR
+    (sqlc.arg(direction)::text = 'out' AND amount < 0)
+)
+AND (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time)::timestamptz)
+AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time)::timestamptz)
+AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount)::bigint)
+AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount)::bigint)
+AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id)::bigint)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_size);
 
--- name: DeleteEntry :exec
-DELETE FROM entries WHERE id = $1;
\ No newline at end of file
+-- name: ListAccountStatementEntries :many
+WITH opening AS (
+    SELECT COALESCE(SUM(amount), 0)::bigint AS balance
+    FROM entries
+    WHERE account_id = sqlc.arg(account_id)::bigint
+    AND created_at < sqlc.arg(from_time)::timestamptz
+)
+SELECT
+    sqlc.embed(entries),
+    (opening.balance + SUM(entries.amount) OVER (ORDER BY entries.created_at, entries.id))::bigint AS running_balance,
+    COALESCE(
+        CASE WHEN transfers.from_account_id = entries.account_id THEN transfers.to_account_id ELSE transfers.from_account_id END,
+        0
+    )::bigint AS counterparty_account_id
+FROM entries
+CROSS JOIN opening
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+WHERE entries.account_id = sqlc.arg(account_id)::bigint
+AND entries.created_at >= sqlc.arg(from_time)::timestamptz
+AND entries.created_at < sqlc.arg(to_time)::timestamptz
+ORDER BY entries.created_at, entries.id;
+
+-- name: ListEntries :many
+SELECT * FROM entries ORDER BY id LIMIT $1 OFFSET $2;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- name: CreateExchangeRate :one
+INSERT INTO exchange_rates (
+    from_currency,
+    to_currency,
+    rate,
+    valid_from,
+    source
+) VALUES (
+    $1, $2, $3, $4, $5
+) ON CONFLICT (from_currency, to_currency, valid_from) DO UPDATE
+SET rate = EXCLUDED.rate, source = EXCLUDED.source
+RETURNING *;
+
+-- name: GetExchangeRate :one
+SELECT * FROM exchange_rates
+WHERE from_currency = sqlc.arg(from_currency)
+  AND to_currency = sqlc.arg(to_currency)
+  AND valid_from <= sqlc.arg(at)
+ORDER BY valid_from DESC
+LIMIT 1;
+
+-- name: ListExchangeRates :many
+SELECT DISTINCT ON (from_currency, to_currency) * FROM exchange_rates
+WHERE valid_from <= sqlc.arg(at)
+ORDER BY from_currency, to_currency, valid_from DESC;
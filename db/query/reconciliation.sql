@@ -0,0 +1,36 @@
+-- name: ListAccountBalanceChecks :many
+SELECT
+    accounts.id,
+    accounts.currency,
+    accounts.balance,
+    COALESCE(SUM(entries.amount), 0)::bigint AS entries_total
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+WHERE accounts.id > sqlc.arg(after_id)::bigint
+GROUP BY accounts.id
+ORDER BY accounts.id
+LIMIT sqlc.arg(batch_size);
+
+-- name: GetAccountEntriesTotal :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS entries_total
+FROM entries
+WHERE account_id = sqlc.arg(account_id)::bigint;
+
+-- name: ListTransferEntryChecks :many
+SELECT
+    transfers.id,
+    transfers.from_account_id,
+    transfers.to_account_id,
+    COUNT(entries.id)::int AS entry_count,
+    (COUNT(entries.id) FILTER (
+        WHERE entries.account_id = transfers.from_account_id AND entries.amount = -transfers.amount
+    ))::int AS debit_count,
+    (COUNT(entries.id) FILTER (
+        WHERE entries.account_id = transfers.to_account_id AND entries.amount = transfers.to_amount
+    ))::int AS credit_count
+FROM transfers
+LEFT JOIN entries ON entries.transfer_id = transfers.id
+WHERE transfers.id > sqlc.arg(after_id)::bigint
+GROUP BY transfers.id
+ORDER BY transfers.id
+LIMIT sqlc.arg(batch_size);
@@ -0,0 +1,46 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    execute_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY execute_at, id
+LIMIT $2
+OFFSET $3;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers SET status = 'cancelled'
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: ClaimDueScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE status = 'pending' AND execute_at <= sqlc.arg(now)
+AND (retry_at IS NULL OR retry_at <= sqlc.arg(now))
+ORDER BY execute_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CompleteScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = $2, transfer_id = $3, failure_reason = $4, executed_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RetryScheduledTransfer :one
+UPDATE scheduled_transfers
+SET attempts = attempts + 1, retry_at = $2, failure_reason = $3
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
@@ -0,0 +1,72 @@
+-- name: CreateStandingOrder :one
+INSERT INTO standing_orders (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    frequency,
+    interval_count,
+    day_of_month,
+    start_at,
+    end_at,
+    max_occurrences,
+    insufficient_funds_policy,
+    next_run_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+) RETURNING *;
+
+-- name: GetStandingOrder :one
+SELECT * FROM standing_orders WHERE id = $1 LIMIT 1;
+
+-- name: GetStandingOrderForUpdate :one
+SELECT * FROM standing_orders WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE;
+
+-- name: ListStandingOrders :many
+SELECT * FROM standing_orders
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateStandingOrder :one
+UPDATE standing_orders
+SET
+    amount = $2,
+    end_at = $3,
+    max_occurrences = $4,
+    insufficient_funds_policy = $5,
+    status = $6,
+    occurrences = $7,
+    retry_count = $8,
+    next_run_at = $9,
+    attempts = $10
+WHERE id = $1
+RETURNING *;
+
+-- name: ClaimDueStandingOrder :one
+SELECT * FROM standing_orders
+WHERE status = 'active' AND next_run_at <= sqlc.arg(now)
+ORDER BY next_run_at, id
+LIMIT 1
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: CreateStandingOrderRun :one
+INSERT INTO standing_order_runs (
+    standing_order_id,
+    occurrence,
+    scheduled_at,
+    status,
+    transfer_id,
+    failure_reason
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListStandingOrderRuns :many
+SELECT * FROM standing_order_runs
+WHERE standing_order_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
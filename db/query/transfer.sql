@@ -2,19 +2,44 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    rate,
+    spread,
+    reversal_of
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers WHERE id = $1 LIMIT 1;
 
--- name: ListTransfers :many
-SELECT * FROM transfers ORDER BY id LIMIT $1 OFFSET $2;
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE;
+
+-- name: GetTransferRefunds :one
+SELECT
+    COALESCE(SUM(amount), 0)::bigint AS refunded,
+    COALESCE(SUM(to_amount), 0)::bigint AS credited
+FROM transfers
+WHERE reversal_of = sqlc.arg(transfer_id)::bigint;
 
--- name: UpdateTransfer :one
-UPDATE transfers SET amount = $2 WHERE id = $1 RETURNING *;
+-- name: ListAccountTransfers :many
+SELECT sqlc.embed(transfers), from_accounts.currency AS from_currency, to_accounts.currency AS to_currency
+FROM transfers
+JOIN accounts AS from_accounts ON from_accounts.id = transfers.from_account_id
+JOIN accounts AS to_accounts ON to_accounts.id = transfers.to_account_id
+WHERE (
+    (transfers.from_account_id = sqlc.arg(account_id)::bigint AND sqlc.arg(direction)::text IN ('all', 'out')) OR
+    (transfers.to_account_id = sqlc.arg(account_id)::bigint AND sqlc.arg(direction)::text IN ('all', 'in'))
+)
+AND (sqlc.narg(from_time)::timestamptz IS NULL OR transfers.created_at >= sqlc.narg(from_time)::timestamptz)
+AND (sqlc.narg(to_time)::timestamptz IS NULL OR transfers.created_at < sqlc.narg(to_time)::timestamptz)
+AND (sqlc.narg(min_amount)::bigint IS NULL OR CASE WHEN transfers.from_account_id = sqlc.arg(account_id)::bigint THEN transfers.amount ELSE transfers.to_amount END >= sqlc.narg(min_amount)::bigint)
+AND (sqlc.narg(max_amount)::bigint IS NULL OR CASE WHEN transfers.from_account_id = sqlc.arg(account_id)::bigint THEN transfers.amount ELSE transfers.to_amount END <= sqlc.narg(max_amount)::bigint)
+AND (sqlc.narg(before_id)::bigint IS NULL OR transfers.id < sqlc.narg(before_id)::bigint)
+ORDER BY transfers.id DESC
+LIMIT sqlc.arg(page_size);
 
--- name: DeleteTransfer :exec
-DELETE FROM transfers WHERE id = $1;
\ No newline at end of file
+-- name: ListTransfers :many
+SELECT * FROM transfers ORDER BY id LIMIT $1 OFFSET $2;
\ No newline at end of file
@@ -0,0 +1,32 @@
+-- name: CreateTransferBatch :one
+INSERT INTO transfer_batches (
+    owner,
+    mode,
+    status
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetTransferBatch :one
+SELECT * FROM transfer_batches WHERE id = $1 LIMIT 1;
+
+-- name: CreateTransferBatchLine :one
+INSERT INTO transfer_batch_lines (
+    batch_id,
+    line_number,
+    reference,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    status,
+    transfer_id,
+    failure_reason
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: ListTransferBatchLines :many
+SELECT * FROM transfer_batch_lines
+WHERE batch_id = $1
+ORDER BY line_number;
@@ -12,4 +12,7 @@ INSERT INTO users (
 SELECT * FROM users WHERE username = $1 LIMIT 1;
 
 -- name: UpdateUserPassword :one
-UPDATE users SET hashed_password = $2, password_changed_at = NOW() WHERE username = $1 RETURNING *;
+UPDATE users SET hashed_password = $2, password_changed_at = $3 WHERE username = $1 RETURNING *;
+
+-- name: UpdateUserRole :one
+UPDATE users SET role = $2 WHERE username = $1 RETURNING *;
@@ -11,12 +11,16 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomMoney(), util.RandomCurrency())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64, currency string) Account {
 	user := createRandomUser(t)
 
 	arg := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Balance:  balance,
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -26,6 +30,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Zero(t, account.OverdraftLimit)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -50,21 +55,21 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, generatedAccount.CreatedAt, accountFromDb.CreatedAt, time.Second)
 }
 
-func TestUpdateAccount(t *testing.T) {
+func TestAddAccountBalance(t *testing.T) {
 	generatedAccount := createRandomAccount(t)
 
-	arg := UpdateAccountBalanceParams{
-		ID:      generatedAccount.ID,
-		Balance: util.RandomMoney(),
+	arg := AddAccountBalanceParams{
+		ID:     generatedAccount.ID,
+		Amount: util.RandomMoney(),
 	}
 
-	updatedAccount, err := testQueries.UpdateAccountBalance(context.Background(), arg)
+	updatedAccount, err := testQueries.AddAccountBalance(context.Background(), arg)
 	require.NoError(t, err)
 	require.NotEmpty(t, updatedAccount)
 
 	require.Equal(t, generatedAccount.ID, updatedAccount.ID)
 	require.Equal(t, generatedAccount.Owner, updatedAccount.Owner)
-	require.Equal(t, arg.Balance, updatedAccount.Balance)
+	require.Equal(t, generatedAccount.Balance+arg.Amount, updatedAccount.Balance)
 	require.Equal(t, generatedAccount.Currency, updatedAccount.Currency)
 	require.WithinDuration(t, generatedAccount.CreatedAt, updatedAccount.CreatedAt, time.Second)
 }
@@ -99,3 +104,44 @@ func TestListAccounts(t *testing.T) {
 		require.NotEmpty(t, account)
 	}
 }
+
+func TestListAccountsByOwner(t *testing.T) {
+	var lastAccount Account
+	for i := 0; i < 10; i++ {
+		lastAccount = createRandomAccount(t)
+	}
+
+	arg := ListAccountsByOwnerParams{
+		Owner:  lastAccount.Owner,
+		Limit:  5,
+		Offset: 0,
+	}
+
+	accounts, err := testQueries.ListAccountsByOwner(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, accounts)
+
+	for _, account := range accounts {
+		require.NotEmpty(t, account)
+		require.Equal(t, lastAccount.Owner, account.Owner)
+	}
+}
+
+func TestUpdateAccountOverdraftLimit(t *testing.T) {
+	account := createRandomAccount(t)
+
+	arg := UpdateAccountOverdraftLimitParams{
+		ID:             account.ID,
+		OverdraftLimit: util.RandomMoney(),
+	}
+
+	updatedAccount, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, updatedAccount.ID)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+	require.Equal(t, arg.OverdraftLimit, updatedAccount.OverdraftLimit)
+
+	arg.OverdraftLimit = -1
+	_, err = testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
+	require.Error(t, err)
+}
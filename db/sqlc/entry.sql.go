@@ -6,45 +6,70 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
+const accountHasEntries = `-- name: AccountHasEntries :one
+SELECT EXISTS (SELECT 1 FROM entries WHERE account_id = $1)::boolean AS has_entries
+`
+
+func (q *Queries) AccountHasEntries(ctx context.Context, accountID int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, accountHasEntries, accountID)
+	var has_entries bool
+	err := row.Scan(&has_entries)
+	return has_entries, err
+}
+
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    transfer_id
 ) VALUES (
-    $1, $2
-) RETURNING id, account_id, amount, created_at
+    $1, $2, $3
+) RETURNING id, account_id, amount, created_at, transfer_id
 `
 
 type CreateEntryParams struct {
-	AccountID sql.NullInt64 `json:"account_id"`
-	Amount    int64         `json:"amount"`
+	AccountID  sql.NullInt64 `json:"account_id"`
+	Amount     int64         `json:"amount"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount, arg.TransferID)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
 	)
 	return i, err
 }
 
-const deleteEntry = `-- name: DeleteEntry :exec
-DELETE FROM entries WHERE id = $1
+const getAccountBalanceAt = `-- name: GetAccountBalanceAt :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS balance
+FROM entries
+WHERE account_id = $1::bigint
+AND created_at < $2::timestamptz
 `
 
-func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteEntry, id)
-	return err
+type GetAccountBalanceAtParams struct {
+	AccountID int64     `json:"account_id"`
+	At        time.Time `json:"at"`
+}
+
+func (q *Queries) GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAccountBalanceAt, arg.AccountID, arg.At)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries WHERE id = $1 LIMIT 1
+SELECT id, account_id, amount, created_at, transfer_id FROM entries WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
@@ -55,21 +80,50 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
 	)
 	return i, err
 }
 
-const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries ORDER BY id LIMIT $1 OFFSET $2
+const listAccountEntries = `-- name: ListAccountEntries :many
+SELECT id, account_id, amount, created_at, transfer_id FROM entries
+WHERE account_id = $1::bigint
+AND (
+    $2::text = 'all' OR
+    ($2::text = 'in' AND amount > 0) OR
+    ($2::text = 'out' AND amount < 0)
+)
+AND ($3::timestamptz IS NULL OR created_at >= $3::timestamptz)
+AND ($4::timestamptz IS NULL OR created_at < $4::timestamptz)
+AND ($5::bigint IS NULL OR abs(amount) >= $5::bigint)
+AND ($6::bigint IS NULL OR abs(amount) <= $6::bigint)
+AND ($7::bigint IS NULL OR id < $7::bigint)
+ORDER BY id DESC
+LIMIT $8
 `
 
-type ListEntriesParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+type ListAccountEntriesParams struct {
+	AccountID int64         `json:"account_id"`
+	Direction string        `json:"direction"`
+	FromTime  sql.NullTime  `json:"from_time"`
+	ToTime    sql.NullTime  `json:"to_time"`
+	MinAmount sql.NullInt64 `json:"min_amount"`
+	MaxAmount sql.NullInt64 `json:"max_amount"`
+	BeforeID  sql.NullInt64 `json:"before_id"`
+	PageSize  int32         `json:"page_size"`
 }
 
-func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
-	rows, err := q.db.QueryContext(ctx, listEntries, arg.Limit, arg.Offset)
+func (q *Queries) ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntries,
+		arg.AccountID,
+		arg.Direction,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.BeforeID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +136,7 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -96,23 +151,106 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	return items, nil
 }
 
-const updateEntry = `-- name: UpdateEntry :one
-UPDATE entries SET amount = $2 WHERE id = $1 RETURNING id, account_id, amount, created_at
+const listAccountStatementEntries = `-- name: ListAccountStatementEntries :many
+WITH opening AS (
+    SELECT COALESCE(SUM(amount), 0)::bigint AS balance
+    FROM entries
+    WHERE account_id = $1::bigint
+    AND created_at < $2::timestamptz
+)
+SELECT
+    entries.id, entries.account_id, entries.amount, entries.created_at, entries.transfer_id,
+    (opening.balance + SUM(entries.amount) OVER (ORDER BY entries.created_at, entries.id))::bigint AS running_balance,
+    COALESCE(
+        CASE WHEN transfers.from_account_id = entries.account_id THEN transfers.to_account_id ELSE transfers.from_account_id END,
+        0
+    )::bigint AS counterparty_account_id
+FROM entries
+CROSS JOIN opening
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+WHERE entries.account_id = $1::bigint
+AND entries.created_at >= $2::timestamptz
+AND entries.created_at < $3::timestamptz
+ORDER BY entries.created_at, entries.id
 `
 
-type UpdateEntryParams struct {
-	ID     int64 `json:"id"`
-	Amount int64 `json:"amount"`
+type ListAccountStatementEntriesParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
 }
 
-func (q *Queries) UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, updateEntry, arg.ID, arg.Amount)
-	var i Entry
-	err := row.Scan(
-		&i.ID,
-		&i.AccountID,
-		&i.Amount,
-		&i.CreatedAt,
-	)
-	return i, err
+type ListAccountStatementEntriesRow struct {
+	Entry                 Entry `json:"entry"`
+	RunningBalance        int64 `json:"running_balance"`
+	CounterpartyAccountID int64 `json:"counterparty_account_id"`
+}
+
+func (q *Queries) ListAccountStatementEntries(ctx context.Context, arg ListAccountStatementEntriesParams) ([]ListAccountStatementEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatementEntries, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementEntriesRow{}
+	for rows.Next() {
+		var i ListAccountStatementEntriesRow
+		if err := rows.Scan(
+			&i.Entry.ID,
+			&i.Entry.AccountID,
+			&i.Entry.Amount,
+			&i.Entry.CreatedAt,
+			&i.Entry.TransferID,
+			&i.RunningBalance,
+			&i.CounterpartyAccountID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntries = `-- name: ListEntries :many
+SELECT id, account_id, amount, created_at, transfer_id FROM entries ORDER BY id LIMIT $1 OFFSET $2
+`
+
+type ListEntriesParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntries, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
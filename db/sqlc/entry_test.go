@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,6 +45,20 @@ func TestGetEntry(t *testing.T) {
 	require.WithinDuration(t, entry1.CreatedAt, entry2.CreatedAt, time.Second)
 }
 
+func TestAccountHasEntries(t *testing.T) {
+	account := createRandomAccount(t)
+
+	hasEntries, err := testQueries.AccountHasEntries(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.False(t, hasEntries)
+
+	createRandomEntry(t, account)
+
+	hasEntries, err = testQueries.AccountHasEntries(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.True(t, hasEntries)
+}
+
 func TestListEntries(t *testing.T) {
 	account := createRandomAccount(t)
 
@@ -65,32 +80,130 @@ func TestListEntries(t *testing.T) {
 	}
 }
 
-func TestUpdateEntry(t *testing.T) {
-	entry := createRandomEntry(t, createRandomAccount(t))
+func TestListAccountEntries(t *testing.T) {
+	account := createRandomAccount(t)
+
+	credit := createRandomEntry(t, account)
+	debit, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+		Amount:    -credit.Amount - 1,
+	})
+	require.NoError(t, err)
+
+	// entries of other accounts are left out
+	createRandomEntry(t, createRandomAccount(t))
+
+	arg := ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: "all",
+		PageSize:  5,
+	}
+
+	entries, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, debit.ID, entries[0].ID)
+	require.Equal(t, credit.ID, entries[1].ID)
+
+	arg.BeforeID = sql.NullInt64{Int64: debit.ID, Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, credit.ID, entries[0].ID)
 
-	arg := UpdateEntryParams{
-		ID:     entry.ID,
-		Amount: util.RandomMoney(),
+	arg = ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: "out",
+		PageSize:  5,
 	}
 
-	updatedEntry, err := testQueries.UpdateEntry(context.Background(), arg)
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
 	require.NoError(t, err)
-	require.NotEmpty(t, updatedEntry)
+	require.Len(t, entries, 1)
+	require.Equal(t, debit.ID, entries[0].ID)
 
-	require.Equal(t, entry.ID, updatedEntry.ID)
-	require.Equal(t, entry.AccountID, updatedEntry.AccountID)
-	require.Equal(t, arg.Amount, updatedEntry.Amount)
-	require.WithinDuration(t, entry.CreatedAt, updatedEntry.CreatedAt, time.Second)
+	// amount bounds apply to the absolute amount
+	arg.Direction = "all"
+	arg.MinAmount = sql.NullInt64{Int64: credit.Amount + 1, Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, debit.ID, entries[0].ID)
+
+	arg.MinAmount = sql.NullInt64{}
+	arg.ToTime = sql.NullTime{Time: credit.CreatedAt.Add(-time.Hour), Valid: true}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, entries)
 }
 
-func TestDeleteEntry(t *testing.T) {
-	entry := createRandomEntry(t, createRandomAccount(t))
+func TestAccountStatement(t *testing.T) {
+	account := createRandomAccount(t)
 
-	err := testQueries.DeleteEntry(context.Background(), entry.ID)
+	entries := make([]Entry, 3)
+	for i := range entries {
+		entries[i] = createRandomEntry(t, account)
+	}
+	end := entries[2].CreatedAt.Add(time.Minute)
+
+	balance, err := testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        entries[0].CreatedAt,
+	})
+	require.NoError(t, err)
+	require.Zero(t, balance)
+
+	balance, err = testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        end,
+	})
+	require.NoError(t, err)
+	require.Equal(t, entries[0].Amount+entries[1].Amount+entries[2].Amount, balance)
+
+	// the running balance carries the entries made before the period
+	rows, err := testQueries.ListAccountStatementEntries(context.Background(), ListAccountStatementEntriesParams{
+		AccountID: account.ID,
+		FromTime:  entries[1].CreatedAt,
+		ToTime:    end,
+	})
 	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, entries[1].ID, rows[0].Entry.ID)
+	require.Equal(t, entries[0].Amount+entries[1].Amount, rows[0].RunningBalance)
+	require.Zero(t, rows[0].CounterpartyAccountID)
+	require.Equal(t, entries[2].ID, rows[1].Entry.ID)
+	require.Equal(t, balance, rows[1].RunningBalance)
+
+	rows, err = testQueries.ListAccountStatementEntries(context.Background(), ListAccountStatementEntriesParams{
+		AccountID: account.ID,
+		FromTime:  end,
+		ToTime:    end.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
+
+func TestEntriesAppendOnly(t *testing.T) {
+	entry := createRandomEntry(t, createRandomAccount(t))
+
+	_, err := testDb.ExecContext(context.Background(), "UPDATE entries SET amount = amount + 1 WHERE id = $1", entry.ID)
+	requireAppendOnly(t, err)
+
+	_, err = testDb.ExecContext(context.Background(), "DELETE FROM entries WHERE id = $1", entry.ID)
+	requireAppendOnly(t, err)
+
+	_, err = testDb.ExecContext(context.Background(), "TRUNCATE entries")
+	requireAppendOnly(t, err)
 
 	entryFromDb, err := testQueries.GetEntry(context.Background(), entry.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, entryFromDb)
+	require.NoError(t, err)
+	require.Equal(t, entry.Amount, entryFromDb.Amount)
+}
+
+// requireAppendOnly checks that the ledger triggers rejected a statement
+func requireAppendOnly(t *testing.T, err error) {
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "restrict_violation", pqErr.Code.Name())
+	require.Contains(t, pqErr.Message, "append-only")
 }
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"master_class/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// randomRateTime returns a time far from the rates saved by other tests, so lookups
+// around it only find the rates the test creates
+func randomRateTime() time.Time {
+	return time.Date(util.RandomInt(3000, 200000), time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func createRandomExchangeRate(t *testing.T, from string, to string, rate string, validFrom time.Time) ExchangeRate {
+	arg := CreateExchangeRateParams{
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         rate,
+		ValidFrom:    validFrom,
+		Source:       util.RandomString(6),
+	}
+
+	exchangeRate, err := testQueries.CreateExchangeRate(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, exchangeRate.ID)
+	require.Equal(t, arg.FromCurrency, exchangeRate.FromCurrency)
+	require.Equal(t, arg.ToCurrency, exchangeRate.ToCurrency)
+	require.Equal(t, arg.Rate, exchangeRate.Rate)
+	require.WithinDuration(t, arg.ValidFrom, exchangeRate.ValidFrom, time.Second)
+	require.Equal(t, arg.Source, exchangeRate.Source)
+	require.NotZero(t, exchangeRate.CreatedAt)
+
+	return exchangeRate
+}
+
+func TestCreateExchangeRate(t *testing.T) {
+	validFrom := randomRateTime()
+	exchangeRate := createRandomExchangeRate(t, util.EUR, util.USD, "1.0800000000", validFrom)
+
+	// a rate given again for the same pair and time replaces the saved one
+	corrected := createRandomExchangeRate(t, util.EUR, util.USD, "1.0900000000", validFrom)
+	require.Equal(t, exchangeRate.ID, corrected.ID)
+
+	_, err := testQueries.CreateExchangeRate(context.Background(), CreateExchangeRateParams{
+		FromCurrency: util.EUR,
+		ToCurrency:   util.USD,
+		Rate:         "0",
+		ValidFrom:    validFrom.Add(time.Hour),
+		Source:       "test",
+	})
+	require.Error(t, err)
+}
+
+func TestGetExchangeRate(t *testing.T) {
+	validFrom := randomRateTime()
+	first := createRandomExchangeRate(t, util.EUR, util.CAD, "1.4700000000", validFrom)
+	second := createRandomExchangeRate(t, util.EUR, util.CAD, "1.4800000000", validFrom.Add(time.Hour))
+
+	exchangeRate, err := testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		FromCurrency: util.EUR,
+		ToCurrency:   util.CAD,
+		At:           validFrom.Add(30 * time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, first.ID, exchangeRate.ID)
+
+	exchangeRate, err = testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		FromCurrency: util.EUR,
+		ToCurrency:   util.CAD,
+		At:           validFrom.Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, second.ID, exchangeRate.ID)
+}
+
+func TestGetExchangeRateNotYetValid(t *testing.T) {
+	validFrom := time.Date(util.RandomInt(200001, 250000), time.January, 1, 0, 0, 0, 0, time.UTC)
+	createRandomExchangeRate(t, util.CAD, util.USD, "0.7300000000", validFrom)
+
+	_, err := testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		FromCurrency: util.CAD,
+		ToCurrency:   util.USD,
+		At:           time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListExchangeRates(t *testing.T) {
+	validFrom := randomRateTime()
+	createRandomExchangeRate(t, util.USD, util.EUR, "0.9200000000", validFrom)
+	latest := createRandomExchangeRate(t, util.USD, util.EUR, "0.9300000000", validFrom.Add(time.Hour))
+
+	rates, err := testQueries.ListExchangeRates(context.Background(), validFrom.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	var found []ExchangeRate
+	for _, exchangeRate := range rates {
+		if exchangeRate.FromCurrency == util.USD && exchangeRate.ToCurrency == util.EUR {
+			found = append(found, exchangeRate)
+		}
+	}
+
+	// only the latest rate of each pair is listed
+	require.Len(t, found, 1)
+	require.Equal(t, latest.ID, found[0].ID)
+}
+
+func TestCreateExchangeRatesTx(t *testing.T) {
+	store := NewStore(testDb)
+	validFrom := randomRateTime()
+
+	rates, err := store.CreateExchangeRatesTx(context.Background(), []CreateExchangeRateParams{
+		{FromCurrency: util.EUR, ToCurrency: util.USD, Rate: "1.0800000000", ValidFrom: validFrom, Source: "test"},
+		{FromCurrency: util.USD, ToCurrency: util.CAD, Rate: "1.3600000000", ValidFrom: validFrom, Source: "test"},
+	})
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+
+	// an invalid rate rolls back the whole batch
+	_, err = store.CreateExchangeRatesTx(context.Background(), []CreateExchangeRateParams{
+		{FromCurrency: util.EUR, ToCurrency: util.CAD, Rate: "1.4700000000", ValidFrom: validFrom, Source: "test"},
+		{FromCurrency: util.EUR, ToCurrency: util.EUR, Rate: "1.0000000000", ValidFrom: validFrom, Source: "test"},
+	})
+	require.Error(t, err)
+
+	_, err = testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		FromCurrency: util.EUR,
+		ToCurrency:   util.CAD,
+		At:           validFrom.Add(time.Minute),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
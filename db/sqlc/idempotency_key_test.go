@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"master_class/util"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomIdempotencyKey(t *testing.T, user User) IdempotencyKey {
+	arg := CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         util.RandomString(16),
+		RequestHash: util.RandomString(64),
+		Response:    json.RawMessage(`{"transfer": {"id": 1}}`),
+		StatusCode:  http.StatusCreated,
+	}
+
+	record, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Username, record.Username)
+	require.Equal(t, arg.Key, record.Key)
+	require.Equal(t, arg.RequestHash, record.RequestHash)
+	require.Equal(t, string(arg.Response), string(record.Response))
+	require.Equal(t, arg.StatusCode, record.StatusCode)
+	require.NotZero(t, record.CreatedAt)
+
+	return record
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+	record := createRandomIdempotencyKey(t, user)
+
+	// keys are unique per user
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:    user.Username,
+		Key:         record.Key,
+		RequestHash: record.RequestHash,
+		Response:    record.Response,
+	})
+	require.Error(t, err)
+
+	otherUser := createRandomUser(t)
+	_, err = testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:    otherUser.Username,
+		Key:         record.Key,
+		RequestHash: record.RequestHash,
+		Response:    record.Response,
+	})
+	require.NoError(t, err)
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+	record := createRandomIdempotencyKey(t, user)
+
+	gotRecord, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: user.Username,
+		Key:      record.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, record.RequestHash, gotRecord.RequestHash)
+	require.Equal(t, string(record.Response), string(gotRecord.Response))
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: createRandomUser(t).Username,
+		Key:      record.Key,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -4,7 +4,10 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -13,6 +16,8 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// How far below zero the balance may go
+	OverdraftLimit int64 `json:"overdraft_limit"`
 }
 
 type Entry struct {
@@ -21,15 +26,168 @@ type Entry struct {
 	// It can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// Transfer that wrote the entry
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+type ExchangeRate struct {
+	ID           int64  `json:"id"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	// Units of the to currency bought by one unit of the from currency
+	Rate string `json:"rate"`
+	// The rate applies from this time until a later rate of the pair takes over
+	ValidFrom time.Time `json:"valid_from"`
+	// Where the rate comes from, such as a feed or the admin who uploaded it
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username string `json:"username"`
+	Key      string `json:"key"`
+	// Fingerprint of the request first sent with the key
+	RequestHash string `json:"request_hash"`
+	// Response replayed for retries of the request, kept verbatim
+	Response  json.RawMessage `json:"response"`
+	CreatedAt time.Time       `json:"created_at"`
+	// HTTP status the response was first sent with, replayed with it
+	StatusCode int32 `json:"status_code"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// Must be positive, in the currency of the source account
+	Amount int64 `json:"amount"`
+	// Currency of the source account when the transfer was scheduled
+	Currency  string    `json:"currency"`
+	ExecuteAt time.Time `json:"execute_at"`
+	// pending until executed, then succeeded or failed; cancelled by the owner
+	Status string `json:"status"`
+	// Transfer made by a successful execution
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	// Why the execution failed
+	FailureReason sql.NullString `json:"failure_reason"`
+	ExecutedAt    sql.NullTime   `json:"executed_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	// Executions that failed on the store, the transfer fails once they run out
+	Attempts int32 `json:"attempts"`
+	// When the execution is retried after a failed attempt, null until one failed
+	RetryAt sql.NullTime `json:"retry_at"`
+}
+
+type Session struct {
+	// ID of the refresh token issued for the session
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type StandingOrder struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// Must be positive, in the currency of the source account
+	Amount int64 `json:"amount"`
+	// Currency of the source account when the order was created
+	Currency  string `json:"currency"`
+	Frequency string `json:"frequency"`
+	// Number of days, weeks or months between occurrences
+	IntervalCount int32 `json:"interval_count"`
+	// Day of monthly occurrences, the day of start_at when null
+	DayOfMonth     sql.NullInt32 `json:"day_of_month"`
+	StartAt        time.Time     `json:"start_at"`
+	EndAt          sql.NullTime  `json:"end_at"`
+	MaxOccurrences sql.NullInt32 `json:"max_occurrences"`
+	// skip the occurrence, retry it later, or suspend the order
+	InsufficientFundsPolicy string `json:"insufficient_funds_policy"`
+	// active, paused by the owner, suspended for lack of funds, completed or cancelled
+	Status string `json:"status"`
+	// Number of occurrences done, skipped or given up; index of the next one
+	Occurrences int32 `json:"occurrences"`
+	// Retries of the next occurrence for lack of funds
+	RetryCount int32 `json:"retry_count"`
+	// When the next occurrence or retry runs, null once the order ended
+	NextRunAt sql.NullTime `json:"next_run_at"`
+	CreatedAt time.Time    `json:"created_at"`
+	// Runs of the next occurrence that failed on the store, it is given up once they run out
+	Attempts int32 `json:"attempts"`
+}
+
+type StandingOrderRun struct {
+	ID              int64 `json:"id"`
+	StandingOrderID int64 `json:"standing_order_id"`
+	// Index of the occurrence, counting from 0
+	Occurrence  int32     `json:"occurrence"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	// succeeded, skipped, retrying, suspended or failed
+	Status        string         `json:"status"`
+	TransferID    sql.NullInt64  `json:"transfer_id"`
+	FailureReason sql.NullString `json:"failure_reason"`
+	CreatedAt     time.Time      `json:"created_at"`
 }
 
 type Transfer struct {
 	ID            int64         `json:"id"`
 	FromAccountID sql.NullInt64 `json:"from_account_id"`
 	ToAccountID   sql.NullInt64 `json:"to_account_id"`
-	// Must be positive
+	// Must be positive, in the currency of the source account
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// Amount credited, in the currency of the destination account
+	ToAmount int64 `json:"to_amount"`
+	// Units of the destination currency bought by one unit of the source currency
+	Rate string `json:"rate"`
+	// Fraction of the converted amount kept by the bank
+	Spread string `json:"spread"`
+	// Transfer refunded, in whole or in part, by this one
+	ReversalOf sql.NullInt64 `json:"reversal_of"`
+}
+
+type TransferBatch struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	// atomic runs all the lines or none, best_effort runs each line on its own
+	Mode string `json:"mode"`
+	// succeeded when all the lines did, failed when none did, partially_succeeded otherwise
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TransferBatchLine struct {
+	BatchID int64 `json:"batch_id"`
+	// Position of the line in the uploaded file, counting from 1
+	LineNumber int32 `json:"line_number"`
+	// Reference of the line given in the file, such as an end to end id
+	Reference string `json:"reference"`
+	// As given in the file, the account may not exist
+	FromAccountID int64 `json:"from_account_id"`
+	// As given in the file, the account may not exist
+	ToAccountID int64 `json:"to_account_id"`
+	// Must be positive, in the currency of the line
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	// succeeded or failed, skipped when an atomic batch failed on another line
+	Status string `json:"status"`
+	// Transfer made for a successful line
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	// Why the line failed validation or execution
+	FailureReason sql.NullString `json:"failure_reason"`
 }
 
 type User struct {
@@ -39,4 +197,12 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+}
+
+type UserTokenRevocation struct {
+	Username string `json:"username"`
+	// Tokens issued at or before this time are revoked
+	RevokedBefore time.Time `json:"revoked_before"`
+	CreatedAt     time.Time `json:"created_at"`
 }
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"master_class/util"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ExchangeRateProvider is a util.RateProvider backed by the exchange_rates table.
+// Pairs missing from the table are looked up in the fallback provider, if any.
+type ExchangeRateProvider struct {
+	querier  Querier
+	fallback util.RateProvider
+}
+
+var _ util.RateProvider = (*ExchangeRateProvider)(nil)
+
+func NewExchangeRateProvider(querier Querier, fallback util.RateProvider) *ExchangeRateProvider {
+	return &ExchangeRateProvider{querier: querier, fallback: fallback}
+}
+
+func (provider *ExchangeRateProvider) Rate(ctx context.Context, from string, to string) (*big.Rat, error) {
+	return provider.RateAt(ctx, from, to, time.Now())
+}
+
+// RateAt returns the rate of the pair that was effective at the given time.
+// The inverse of the opposite pair is used when only that one is stored.
+func (provider *ExchangeRateProvider) RateAt(ctx context.Context, from string, to string, at time.Time) (*big.Rat, error) {
+	if strings.EqualFold(from, to) {
+		return big.NewRat(1, 1), nil
+	}
+
+	rate, err := provider.storedRate(ctx, from, to, at)
+	if err == nil {
+		return rate, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rate, err = provider.storedRate(ctx, to, from, at)
+	if err == nil {
+		return rate.Inv(rate), nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if provider.fallback != nil {
+		return provider.fallback.Rate(ctx, from, to)
+	}
+
+	return nil, fmt.Errorf("%w: %s/%s", util.ErrRateNotFound, from, to)
+}
+
+func (provider *ExchangeRateProvider) storedRate(ctx context.Context, from string, to string, at time.Time) (*big.Rat, error) {
+	exchangeRate, err := provider.querier.GetExchangeRate(ctx, GetExchangeRateParams{
+		FromCurrency: strings.ToUpper(from),
+		ToCurrency:   strings.ToUpper(to),
+		At:           at,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := util.ParseDecimal(exchangeRate.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored rate for %s/%s: %w", from, to, err)
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DefaultReconciliationBatchSize is the number of accounts, then transfers, read at a time by Reconcile
+const DefaultReconciliationBatchSize = 500
+
+type ReconcileParams struct {
+	BatchSize int32 `json:"batch_size"`
+	// Repair makes up each balance drift with an adjustment entry.
+	// Nothing is written otherwise.
+	Repair bool `json:"repair"`
+}
+
+// BalanceDrift is an account whose balance differs from the sum of its entries
+type BalanceDrift struct {
+	AccountID    int64  `json:"account_id"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+	EntriesTotal int64  `json:"entries_total"`
+	// AdjustmentEntryID is the entry written by a repair, of the amount the entries were short
+	AdjustmentEntryID int64 `json:"adjustment_entry_id,omitempty"`
+}
+
+// Drift is the amount missing from the entries for them to add up to the balance
+func (drift BalanceDrift) Drift() int64 {
+	return drift.Balance - drift.EntriesTotal
+}
+
+// UnbalancedTransfer is a transfer without exactly one debit of its source account
+// and one credit of its destination account for its amounts
+type UnbalancedTransfer struct {
+	TransferID    int64 `json:"transfer_id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	EntryCount    int32 `json:"entry_count"`
+	DebitCount    int32 `json:"debit_count"`
+	CreditCount   int32 `json:"credit_count"`
+}
+
+type ReconciliationReport struct {
+	AccountsChecked     int64                `json:"accounts_checked"`
+	TransfersChecked    int64                `json:"transfers_checked"`
+	BalanceDrifts       []BalanceDrift       `json:"balance_drifts"`
+	UnbalancedTransfers []UnbalancedTransfer `json:"unbalanced_transfers"`
+}
+
+// Unresolved tells whether the report has findings that were not repaired
+func (report ReconciliationReport) Unresolved() bool {
+	if len(report.UnbalancedTransfers) > 0 {
+		return true
+	}
+
+	for _, drift := range report.BalanceDrifts {
+		if drift.AdjustmentEntryID == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reconcile checks that the balance of every account is the sum of its entries,
+// and that every transfer is backed by its debit and credit entries.
+// Accounts and transfers are read in batches so that the ledger never has to fit in memory.
+// Only balance drifts can be repaired, transfers are reported for a human to look into.
+func (store *SQLStore) Reconcile(ctx context.Context, arg ReconcileParams) (ReconciliationReport, error) {
+	report := ReconciliationReport{
+		BalanceDrifts:       []BalanceDrift{},
+		UnbalancedTransfers: []UnbalancedTransfer{},
+	}
+
+	batchSize := arg.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultReconciliationBatchSize
+	}
+
+	var afterID int64
+	for {
+		accounts, err := store.ListAccountBalanceChecks(ctx, ListAccountBalanceChecksParams{
+			AfterID:   afterID,
+			BatchSize: batchSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, account := range accounts {
+			if account.Balance == account.EntriesTotal {
+				continue
+			}
+
+			drift := BalanceDrift{
+				AccountID:    account.ID,
+				Currency:     account.Currency,
+				Balance:      account.Balance,
+				EntriesTotal: account.EntriesTotal,
+			}
+
+			if arg.Repair {
+				drift, err = store.repairBalanceDrift(ctx, drift)
+				if err != nil {
+					return report, err
+				}
+			}
+
+			// a transfer may have moved the balance and its entries together before the repair
+			if drift.Drift() != 0 {
+				report.BalanceDrifts = append(report.BalanceDrifts, drift)
+			}
+		}
+
+		report.AccountsChecked += int64(len(accounts))
+		if len(accounts) < int(batchSize) {
+			break
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+
+	afterID = 0
+	for {
+		transfers, err := store.ListTransferEntryChecks(ctx, ListTransferEntryChecksParams{
+			AfterID:   afterID,
+			BatchSize: batchSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, transfer := range transfers {
+			if transfer.EntryCount == 2 && transfer.DebitCount == 1 && transfer.CreditCount == 1 {
+				continue
+			}
+
+			report.UnbalancedTransfers = append(report.UnbalancedTransfers, UnbalancedTransfer{
+				TransferID:    transfer.ID,
+				FromAccountID: transfer.FromAccountID.Int64,
+				ToAccountID:   transfer.ToAccountID.Int64,
+				EntryCount:    transfer.EntryCount,
+				DebitCount:    transfer.DebitCount,
+				CreditCount:   transfer.CreditCount,
+			})
+		}
+
+		report.TransfersChecked += int64(len(transfers))
+		if len(transfers) < int(batchSize) {
+			break
+		}
+		afterID = transfers[len(transfers)-1].ID
+	}
+
+	return report, nil
+}
+
+// repairBalanceDrift appends the entry that makes the entries of the account add up to its balance.
+// The account is locked and checked again, so that a transfer running meanwhile is not mistaken for drift.
+func (store *SQLStore) repairBalanceDrift(ctx context.Context, drift BalanceDrift) (BalanceDrift, error) {
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, drift.AccountID)
+		if err != nil {
+			return err
+		}
+
+		entriesTotal, err := q.GetAccountEntriesTotal(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		drift.Balance = account.Balance
+		drift.EntriesTotal = entriesTotal
+		if drift.Drift() == 0 {
+			return nil
+		}
+
+		entry, err := q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+			Amount:    drift.Drift(),
+		})
+		if err != nil {
+			return err
+		}
+
+		drift.AdjustmentEntryID = entry.ID
+		return nil
+	})
+
+	return drift, err
+}
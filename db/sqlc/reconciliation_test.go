@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"master_class/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// findDrift returns the drift reported for the account, if any
+func findDrift(report ReconciliationReport, accountID int64) (BalanceDrift, bool) {
+	for _, drift := range report.BalanceDrifts {
+		if drift.AccountID == accountID {
+			return drift, true
+		}
+	}
+
+	return BalanceDrift{}, false
+}
+
+// findUnbalancedTransfer returns the finding reported for the transfer, if any
+func findUnbalancedTransfer(report ReconciliationReport, transferID int64) (UnbalancedTransfer, bool) {
+	for _, transfer := range report.UnbalancedTransfers {
+		if transfer.TransferID == transferID {
+			return transfer, true
+		}
+	}
+
+	return UnbalancedTransfer{}, false
+}
+
+func TestReconcile(t *testing.T) {
+	store := NewStore(testDb)
+
+	// the opening balances have no entries behind them
+	account1 := createRandomAccountWithBalance(t, 1000, util.USD)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// a transfer written without its entries
+	orphan := createRandomTransfer(t, account1, account2)
+
+	// small batches make the walk go over several pages
+	report, err := store.Reconcile(context.Background(), ReconcileParams{BatchSize: 5})
+	require.NoError(t, err)
+	require.True(t, report.Unresolved())
+	require.NotZero(t, report.AccountsChecked)
+	require.NotZero(t, report.TransfersChecked)
+
+	drift, found := findDrift(report, account1.ID)
+	require.True(t, found)
+	require.Equal(t, int64(900), drift.Balance)
+	require.Equal(t, int64(-100), drift.EntriesTotal)
+	require.Equal(t, int64(1000), drift.Drift())
+	require.Zero(t, drift.AdjustmentEntryID)
+
+	_, found = findDrift(report, account2.ID)
+	require.False(t, found)
+
+	_, found = findUnbalancedTransfer(report, result.Transfer.ID)
+	require.False(t, found)
+
+	unbalanced, found := findUnbalancedTransfer(report, orphan.ID)
+	require.True(t, found)
+	require.Equal(t, account1.ID, unbalanced.FromAccountID)
+	require.Equal(t, account2.ID, unbalanced.ToAccountID)
+	require.Zero(t, unbalanced.EntryCount)
+
+	// reporting wrote nothing
+	total, err := testQueries.GetAccountEntriesTotal(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-100), total)
+
+	report, err = store.Reconcile(context.Background(), ReconcileParams{Repair: true})
+	require.NoError(t, err)
+
+	drift, found = findDrift(report, account1.ID)
+	require.True(t, found)
+	require.NotZero(t, drift.AdjustmentEntryID)
+
+	entry, err := testQueries.GetEntry(context.Background(), drift.AdjustmentEntryID)
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, entry.AccountID.Int64)
+	require.Equal(t, int64(1000), entry.Amount)
+	require.False(t, entry.TransferID.Valid)
+
+	// the balance is left alone, the entries now add up to it
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(900), account.Balance)
+
+	report, err = store.Reconcile(context.Background(), ReconcileParams{})
+	require.NoError(t, err)
+
+	_, found = findDrift(report, account1.ID)
+	require.False(t, found)
+
+	// transfers cannot be repaired
+	_, found = findUnbalancedTransfer(report, orphan.ID)
+	require.True(t, found)
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"master_class/token"
+	"time"
+)
+
+// PostgresRevocationStore is a token.RevocationStore backed by the revoked_tokens
+// and user_token_revocations tables
+type PostgresRevocationStore struct {
+	querier Querier
+}
+
+var _ token.RevocationStore = (*PostgresRevocationStore)(nil)
+
+func NewPostgresRevocationStore(querier Querier) *PostgresRevocationStore {
+	return &PostgresRevocationStore{querier: querier}
+}
+
+func (store *PostgresRevocationStore) RevokeToken(ctx context.Context, payload *token.Payload) error {
+	return store.querier.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+}
+
+func (store *PostgresRevocationStore) RevokeUserTokens(ctx context.Context, username string, issuedBefore time.Time) error {
+	return store.querier.RevokeUserTokens(ctx, RevokeUserTokensParams{
+		Username:      username,
+		RevokedBefore: issuedBefore,
+	})
+}
+
+func (store *PostgresRevocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	return store.querier.IsTokenRevoked(ctx, IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"master_class/token"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeToken(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewPostgresRevocationStore(testQueries)
+
+	payload, err := token.NewPayload(user.Username, user.Role, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.RevokeToken(context.Background(), payload)
+	require.NoError(t, err)
+
+	// revoking twice is not an error
+	err = store.RevokeToken(context.Background(), payload)
+	require.NoError(t, err)
+
+	revoked, err = store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewPostgresRevocationStore(testQueries)
+
+	oldPayload, err := token.NewPayload(user.Username, user.Role, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+	oldPayload.IssuedAt = time.Now().Add(-time.Second)
+
+	err = store.RevokeUserTokens(context.Background(), user.Username, time.Now())
+	require.NoError(t, err)
+
+	// an earlier cut-off never moves the revocation back
+	err = store.RevokeUserTokens(context.Background(), user.Username, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	newPayload, err := token.NewPayload(user.Username, user.Role, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+	newPayload.IssuedAt = time.Now().Add(time.Second)
+
+	revoked, err := store.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewPostgresRevocationStore(testQueries)
+
+	expired, err := token.NewPayload(user.Username, user.Role, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+	expired.ExpiredAt = time.Now().Add(-time.Minute)
+
+	live, err := token.NewPayload(user.Username, user.Role, token.AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeToken(context.Background(), expired))
+	require.NoError(t, store.RevokeToken(context.Background(), live))
+
+	err = testQueries.DeleteExpiredRevokedTokens(context.Background(), time.Now())
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), expired)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), live)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"master_class/util"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, from Account, to Account, amount int64, executeAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:         from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		Currency:      from.Currency,
+		ExecuteAt:     executeAt,
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, scheduled.ID)
+	require.Equal(t, arg.Owner, scheduled.Owner)
+	require.Equal(t, arg.FromAccountID, scheduled.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduled.ToAccountID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.Currency, scheduled.Currency)
+	require.WithinDuration(t, arg.ExecuteAt, scheduled.ExecuteAt, time.Second)
+	require.Equal(t, ScheduledTransferPending, scheduled.Status)
+	require.False(t, scheduled.TransferID.Valid)
+	require.False(t, scheduled.ExecutedAt.Valid)
+
+	return scheduled
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+
+	createRandomScheduledTransfer(t, from, to, 10, time.Now().Add(time.Hour))
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+
+	later := createRandomScheduledTransfer(t, from, to, 10, time.Now().Add(2*time.Hour))
+	sooner := createRandomScheduledTransfer(t, from, to, 10, time.Now().Add(time.Hour))
+
+	scheduledTransfers, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Owner:  from.Owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduledTransfers, 2)
+	require.Equal(t, sooner.ID, scheduledTransfers[0].ID)
+	require.Equal(t, later.ID, scheduledTransfers[1].ID)
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	scheduled := createRandomScheduledTransfer(t, from, to, 10, time.Now().Add(time.Hour))
+
+	cancelled, err := testQueries.CancelScheduledTransfer(context.Background(), scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCancelled, cancelled.Status)
+
+	// only pending transfers can be cancelled
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), scheduled.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// executeScheduledTransfer runs due transfers until the given one has been executed
+func executeScheduledTransfer(t *testing.T, store *SQLStore, scheduled ScheduledTransfer) ScheduledTransfer {
+	for {
+		executed, err := store.ExecuteScheduledTransferTx(context.Background(), scheduled.ExecuteAt)
+		require.NoError(t, err)
+
+		if executed.ID == scheduled.ID {
+			return executed
+		}
+	}
+}
+
+func TestExecuteScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	scheduled := createRandomScheduledTransfer(t, from, to, 30, time.Now().Add(-time.Minute))
+
+	executed := executeScheduledTransfer(t, store, scheduled)
+	require.Equal(t, ScheduledTransferSucceeded, executed.Status)
+	require.True(t, executed.TransferID.Valid)
+	require.False(t, executed.FailureReason.Valid)
+	require.True(t, executed.ExecutedAt.Valid)
+
+	transfer, err := testQueries.GetTransfer(context.Background(), executed.TransferID.Int64)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.Amount, transfer.Amount)
+
+	updatedFrom, err := testQueries.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(70), updatedFrom.Balance)
+
+	// executed transfers are not picked up again
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), scheduled.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestExecuteScheduledTransferTxFailure(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 10, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	scheduled := createRandomScheduledTransfer(t, from, to, 30, time.Now().Add(-time.Minute))
+
+	executed := executeScheduledTransfer(t, store, scheduled)
+	require.Equal(t, ScheduledTransferFailed, executed.Status)
+	require.False(t, executed.TransferID.Valid)
+	require.Contains(t, executed.FailureReason.String, "insufficient funds")
+	require.True(t, executed.ExecutedAt.Valid)
+
+	// the failed transfer left the balances untouched
+	updatedFrom, err := testQueries.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance, updatedFrom.Balance)
+
+	updatedTo, err := testQueries.GetAccount(context.Background(), to.ID)
+	require.NoError(t, err)
+	require.Equal(t, to.Balance, updatedTo.Balance)
+}
+
+func TestExecuteScheduledTransferTxOutOfRange(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, math.MaxInt64, util.USD)
+	scheduled := createRandomScheduledTransfer(t, from, to, 10, time.Now().Add(-time.Minute))
+
+	// the balance rejected by the store fails the transfer, it is not retried
+	executed := executeScheduledTransfer(t, store, scheduled)
+	require.Equal(t, ScheduledTransferFailed, executed.Status)
+	require.False(t, executed.TransferID.Valid)
+	require.Contains(t, executed.FailureReason.String, "out of range")
+	require.Zero(t, executed.Attempts)
+}
+
+func TestExecuteScheduledTransferTxRetry(t *testing.T) {
+	store := NewStore(testDb)
+	now := time.Now()
+
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	stuck := createRandomScheduledTransfer(t, from, to, 10, now.Add(-2*time.Hour))
+	later := createRandomScheduledTransfer(t, from, to, 10, now.Add(-time.Hour))
+
+	cause := errors.New("deadlock detected")
+	retried, err := store.retryScheduledTransfer(context.Background(), stuck, now, cause)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferPending, retried.Status)
+	require.Equal(t, int32(1), retried.Attempts)
+	require.WithinDuration(t, now.Add(ScheduledTransferRetryDelay), retried.RetryAt.Time, time.Second)
+	require.Equal(t, cause.Error(), retried.FailureReason.String)
+
+	// the transfer backing off does not hold up the one due after it
+	executed := executeScheduledTransfer(t, store, later)
+	require.Equal(t, ScheduledTransferSucceeded, executed.Status)
+
+	// the delay doubles with each attempt, until the transfer runs out of them
+	retried, err = store.retryScheduledTransfer(context.Background(), retried, now, cause)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), retried.Attempts)
+	require.WithinDuration(t, now.Add(2*ScheduledTransferRetryDelay), retried.RetryAt.Time, time.Second)
+
+	for retried.Status == ScheduledTransferPending {
+		retried, err = store.retryScheduledTransfer(context.Background(), retried, now, cause)
+		require.NoError(t, err)
+	}
+	require.Equal(t, ScheduledTransferFailed, retried.Status)
+	require.Equal(t, int32(ScheduledTransferMaxAttempts), retried.Attempts)
+	require.Equal(t, cause.Error(), retried.FailureReason.String)
+	require.False(t, retried.TransferID.Valid)
+}
+
+func TestExecuteScheduledTransferTxNotDue(t *testing.T) {
+	store := NewStore(testDb)
+
+	// nothing is due this far in the past
+	_, err := store.ExecuteScheduledTransferTx(context.Background(), time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
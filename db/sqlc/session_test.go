@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t)
+
+	arg := CreateSessionParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		UserAgent: "go-test",
+		ClientIp:  "127.0.0.1",
+		IsBlocked: false,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.Equal(t, arg.IsBlocked, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	generatedSession := createRandomSession(t)
+	sessionFromDb, err := testQueries.GetSession(context.Background(), generatedSession.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, sessionFromDb)
+
+	require.Equal(t, generatedSession.ID, sessionFromDb.ID)
+	require.Equal(t, generatedSession.Username, sessionFromDb.Username)
+	require.Equal(t, generatedSession.IsBlocked, sessionFromDb.IsBlocked)
+	require.WithinDuration(t, generatedSession.ExpiresAt, sessionFromDb.ExpiresAt, time.Second)
+	require.WithinDuration(t, generatedSession.CreatedAt, sessionFromDb.CreatedAt, time.Second)
+}
+
+func TestBlockSession(t *testing.T) {
+	generatedSession := createRandomSession(t)
+
+	blockedSession, err := testQueries.BlockSession(context.Background(), generatedSession.ID)
+	require.NoError(t, err)
+	require.Equal(t, generatedSession.ID, blockedSession.ID)
+	require.True(t, blockedSession.IsBlocked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	generatedSession := createRandomSession(t)
+
+	err := testQueries.BlockUserSessions(context.Background(), generatedSession.Username)
+	require.NoError(t, err)
+
+	sessionFromDb, err := testQueries.GetSession(context.Background(), generatedSession.ID)
+	require.NoError(t, err)
+	require.True(t, sessionFromDb.IsBlocked)
+}
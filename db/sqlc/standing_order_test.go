@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"master_class/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomStandingOrder(t *testing.T, from Account, to Account, amount int64, policy string, startAt time.Time) StandingOrder {
+	arg := CreateStandingOrderParams{
+		Owner:                   from.Owner,
+		FromAccountID:           from.ID,
+		ToAccountID:             to.ID,
+		Amount:                  amount,
+		Currency:                from.Currency,
+		Frequency:               util.DailyFrequency,
+		IntervalCount:           1,
+		StartAt:                 startAt,
+		MaxOccurrences:          sql.NullInt32{Int32: 2, Valid: true},
+		InsufficientFundsPolicy: policy,
+		NextRunAt:               sql.NullTime{Time: startAt, Valid: true},
+	}
+
+	order, err := testQueries.CreateStandingOrder(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, order.ID)
+	require.Equal(t, arg.Owner, order.Owner)
+	require.Equal(t, arg.FromAccountID, order.FromAccountID)
+	require.Equal(t, arg.ToAccountID, order.ToAccountID)
+	require.Equal(t, arg.Amount, order.Amount)
+	require.Equal(t, arg.Frequency, order.Frequency)
+	require.Equal(t, arg.MaxOccurrences, order.MaxOccurrences)
+	require.Equal(t, arg.InsufficientFundsPolicy, order.InsufficientFundsPolicy)
+	require.WithinDuration(t, arg.StartAt, order.StartAt, time.Second)
+	require.WithinDuration(t, arg.NextRunAt.Time, order.NextRunAt.Time, time.Second)
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.Zero(t, order.Occurrences)
+	require.Zero(t, order.RetryCount)
+
+	return order
+}
+
+func TestCreateStandingOrder(t *testing.T) {
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+
+	createRandomStandingOrder(t, from, to, 10, SkipOnInsufficientFunds, time.Now().Add(time.Hour))
+}
+
+func TestListStandingOrders(t *testing.T) {
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+
+	first := createRandomStandingOrder(t, from, to, 10, SkipOnInsufficientFunds, time.Now().Add(2*time.Hour))
+	second := createRandomStandingOrder(t, from, to, 10, SkipOnInsufficientFunds, time.Now().Add(time.Hour))
+
+	orders, err := testQueries.ListStandingOrders(context.Background(), ListStandingOrdersParams{
+		Owner:  from.Owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	require.Equal(t, first.ID, orders[0].ID)
+	require.Equal(t, second.ID, orders[1].ID)
+}
+
+func TestUpdateStandingOrderTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	order := createRandomStandingOrder(t, from, to, 10, SkipOnInsufficientFunds, time.Now().Add(time.Hour))
+
+	paused, err := store.UpdateStandingOrderTx(context.Background(), order.ID, func(order *StandingOrder) error {
+		order.Status = StandingOrderPaused
+		order.Amount = 25
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderPaused, paused.Status)
+	require.Equal(t, int64(25), paused.Amount)
+
+	// a failed update saves nothing
+	errRefused := errors.New("refused")
+	_, err = store.UpdateStandingOrderTx(context.Background(), order.ID, func(order *StandingOrder) error {
+		order.Status = StandingOrderCancelled
+		return errRefused
+	})
+	require.ErrorIs(t, err, errRefused)
+
+	unchanged, err := testQueries.GetStandingOrder(context.Background(), order.ID)
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderPaused, unchanged.Status)
+
+	_, err = store.UpdateStandingOrderTx(context.Background(), 0, func(order *StandingOrder) error {
+		return nil
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// executeStandingOrder runs due standing orders until the given one has run once
+func executeStandingOrder(t *testing.T, store *SQLStore, order StandingOrder) (StandingOrderRun, StandingOrder) {
+	for {
+		run, err := store.ExecuteStandingOrderTx(context.Background(), order.NextRunAt.Time)
+		require.NoError(t, err)
+
+		if run.StandingOrderID == order.ID {
+			order, err = testQueries.GetStandingOrder(context.Background(), order.ID)
+			require.NoError(t, err)
+
+			return run, order
+		}
+	}
+}
+
+func TestExecuteStandingOrderTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	order := createRandomStandingOrder(t, from, to, 30, SkipOnInsufficientFunds, time.Now().Add(-time.Minute))
+
+	run, order := executeStandingOrder(t, store, order)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+	require.Zero(t, run.Occurrence)
+	require.True(t, run.TransferID.Valid)
+	require.False(t, run.FailureReason.Valid)
+
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.Equal(t, int32(1), order.Occurrences)
+	require.WithinDuration(t, order.StartAt.AddDate(0, 0, 1), order.NextRunAt.Time, time.Second)
+
+	// the second and last occurrence completes the order
+	run, order = executeStandingOrder(t, store, order)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+	require.Equal(t, int32(1), run.Occurrence)
+	require.Equal(t, StandingOrderCompleted, order.Status)
+	require.False(t, order.NextRunAt.Valid)
+
+	updatedFrom, err := testQueries.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), updatedFrom.Balance)
+
+	runs, err := testQueries.ListStandingOrderRuns(context.Background(), ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           5,
+		Offset:          0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, int32(1), runs[0].Occurrence)
+	require.Equal(t, int32(0), runs[1].Occurrence)
+}
+
+func TestExecuteStandingOrderTxSkip(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 10, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	order := createRandomStandingOrder(t, from, to, 30, SkipOnInsufficientFunds, time.Now().Add(-time.Minute))
+
+	run, order := executeStandingOrder(t, store, order)
+	require.Equal(t, StandingOrderRunSkipped, run.Status)
+	require.False(t, run.TransferID.Valid)
+	require.Contains(t, run.FailureReason.String, "insufficient funds")
+
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.Equal(t, int32(1), order.Occurrences)
+	require.WithinDuration(t, order.StartAt.AddDate(0, 0, 1), order.NextRunAt.Time, time.Second)
+
+	// the skipped run left the balance untouched
+	updatedFrom, err := testQueries.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance, updatedFrom.Balance)
+}
+
+func TestExecuteStandingOrderTxRetry(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 10, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	order := createRandomStandingOrder(t, from, to, 30, RetryOnInsufficientFunds, time.Now().Add(-time.Minute))
+	now := order.NextRunAt.Time
+
+	run, order := executeStandingOrder(t, store, order)
+	require.Equal(t, StandingOrderRunRetrying, run.Status)
+	require.Zero(t, run.Occurrence)
+
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.Zero(t, order.Occurrences)
+	require.Equal(t, int32(1), order.RetryCount)
+	require.WithinDuration(t, now.Add(StandingOrderRetryDelay), order.NextRunAt.Time, time.Second)
+
+	// the retry finds the funds
+	_, err := testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     from.ID,
+		Amount: 50,
+	})
+	require.NoError(t, err)
+
+	run, order = executeStandingOrder(t, store, order)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+	require.Zero(t, run.Occurrence)
+	require.Equal(t, int32(1), order.Occurrences)
+	require.Zero(t, order.RetryCount)
+}
+
+func TestExecuteStandingOrderTxAttempts(t *testing.T) {
+	store := NewStore(testDb)
+	now := time.Now()
+
+	from := createRandomAccountWithBalance(t, 100, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	stuck := createRandomStandingOrder(t, from, to, 10, SkipOnInsufficientFunds, now.Add(-2*time.Hour))
+	later := createRandomStandingOrder(t, from, to, 10, SkipOnInsufficientFunds, now.Add(-time.Hour))
+
+	cause := errors.New("deadlock detected")
+	run, err := store.retryStandingOrder(context.Background(), stuck.ID, now, cause)
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderRunRetrying, run.Status)
+	require.Zero(t, run.Occurrence)
+	require.Equal(t, cause.Error(), run.FailureReason.String)
+
+	order, err := testQueries.GetStandingOrder(context.Background(), stuck.ID)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), order.Attempts)
+	require.Zero(t, order.Occurrences)
+	require.WithinDuration(t, now.Add(StandingOrderAttemptDelay), order.NextRunAt.Time, time.Second)
+
+	// the order backing off does not hold up the one due after it
+	run, _ = executeStandingOrder(t, store, later)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+
+	// the occurrence is given up once it ran out of attempts
+	for run.Status != StandingOrderRunFailed {
+		run, err = store.retryStandingOrder(context.Background(), stuck.ID, now, cause)
+		require.NoError(t, err)
+		require.Equal(t, stuck.ID, run.StandingOrderID)
+	}
+	require.Zero(t, run.Occurrence)
+	require.Equal(t, cause.Error(), run.FailureReason.String)
+
+	order, err = testQueries.GetStandingOrder(context.Background(), stuck.ID)
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.Equal(t, int32(1), order.Occurrences)
+	require.Zero(t, order.Attempts)
+	require.WithinDuration(t, stuck.StartAt.AddDate(0, 0, 1), order.NextRunAt.Time, time.Second)
+}
+
+func TestExecuteStandingOrderTxSuspend(t *testing.T) {
+	store := NewStore(testDb)
+
+	from := createRandomAccountWithBalance(t, 10, util.USD)
+	to := createRandomAccountWithBalance(t, 0, util.USD)
+	order := createRandomStandingOrder(t, from, to, 30, SuspendOnInsufficientFunds, time.Now().Add(-time.Minute))
+
+	run, order := executeStandingOrder(t, store, order)
+	require.Equal(t, StandingOrderRunSuspended, run.Status)
+	require.Equal(t, StandingOrderSuspended, order.Status)
+	require.Zero(t, order.Occurrences)
+
+	// suspended orders are not picked up again
+	for {
+		run, err := store.ExecuteStandingOrderTx(context.Background(), order.NextRunAt.Time)
+		if err == sql.ErrNoRows {
+			break
+		}
+
+		require.NoError(t, err)
+		require.NotEqual(t, order.ID, run.StandingOrderID)
+	}
+}
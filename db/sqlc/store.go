@@ -3,12 +3,26 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"master_class/util"
+	"math/big"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	CreateExchangeRatesTx(ctx context.Context, rates []CreateExchangeRateParams) ([]ExchangeRate, error)
+	ExecuteScheduledTransferTx(ctx context.Context, now time.Time) (ScheduledTransfer, error)
+	UpdateStandingOrderTx(ctx context.Context, id int64, update func(order *StandingOrder) error) (StandingOrder, error)
+	ExecuteStandingOrderTx(ctx context.Context, now time.Time) (StandingOrderRun, error)
+	Reconcile(ctx context.Context, arg ReconcileParams) (ReconciliationReport, error)
+	CreateTransferBatchTx(ctx context.Context, arg CreateTransferBatchTxParams) (TransferBatchTxResult, error)
 }
 
 type SQLStore struct {
@@ -45,6 +59,28 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// FX converts the amount into the currency of the destination account.
+	// It is required when the currencies of the accounts differ.
+	FX *TransferFX `json:"fx,omitempty"`
+	// Idempotency saves the result under an idempotency key when set
+	Idempotency *TransferIdempotency `json:"-"`
+}
+
+// TransferFX is the conversion applied to a transfer between accounts of different currencies
+type TransferFX struct {
+	// Rate is how many units of the destination currency one unit of the source currency buys
+	Rate string `json:"rate"`
+	// Spread is the fraction of the converted amount kept by the bank
+	Spread string `json:"spread"`
+}
+
+// TransferIdempotency identifies the request a transfer is made for, so retries can be detected
+type TransferIdempotency struct {
+	Username    string
+	Key         string
+	RequestHash string
+	// StatusCode is the HTTP status the response is sent with, and replayed with
+	StatusCode int32
 }
 
 type TransferTxResult struct {
@@ -55,53 +91,245 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 }
 
+// ErrDuplicateIdempotencyKey is returned by TransferTx when another transfer
+// has already been saved under the same idempotency key
+var ErrDuplicateIdempotencyKey = errors.New("idempotency key has already been used")
+
+var (
+	ErrCurrencyMismatch  = errors.New("accounts have different currencies and no exchange rate was given")
+	ErrConvertedTooSmall = errors.New("converted amount rounds down to zero")
+	// ErrAccountCurrencyChanged is returned when the source account of a transfer set up
+	// ahead of time no longer holds the currency of its amount
+	ErrAccountCurrencyChanged = errors.New("account currency changed")
+)
+
+var (
+	ErrReversalNotReversible = errors.New("a reversal cannot be reversed")
+	ErrRefundExceedsTransfer = errors.New("refund exceeds the amount of the transfer left to refund")
+)
+
+// InsufficientFundsError is returned by TransferTx when the transfer would take
+// the balance of the source account below its overdraft limit
+type InsufficientFundsError struct {
+	AccountID      int64
+	Balance        int64
+	OverdraftLimit int64
+	Amount         int64
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf(
+		"insufficient funds in account [%d]: balance %d with overdraft limit %d cannot cover %d",
+		e.AccountID, e.Balance, e.OverdraftLimit, e.Amount,
+	)
+}
+
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := store.ExecTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = transfer(ctx, q, arg)
+		return err
+	})
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: sql.NullInt64{Int64: arg.FromAccountID, Valid: true},
-			ToAccountID:   sql.NullInt64{Int64: arg.ToAccountID, Valid: true},
-			Amount:        arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: sql.NullInt64{Int64: arg.FromAccountID, Valid: true},
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+// transfer moves the money within the transaction of q
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: sql.NullInt64{Int64: arg.ToAccountID, Valid: true},
-			Amount:    arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	conversion, err := newTransferConversion(fromAccount, toAccount, arg)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+	result, err := postTransfer(ctx, q, fromAccount, CreateTransferParams{
+		FromAccountID: sql.NullInt64{Int64: arg.FromAccountID, Valid: true},
+		ToAccountID:   sql.NullInt64{Int64: arg.ToAccountID, Valid: true},
+		Amount:        arg.Amount,
+		ToAmount:      conversion.toAmount,
+		Rate:          conversion.rate,
+		Spread:        conversion.spread,
+	})
+	if err != nil {
+		return result, err
+	}
 
-		if err != nil {
-			return err
+	if arg.Idempotency != nil {
+		err = saveIdempotencyKey(ctx, q, arg.Idempotency, result)
+	}
+
+	return result, err
+}
+
+// postTransfer records the transfer with its entries and moves the money between accounts
+// locked by the caller, unless the source account cannot cover the amount
+func postTransfer(ctx context.Context, q *Queries, fromAccount Account, arg CreateTransferParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	// an amount so large the balance would overflow cannot be covered either
+	balance, err := util.NewMoney(fromAccount.Balance, fromAccount.Currency).
+		Sub(util.NewMoney(arg.Amount, fromAccount.Currency))
+	if err != nil || balance.Amount < -fromAccount.OverdraftLimit {
+		return result, &InsufficientFundsError{
+			AccountID:      fromAccount.ID,
+			Balance:        fromAccount.Balance,
+			OverdraftLimit: fromAccount.OverdraftLimit,
+			Amount:         arg.Amount,
 		}
+	}
 
-		return nil
+	result.Transfer, err = q.CreateTransfer(ctx, arg)
+	if err != nil {
+		return result, err
+	}
+
+	transferID := sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.FromAccountID,
+		Amount:     -arg.Amount,
+		TransferID: transferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.ToAccountID,
+		Amount:     arg.ToAmount,
+		TransferID: transferID,
 	})
+	if err != nil {
+		return result, err
+	}
+
+	fromAccountID := arg.FromAccountID.Int64
+	toAccountID := arg.ToAccountID.Int64
+
+	if fromAccountID < toAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, fromAccountID, -arg.Amount, toAccountID, arg.ToAmount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, toAccountID, arg.ToAmount, fromAccountID, -arg.Amount)
+	}
 
 	return result, err
 }
 
+// saveIdempotencyKey stores the response of the request. A concurrent transfer
+// saving the same key makes it fail, which rolls back the whole transaction.
+func saveIdempotencyKey(ctx context.Context, q *Queries, idempotency *TransferIdempotency, response any) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:    idempotency.Username,
+		Key:         idempotency.Key,
+		RequestHash: idempotency.RequestHash,
+		Response:    data,
+		StatusCode:  idempotency.StatusCode,
+	})
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return ErrDuplicateIdempotencyKey
+	}
+
+	return err
+}
+
+// lockAccounts locks both accounts of a transfer in ID order, so concurrent transfers
+// between the same accounts cannot deadlock
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (fromAccount Account, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return
+		}
+
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return
+	}
+
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return
+}
+
+// transferConversion is the amount credited to the destination account and how it was computed
+type transferConversion struct {
+	toAmount int64
+	rate     string
+	spread   string
+}
+
+// newTransferConversion converts the amount when the account currencies differ.
+// Rate and spread are rounded to the precision of their columns first, so the
+// recorded values reproduce the credited amount.
+func newTransferConversion(fromAccount Account, toAccount Account, arg TransferTxParams) (transferConversion, error) {
+	if fromAccount.Currency == toAccount.Currency {
+		return transferConversion{toAmount: arg.Amount, rate: "1", spread: "0"}, nil
+	}
+
+	if arg.FX == nil {
+		return transferConversion{}, ErrCurrencyMismatch
+	}
+
+	rate, err := util.ParseDecimal(arg.FX.Rate)
+	if err != nil {
+		return transferConversion{}, err
+	}
+
+	spread, err := util.ParseDecimal(arg.FX.Spread)
+	if err != nil {
+		return transferConversion{}, err
+	}
+
+	rate.SetString(rate.FloatString(10))
+	spread.SetString(spread.FloatString(6))
+
+	if rate.Sign() <= 0 || spread.Cmp(big.NewRat(1, 1)) >= 0 {
+		return transferConversion{}, fmt.Errorf("invalid exchange rate %s with spread %s", arg.FX.Rate, arg.FX.Spread)
+	}
+
+	fromCurrency, ok := util.LookupCurrency(fromAccount.Currency)
+	if !ok {
+		return transferConversion{}, fmt.Errorf("unknown currency %q", fromAccount.Currency)
+	}
+
+	toCurrency, ok := util.LookupCurrency(toAccount.Currency)
+	if !ok {
+		return transferConversion{}, fmt.Errorf("unknown currency %q", toAccount.Currency)
+	}
+
+	// amounts are in minor units, which currencies scale differently
+	minorUnitRate := util.MinorUnitRate(rate, fromCurrency, toCurrency)
+
+	toAmount, err := util.ConvertAmount(arg.Amount, minorUnitRate, spread)
+	if err != nil {
+		return transferConversion{}, err
+	}
+
+	if toAmount <= 0 {
+		return transferConversion{}, ErrConvertedTooSmall
+	}
+
+	return transferConversion{
+		toAmount: toAmount,
+		rate:     rate.FloatString(10),
+		spread:   spread.FloatString(6),
+	}, nil
+}
+
 func addMoney(
 	ctx context.Context,
 	q *Queries,
@@ -125,3 +353,565 @@ func addMoney(
 
 	return
 }
+
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount refunded in minor units of the currency of the destination account.
+	// Zero refunds all that is left of the transfer.
+	Amount int64 `json:"amount"`
+	// Idempotency saves the result under an idempotency key when set
+	Idempotency *TransferIdempotency `json:"-"`
+}
+
+// ReverseTransferTx refunds a transfer, in whole or in part, with a compensating transfer
+// back to the source account that is linked to it. The refunds of a transfer together cannot
+// exceed the amount it credited, and they convert back at its rate, the last one returning
+// exactly what is left of the amount debited.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		// locking the transfer serializes its refunds, so they cannot overrun it together
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversalOf.Valid {
+			return ErrReversalNotReversible
+		}
+
+		refunds, err := q.GetTransferRefunds(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+
+		left := original.ToAmount - refunds.Refunded
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = left
+		}
+
+		if amount <= 0 || amount > left {
+			return ErrRefundExceedsTransfer
+		}
+
+		credited := original.Amount - refunds.Credited
+		if amount < left {
+			share := new(big.Int).Div(
+				new(big.Int).Mul(big.NewInt(amount), big.NewInt(original.Amount)),
+				big.NewInt(original.ToAmount),
+			)
+			if !share.IsInt64() {
+				return util.ErrMoneyOverflow
+			}
+
+			credited = share.Int64()
+		}
+
+		if credited <= 0 {
+			return ErrConvertedTooSmall
+		}
+
+		// the money goes back from the destination account to the source account
+		fromAccount, toAccount, err := lockAccounts(ctx, q, original.ToAccountID.Int64, original.FromAccountID.Int64)
+		if err != nil {
+			return err
+		}
+
+		rate, err := reversalRate(fromAccount, toAccount, amount, credited)
+		if err != nil {
+			return err
+		}
+
+		result, err = postTransfer(ctx, q, fromAccount, CreateTransferParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        amount,
+			ToAmount:      credited,
+			Rate:          rate,
+			Spread:        "0",
+			ReversalOf:    sql.NullInt64{Int64: original.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			err = saveIdempotencyKey(ctx, q, arg.Idempotency, result)
+		}
+
+		return err
+	})
+
+	return result, err
+}
+
+// reversalRate is the rate a refund of amount crediting credited converted at,
+// per major unit like the rates of other transfers
+func reversalRate(fromAccount Account, toAccount Account, amount int64, credited int64) (string, error) {
+	if fromAccount.Currency == toAccount.Currency {
+		return "1", nil
+	}
+
+	fromCurrency, ok := util.LookupCurrency(fromAccount.Currency)
+	if !ok {
+		return "", fmt.Errorf("unknown currency %q", fromAccount.Currency)
+	}
+
+	toCurrency, ok := util.LookupCurrency(toAccount.Currency)
+	if !ok {
+		return "", fmt.Errorf("unknown currency %q", toAccount.Currency)
+	}
+
+	// scaling the other way round turns the rate between minor units into one between major units
+	rate := util.MinorUnitRate(big.NewRat(credited, amount), toCurrency, fromCurrency)
+	return rate.FloatString(10), nil
+}
+
+// CreateExchangeRatesTx saves a batch of rates, all of them or none.
+// A rate given again for the same pair and time replaces the saved one.
+func (store *SQLStore) CreateExchangeRatesTx(ctx context.Context, rates []CreateExchangeRateParams) ([]ExchangeRate, error) {
+	result := make([]ExchangeRate, 0, len(rates))
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		for _, arg := range rates {
+			rate, err := q.CreateExchangeRate(ctx, arg)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, rate)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// Statuses of a scheduled transfer
+const (
+	ScheduledTransferPending   = "pending"
+	ScheduledTransferSucceeded = "succeeded"
+	ScheduledTransferFailed    = "failed"
+	ScheduledTransferCancelled = "cancelled"
+)
+
+const (
+	// ScheduledTransferRetryDelay is how long a scheduled transfer waits after its first attempt
+	// failed on the store, the delay doubling with each further attempt
+	ScheduledTransferRetryDelay = time.Minute
+	// ScheduledTransferMaxAttempts is how many attempts failing on the store a scheduled transfer
+	// gets before it is recorded as failed
+	ScheduledTransferMaxAttempts = 5
+)
+
+// ExecuteScheduledTransferTx runs the earliest scheduled transfer due at now and records
+// whether it succeeded. The row stays locked until then, and rows locked by concurrent
+// executors are skipped. It returns sql.ErrNoRows when no transfer is due.
+// A transfer refused for a business reason is recorded as failed. A failure of the store is
+// recorded as a failed attempt instead, and the transfer is returned still pending, to be retried
+// once it backed off so it does not hold up the transfers due after it. The transfer fails after
+// ScheduledTransferMaxAttempts attempts. The error is returned when the attempt cannot be recorded.
+func (store *SQLStore) ExecuteScheduledTransferTx(ctx context.Context, now time.Time) (ScheduledTransfer, error) {
+	var scheduled, claimed ScheduledTransfer
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		var err error
+		claimed, err = q.ClaimDueScheduledTransfer(ctx, now)
+		if err != nil {
+			return err
+		}
+		scheduled = claimed
+
+		arg := CompleteScheduledTransferParams{
+			ID:     scheduled.ID,
+			Status: ScheduledTransferSucceeded,
+		}
+
+		failure, err := execTransferInSavepoint(ctx, q, "scheduled_transfer", func() error {
+			result, err := transferInCurrency(ctx, q, TransferTxParams{
+				FromAccountID: scheduled.FromAccountID,
+				ToAccountID:   scheduled.ToAccountID,
+				Amount:        scheduled.Amount,
+			}, scheduled.Currency)
+			if err != nil {
+				return err
+			}
+
+			arg.TransferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if failure != nil {
+			arg.Status = ScheduledTransferFailed
+			arg.FailureReason = sql.NullString{String: failure.Error(), Valid: true}
+		}
+
+		scheduled, err = q.CompleteScheduledTransfer(ctx, arg)
+		return err
+	})
+	if err != nil && claimed.ID != 0 {
+		return store.retryScheduledTransfer(ctx, claimed, now, err)
+	}
+
+	return scheduled, err
+}
+
+// retryScheduledTransfer records an attempt of the claimed transfer that failed on the store with
+// cause. The transfer is retried after a delay doubling with each attempt, or recorded as failed
+// once it ran out of attempts. cause is returned when the attempt cannot be recorded.
+func (store *SQLStore) retryScheduledTransfer(ctx context.Context, claimed ScheduledTransfer, now time.Time, cause error) (ScheduledTransfer, error) {
+	var scheduled ScheduledTransfer
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		attempts := claimed.Attempts + 1
+		failureReason := sql.NullString{String: cause.Error(), Valid: true}
+
+		var err error
+		scheduled, err = q.RetryScheduledTransfer(ctx, RetryScheduledTransferParams{
+			ID:            claimed.ID,
+			RetryAt:       sql.NullTime{Time: now.Add(ScheduledTransferRetryDelay << (attempts - 1)), Valid: true},
+			FailureReason: failureReason,
+		})
+		if err != nil || scheduled.Attempts < ScheduledTransferMaxAttempts {
+			return err
+		}
+
+		scheduled, err = q.CompleteScheduledTransfer(ctx, CompleteScheduledTransferParams{
+			ID:            claimed.ID,
+			Status:        ScheduledTransferFailed,
+			FailureReason: failureReason,
+		})
+		return err
+	})
+	if err != nil {
+		return ScheduledTransfer{}, cause
+	}
+
+	return scheduled, nil
+}
+
+// Statuses of a standing order
+const (
+	StandingOrderActive    = "active"
+	StandingOrderPaused    = "paused"
+	StandingOrderSuspended = "suspended"
+	StandingOrderCompleted = "completed"
+	StandingOrderCancelled = "cancelled"
+)
+
+// Policies of a standing order when its source account lacks the funds for an occurrence
+const (
+	// SkipOnInsufficientFunds gives up the occurrence and waits for the next one
+	SkipOnInsufficientFunds = "skip"
+	// RetryOnInsufficientFunds retries the occurrence later, then skips it
+	RetryOnInsufficientFunds = "retry"
+	// SuspendOnInsufficientFunds suspends the order until its owner resumes it
+	SuspendOnInsufficientFunds = "suspend"
+)
+
+// Statuses of a standing order run
+const (
+	StandingOrderRunSucceeded = "succeeded"
+	StandingOrderRunSkipped   = "skipped"
+	StandingOrderRunRetrying  = "retrying"
+	StandingOrderRunSuspended = "suspended"
+	StandingOrderRunFailed    = "failed"
+)
+
+const (
+	// StandingOrderRetryDelay is how long an occurrence waits for funds before it is retried
+	StandingOrderRetryDelay = time.Hour
+	// StandingOrderMaxRetries is how many times an occurrence is retried before it is skipped
+	StandingOrderMaxRetries = 3
+	// StandingOrderAttemptDelay is how long an occurrence waits after its first run failed on the
+	// store, the delay doubling with each further run
+	StandingOrderAttemptDelay = time.Minute
+	// StandingOrderMaxAttempts is how many runs failing on the store an occurrence gets before it is given up
+	StandingOrderMaxAttempts = 5
+)
+
+// Recurrence returns when the occurrences of the order fall
+func (order StandingOrder) Recurrence() util.Recurrence {
+	return util.Recurrence{
+		Frequency:      order.Frequency,
+		Interval:       int(order.IntervalCount),
+		DayOfMonth:     int(order.DayOfMonth.Int32),
+		StartAt:        order.StartAt,
+		EndAt:          order.EndAt.Time,
+		MaxOccurrences: int(order.MaxOccurrences.Int32),
+	}
+}
+
+// ScheduleFrom sets the next run of the order to its first occurrence, from index Occurrences on,
+// that does not fall before t. A retry pending for that occurrence is kept. The order is completed
+// when its recurrence ends first.
+func (order *StandingOrder) ScheduleFrom(t time.Time) {
+	n, at, ok := order.Recurrence().NextFrom(int(order.Occurrences), t)
+	if !ok {
+		order.Status = StandingOrderCompleted
+		order.RetryCount = 0
+		order.NextRunAt = sql.NullTime{}
+		return
+	}
+
+	if n == int(order.Occurrences) && order.RetryCount > 0 {
+		return
+	}
+
+	order.Occurrences = int32(n)
+	order.RetryCount = 0
+	order.NextRunAt = sql.NullTime{Time: at, Valid: true}
+}
+
+func (order StandingOrder) updateParams() UpdateStandingOrderParams {
+	return UpdateStandingOrderParams{
+		ID:                      order.ID,
+		Amount:                  order.Amount,
+		EndAt:                   order.EndAt,
+		MaxOccurrences:          order.MaxOccurrences,
+		InsufficientFundsPolicy: order.InsufficientFundsPolicy,
+		Status:                  order.Status,
+		Occurrences:             order.Occurrences,
+		RetryCount:              order.RetryCount,
+		NextRunAt:               order.NextRunAt,
+		Attempts:                order.Attempts,
+	}
+}
+
+// UpdateStandingOrderTx applies update to the standing order and saves it. The row stays
+// locked meanwhile, so the change cannot interleave with a run of the order.
+// The error of update is returned as is, and nothing is saved.
+func (store *SQLStore) UpdateStandingOrderTx(ctx context.Context, id int64, update func(order *StandingOrder) error) (StandingOrder, error) {
+	var order StandingOrder
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		var err error
+		order, err = q.GetStandingOrderForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = update(&order)
+		if err != nil {
+			return err
+		}
+
+		order, err = q.UpdateStandingOrder(ctx, order.updateParams())
+		return err
+	})
+
+	return order, err
+}
+
+// ExecuteStandingOrderTx runs the standing order due earliest at now, records the run in its
+// history and schedules the next one. The row stays locked until then, and rows locked by
+// concurrent executors are skipped. It returns sql.ErrNoRows when no order is due.
+//
+// When the source account lacks the funds, the insufficient funds policy of the order decides
+// whether the occurrence is skipped, retried after StandingOrderRetryDelay or suspends the order.
+// Any other transfer refused for a business reason skips the occurrence as failed.
+//
+// A failure of the store is recorded as a retrying run instead, and the occurrence backs off so
+// it does not hold up the orders due after it. The occurrence is given up as failed after
+// StandingOrderMaxAttempts runs. The error is returned when the run cannot be recorded.
+func (store *SQLStore) ExecuteStandingOrderTx(ctx context.Context, now time.Time) (StandingOrderRun, error) {
+	var run StandingOrderRun
+	var claimedID int64
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		order, err := q.ClaimDueStandingOrder(ctx, now)
+		if err != nil {
+			return err
+		}
+		claimedID = order.ID
+
+		arg := CreateStandingOrderRunParams{
+			StandingOrderID: order.ID,
+			Occurrence:      order.Occurrences,
+			ScheduledAt:     order.Recurrence().Occurrence(int(order.Occurrences)),
+			Status:          StandingOrderRunSucceeded,
+		}
+
+		failure, err := execTransferInSavepoint(ctx, q, "standing_order", func() error {
+			result, err := transferInCurrency(ctx, q, TransferTxParams{
+				FromAccountID: order.FromAccountID,
+				ToAccountID:   order.ToAccountID,
+				Amount:        order.Amount,
+			}, order.Currency)
+			if err != nil {
+				return err
+			}
+
+			arg.TransferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var insufficientFunds *InsufficientFundsError
+		lacksFunds := errors.As(failure, &insufficientFunds)
+
+		switch {
+		case failure == nil:
+			order.Occurrences++
+		case lacksFunds && order.InsufficientFundsPolicy == RetryOnInsufficientFunds && order.RetryCount < StandingOrderMaxRetries:
+			arg.Status = StandingOrderRunRetrying
+			order.RetryCount++
+			order.NextRunAt = sql.NullTime{Time: now.Add(StandingOrderRetryDelay), Valid: true}
+		case lacksFunds && order.InsufficientFundsPolicy == SuspendOnInsufficientFunds:
+			arg.Status = StandingOrderRunSuspended
+			order.Status = StandingOrderSuspended
+		case lacksFunds:
+			arg.Status = StandingOrderRunSkipped
+			order.Occurrences++
+		default:
+			arg.Status = StandingOrderRunFailed
+			order.Occurrences++
+		}
+
+		if failure != nil {
+			arg.FailureReason = sql.NullString{String: failure.Error(), Valid: true}
+		}
+
+		// the next occurrence follows right away when runs were missed, none is skipped
+		if arg.Status != StandingOrderRunRetrying && arg.Status != StandingOrderRunSuspended {
+			order.RetryCount = 0
+			order.ScheduleFrom(time.Time{})
+		}
+		order.Attempts = 0
+
+		run, err = q.CreateStandingOrderRun(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.UpdateStandingOrder(ctx, order.updateParams())
+		return err
+	})
+	if err != nil && claimedID != 0 {
+		return store.retryStandingOrder(ctx, claimedID, now, err)
+	}
+
+	return run, err
+}
+
+// retryStandingOrder records a run of the order that failed on the store with cause. The occurrence
+// is run again after a delay doubling with each attempt, or given up as failed once it ran out of
+// attempts. cause is returned when the run cannot be recorded.
+func (store *SQLStore) retryStandingOrder(ctx context.Context, id int64, now time.Time, cause error) (StandingOrderRun, error) {
+	var run StandingOrderRun
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		order, err := q.GetStandingOrderForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// the order was paused or cancelled meanwhile
+		if order.Status != StandingOrderActive {
+			return sql.ErrNoRows
+		}
+
+		arg := CreateStandingOrderRunParams{
+			StandingOrderID: order.ID,
+			Occurrence:      order.Occurrences,
+			ScheduledAt:     order.Recurrence().Occurrence(int(order.Occurrences)),
+			Status:          StandingOrderRunRetrying,
+			FailureReason:   sql.NullString{String: cause.Error(), Valid: true},
+		}
+
+		order.Attempts++
+		if order.Attempts < StandingOrderMaxAttempts {
+			order.NextRunAt = sql.NullTime{Time: now.Add(StandingOrderAttemptDelay << (order.Attempts - 1)), Valid: true}
+		} else {
+			arg.Status = StandingOrderRunFailed
+			order.Occurrences++
+			order.RetryCount = 0
+			order.Attempts = 0
+			order.ScheduleFrom(time.Time{})
+		}
+
+		run, err = q.CreateStandingOrderRun(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.UpdateStandingOrder(ctx, order.updateParams())
+		return err
+	})
+	if err != nil {
+		return StandingOrderRun{}, cause
+	}
+
+	return run, nil
+}
+
+// transferInCurrency makes a transfer set up ahead of time, whose amount was given in minor units
+// of currency. It fails when the source account no longer holds that currency.
+func transferInCurrency(ctx context.Context, q *Queries, arg TransferTxParams, currency string) (TransferTxResult, error) {
+	account, err := q.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return TransferTxResult{}, err
+	}
+
+	if account.Currency != currency {
+		return TransferTxResult{}, fmt.Errorf("%w: account [%d] holds %s instead of %s", ErrAccountCurrencyChanged, account.ID, account.Currency, currency)
+	}
+
+	return transfer(ctx, q, arg)
+}
+
+// execInSavepoint runs fn within a savepoint of the transaction of q. When fn fails, only its
+// work is rolled back and its error is returned as failure, so the transaction can go on.
+// Errors managing the savepoint itself are returned as err.
+func execInSavepoint(ctx context.Context, q *Queries, name string, fn func() error) (failure error, err error) {
+	_, err = q.db.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return nil, err
+	}
+
+	failure = fn()
+	if failure != nil {
+		_, err = q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return failure, err
+	}
+
+	_, err = q.db.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return nil, err
+}
+
+// isTransferFailure tells whether err refuses a transfer for a business reason, which no retry
+// gets past, rather than a failure of the store such as a deadlock or a lost connection.
+// The data the transfer writes being rejected, such as a balance out of range, is one of them.
+func isTransferFailure(err error) bool {
+	var insufficientFunds *InsufficientFundsError
+	var pqErr *pq.Error
+
+	return errors.As(err, &insufficientFunds) ||
+		errors.Is(err, ErrCurrencyMismatch) ||
+		errors.Is(err, ErrConvertedTooSmall) ||
+		errors.Is(err, ErrAccountCurrencyChanged) ||
+		errors.Is(err, util.ErrMoneyOverflow) ||
+		errors.Is(err, sql.ErrNoRows) ||
+		errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Name() == "check_violation")
+}
+
+// execTransferInSavepoint runs a transfer set up ahead of time like execInSavepoint. Only a transfer
+// refused for a business reason is returned as failure, any other error is returned as err so
+// the whole transaction fails and can be retried.
+func execTransferInSavepoint(ctx context.Context, q *Queries, name string, fn func() error) (failure error, err error) {
+	failure, err = execInSavepoint(ctx, q, name, fn)
+	if err == nil && failure != nil && !isTransferFailure(failure) {
+		return nil, failure
+	}
+
+	return failure, err
+}
@@ -2,17 +2,23 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"master_class/util"
+	"math"
 	"testing"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDb)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000, util.USD)
+	account2 := createRandomAccountWithBalance(t, 1000, util.USD)
 	fmt.Println(">> Before:", account1.Balance, account2.Balance)
 
 	n := 5
@@ -109,8 +115,8 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadLock(t *testing.T) {
 	store := NewStore(testDb)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000, util.USD)
+	account2 := createRandomAccountWithBalance(t, 1000, util.USD)
 	fmt.Println(">> Before:", account1.Balance, account2.Balance)
 
 	n := 10
@@ -153,3 +159,377 @@ func TestTransferTxDeadLock(t *testing.T) {
 	require.Equal(t, account1.Balance, updateAccount1.Balance)
 	require.Equal(t, account2.Balance, updateAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 100, util.USD)
+	account2 := createRandomAccountWithBalance(t, 100, util.USD)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        101,
+	})
+	require.Error(t, err)
+
+	var fundsErr *InsufficientFundsError
+	require.True(t, errors.As(err, &fundsErr))
+	require.Equal(t, account1.ID, fundsErr.AccountID)
+	require.Equal(t, int64(100), fundsErr.Balance)
+	require.Zero(t, fundsErr.OverdraftLimit)
+	require.Equal(t, int64(101), fundsErr.Amount)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+
+	// the whole balance can still be moved
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccount.Balance)
+}
+
+func TestTransferTxAmountOverflow(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, -2, util.USD)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	// -2 - MaxInt64 wraps around to a positive balance without a checked subtraction
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        math.MaxInt64,
+	})
+
+	var fundsErr *InsufficientFundsError
+	require.True(t, errors.As(err, &fundsErr))
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 100, util.USD)
+	account2 := createRandomAccountWithBalance(t, 100, util.USD)
+
+	_, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 50,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        150,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), result.FromAccount.Balance)
+	require.Equal(t, int64(250), result.ToAccount.Balance)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+
+	var fundsErr *InsufficientFundsError
+	require.True(t, errors.As(err, &fundsErr))
+	require.Equal(t, int64(-50), fundsErr.Balance)
+	require.Equal(t, int64(50), fundsErr.OverdraftLimit)
+}
+
+func TestTransferTxConcurrentInsufficientFunds(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 50, util.USD)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	n := 10
+	amount := int64(10)
+
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+
+		var fundsErr *InsufficientFundsError
+		require.True(t, errors.As(err, &fundsErr))
+	}
+
+	// the balance lock lets exactly as many transfers through as the funds cover
+	require.Equal(t, 5, succeeded)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount1.Balance)
+}
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 1000, util.USD)
+	account2 := createRandomAccountWithBalance(t, 1000, util.USD)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Idempotency: &TransferIdempotency{
+			Username:    account1.Owner,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(64),
+		},
+	}
+
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	record, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.Idempotency.Username,
+		Key:      arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.Idempotency.RequestHash, record.RequestHash)
+
+	var savedResult TransferTxResult
+	err = json.Unmarshal(record.Response, &savedResult)
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, savedResult.Transfer.ID)
+	require.Equal(t, result.FromAccount.Balance, savedResult.FromAccount.Balance)
+
+	// reusing the key rolls the second transfer back entirely
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrDuplicateIdempotencyKey)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance+10, updatedAccount2.Balance)
+}
+
+func TestTransferTxFX(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 10000, util.EUR)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	// without a rate the currencies must match
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		FX:            &TransferFX{Rate: "1.08", Spread: "0.005"},
+	})
+	require.NoError(t, err)
+
+	// 1000 * 1.08 * 0.995 = 1074.6, rounded down
+	transfer := result.Transfer
+	require.Equal(t, int64(1000), transfer.Amount)
+	require.Equal(t, int64(1074), transfer.ToAmount)
+	require.Equal(t, "1.0800000000", transfer.Rate)
+	require.Equal(t, "0.005000", transfer.Spread)
+
+	require.Equal(t, int64(-1000), result.FromEntry.Amount)
+	require.Equal(t, int64(1074), result.ToEntry.Amount)
+	require.Equal(t, int64(9000), result.FromAccount.Balance)
+	require.Equal(t, int64(1074), result.ToAccount.Balance)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+		FX:            &TransferFX{Rate: "0.5", Spread: "0"},
+	})
+	require.ErrorIs(t, err, ErrConvertedTooSmall)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 1000, util.USD)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        300,
+	})
+	require.NoError(t, err)
+	require.Equal(t, original.Transfer.ID, original.FromEntry.TransferID.Int64)
+	require.Equal(t, original.Transfer.ID, original.ToEntry.TransferID.Int64)
+
+	partial, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     100,
+	})
+	require.NoError(t, err)
+
+	reversal := partial.Transfer
+	require.Equal(t, original.Transfer.ID, reversal.ReversalOf.Int64)
+	require.Equal(t, account2.ID, reversal.FromAccountID.Int64)
+	require.Equal(t, account1.ID, reversal.ToAccountID.Int64)
+	require.Equal(t, int64(100), reversal.Amount)
+	require.Equal(t, int64(100), reversal.ToAmount)
+
+	require.Equal(t, reversal.ID, partial.FromEntry.TransferID.Int64)
+	require.Equal(t, int64(-100), partial.FromEntry.Amount)
+	require.Equal(t, int64(100), partial.ToEntry.Amount)
+	require.Equal(t, int64(100), partial.FromAccount.Balance)
+	require.Equal(t, int64(800), partial.ToAccount.Balance)
+
+	// only 200 are left to refund
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     250,
+	})
+	require.ErrorIs(t, err, ErrRefundExceedsTransfer)
+
+	rest, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(200), rest.Transfer.Amount)
+	require.Zero(t, rest.FromAccount.Balance)
+	require.Equal(t, account1.Balance, rest.ToAccount.Balance)
+
+	refunds, err := testQueries.GetTransferRefunds(context.Background(), original.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, original.Transfer.ToAmount, refunds.Refunded)
+	require.Equal(t, original.Transfer.Amount, refunds.Credited)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrRefundExceedsTransfer)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: reversal.ID,
+	})
+	require.ErrorIs(t, err, ErrReversalNotReversible)
+}
+
+func TestReverseTransferTxFX(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 10000, util.EUR)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1000,
+		FX:            &TransferFX{Rate: "1.08", Spread: "0.005"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1074), original.Transfer.ToAmount)
+
+	// 500 * 1000 / 1074 = 465.5, rounded down
+	partial, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     500,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), partial.Transfer.Amount)
+	require.Equal(t, int64(465), partial.Transfer.ToAmount)
+	require.Equal(t, "0.9300000000", partial.Transfer.Rate)
+	require.Equal(t, "0.000000", partial.Transfer.Spread)
+
+	// the last refund returns exactly what is left of the debited amount
+	rest, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(574), rest.Transfer.Amount)
+	require.Equal(t, int64(535), rest.Transfer.ToAmount)
+	require.Zero(t, rest.FromAccount.Balance)
+	require.Equal(t, account1.Balance, rest.ToAccount.Balance)
+}
+
+func TestReverseTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createRandomAccountWithBalance(t, 1000, util.USD)
+	account2 := createRandomAccountWithBalance(t, 0, util.USD)
+	account3 := createRandomAccountWithBalance(t, 0, util.USD)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        300,
+	})
+	require.NoError(t, err)
+
+	// the money was spent before the refund
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account3.ID,
+		Amount:        300,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+	})
+
+	var fundsErr *InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	require.Equal(t, account2.ID, fundsErr.AccountID)
+}
+
+func TestIsTransferFailure(t *testing.T) {
+	failures := []error{
+		&InsufficientFundsError{AccountID: 1, Amount: 10},
+		ErrCurrencyMismatch,
+		ErrConvertedTooSmall,
+		util.ErrMoneyOverflow,
+		fmt.Errorf("%w: account [1] holds EUR instead of USD", ErrAccountCurrencyChanged),
+		sql.ErrNoRows,
+		&pq.Error{Code: "22003"},
+		&pq.Error{Code: "23514"},
+	}
+	for _, err := range failures {
+		require.True(t, isTransferFailure(err), err.Error())
+	}
+
+	// failures of the store are left for the caller to retry
+	for _, err := range []error{sql.ErrConnDone, context.DeadlineExceeded, &pq.Error{Code: "40P01"}} {
+		require.False(t, isTransferFailure(err), err.Error())
+	}
+}
@@ -12,20 +12,36 @@ const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    rate,
+    spread,
+    reversal_of
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, rate, spread, reversal_of
 `
 
 type CreateTransferParams struct {
 	FromAccountID sql.NullInt64 `json:"from_account_id"`
 	ToAccountID   sql.NullInt64 `json:"to_account_id"`
 	Amount        int64         `json:"amount"`
+	ToAmount      int64         `json:"to_amount"`
+	Rate          string        `json:"rate"`
+	Spread        string        `json:"spread"`
+	ReversalOf    sql.NullInt64 `json:"reversal_of"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.Rate,
+		arg.Spread,
+		arg.ReversalOf,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -33,25 +49,41 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.Rate,
+		&i.Spread,
+		&i.ReversalOf,
 	)
 	return i, err
 }
 
-const deleteTransfer = `-- name: DeleteTransfer :exec
-DELETE FROM transfers WHERE id = $1
+const getTransfer = `-- name: GetTransfer :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, rate, spread, reversal_of FROM transfers WHERE id = $1 LIMIT 1
 `
 
-func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteTransfer, id)
-	return err
+func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransfer, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.Rate,
+		&i.Spread,
+		&i.ReversalOf,
+	)
+	return i, err
 }
 
-const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers WHERE id = $1 LIMIT 1
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, rate, spread, reversal_of FROM transfers WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE
 `
 
-func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, getTransfer, id)
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -59,12 +91,115 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.Rate,
+		&i.Spread,
+		&i.ReversalOf,
 	)
 	return i, err
 }
 
+const getTransferRefunds = `-- name: GetTransferRefunds :one
+SELECT
+    COALESCE(SUM(amount), 0)::bigint AS refunded,
+    COALESCE(SUM(to_amount), 0)::bigint AS credited
+FROM transfers
+WHERE reversal_of = $1::bigint
+`
+
+type GetTransferRefundsRow struct {
+	Refunded int64 `json:"refunded"`
+	Credited int64 `json:"credited"`
+}
+
+func (q *Queries) GetTransferRefunds(ctx context.Context, transferID int64) (GetTransferRefundsRow, error) {
+	row := q.db.QueryRowContext(ctx, getTransferRefunds, transferID)
+	var i GetTransferRefundsRow
+	err := row.Scan(&i.Refunded, &i.Credited)
+	return i, err
+}
+
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+SELECT transfers.id, transfers.from_account_id, transfers.to_account_id, transfers.amount, transfers.created_at, transfers.to_amount, transfers.rate, transfers.spread, transfers.reversal_of, from_accounts.currency AS from_currency, to_accounts.currency AS to_currency
+FROM transfers
+JOIN accounts AS from_accounts ON from_accounts.id = transfers.from_account_id
+JOIN accounts AS to_accounts ON to_accounts.id = transfers.to_account_id
+WHERE (
+    (transfers.from_account_id = $1::bigint AND $2::text IN ('all', 'out')) OR
+    (transfers.to_account_id = $1::bigint AND $2::text IN ('all', 'in'))
+)
+AND ($3::timestamptz IS NULL OR transfers.created_at >= $3::timestamptz)
+AND ($4::timestamptz IS NULL OR transfers.created_at < $4::timestamptz)
+AND ($5::bigint IS NULL OR CASE WHEN transfers.from_account_id = $1::bigint THEN transfers.amount ELSE transfers.to_amount END >= $5::bigint)
+AND ($6::bigint IS NULL OR CASE WHEN transfers.from_account_id = $1::bigint THEN transfers.amount ELSE transfers.to_amount END <= $6::bigint)
+AND ($7::bigint IS NULL OR transfers.id < $7::bigint)
+ORDER BY transfers.id DESC
+LIMIT $8
+`
+
+type ListAccountTransfersParams struct {
+	AccountID int64         `json:"account_id"`
+	Direction string        `json:"direction"`
+	FromTime  sql.NullTime  `json:"from_time"`
+	ToTime    sql.NullTime  `json:"to_time"`
+	MinAmount sql.NullInt64 `json:"min_amount"`
+	MaxAmount sql.NullInt64 `json:"max_amount"`
+	BeforeID  sql.NullInt64 `json:"before_id"`
+	PageSize  int32         `json:"page_size"`
+}
+
+type ListAccountTransfersRow struct {
+	Transfer     Transfer `json:"transfer"`
+	FromCurrency string   `json:"from_currency"`
+	ToCurrency   string   `json:"to_currency"`
+}
+
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]ListAccountTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.AccountID,
+		arg.Direction,
+		arg.FromTime,
+		arg.ToTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountTransfersRow{}
+	for rows.Next() {
+		var i ListAccountTransfersRow
+		if err := rows.Scan(
+			&i.Transfer.ID,
+			&i.Transfer.FromAccountID,
+			&i.Transfer.ToAccountID,
+			&i.Transfer.Amount,
+			&i.Transfer.CreatedAt,
+			&i.Transfer.ToAmount,
+			&i.Transfer.Rate,
+			&i.Transfer.Spread,
+			&i.Transfer.ReversalOf,
+			&i.FromCurrency,
+			&i.ToCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers ORDER BY id LIMIT $1 OFFSET $2
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, rate, spread, reversal_of FROM transfers ORDER BY id LIMIT $1 OFFSET $2
 `
 
 type ListTransfersParams struct {
@@ -87,6 +222,10 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.Rate,
+			&i.Spread,
+			&i.ReversalOf,
 		); err != nil {
 			return nil, err
 		}
@@ -100,25 +239,3 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
-
-const updateTransfer = `-- name: UpdateTransfer :one
-UPDATE transfers SET amount = $2 WHERE id = $1 RETURNING id, from_account_id, to_account_id, amount, created_at
-`
-
-type UpdateTransferParams struct {
-	ID     int64 `json:"id"`
-	Amount int64 `json:"amount"`
-}
-
-func (q *Queries) UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, updateTransfer, arg.ID, arg.Amount)
-	var i Transfer
-	err := row.Scan(
-		&i.ID,
-		&i.FromAccountID,
-		&i.ToAccountID,
-		&i.Amount,
-		&i.CreatedAt,
-	)
-	return i, err
-}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+)
+
+// Modes of a transfer batch
+const (
+	// TransferBatchAtomic makes the transfers of all the lines or none of them
+	TransferBatchAtomic = "atomic"
+	// TransferBatchBestEffort makes the transfer of each line on its own, the failed lines are left out
+	TransferBatchBestEffort = "best_effort"
+)
+
+// Statuses of a transfer batch
+const (
+	TransferBatchSucceeded          = "succeeded"
+	TransferBatchPartiallySucceeded = "partially_succeeded"
+	TransferBatchFailed             = "failed"
+)
+
+// Statuses of a line of a transfer batch
+const (
+	TransferBatchLineSucceeded = "succeeded"
+	TransferBatchLineFailed    = "failed"
+	// TransferBatchLineSkipped marks the lines of an atomic batch that failed on another line
+	TransferBatchLineSkipped = "skipped"
+)
+
+// TransferBatchLineParams is a transfer of a batch, with an amount in minor units of its currency
+type TransferBatchLineParams struct {
+	LineNumber    int32
+	Reference     string
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        int64
+	Currency      string
+	// FailureReason rejects the line without running it, when it did not pass validation
+	FailureReason string
+}
+
+type CreateTransferBatchTxParams struct {
+	Owner string
+	Mode  string
+	Lines []TransferBatchLineParams
+	// Idempotency saves the result under an idempotency key when set
+	Idempotency *TransferIdempotency
+}
+
+type TransferBatchTxResult struct {
+	Batch TransferBatch       `json:"batch"`
+	Lines []TransferBatchLine `json:"lines"`
+}
+
+// CreateTransferBatchTx makes the transfers of a batch and records the outcome of each line.
+// An atomic batch runs no line once one of them was rejected, and undoes the transfers made
+// when one fails. A best effort batch runs each line within its own savepoint instead.
+// Lines refused for a business reason, such as insufficient funds, are recorded as failed, while
+// failures of the store are returned as errors and record nothing.
+// A batch saved under an idempotency key already used fails with ErrDuplicateIdempotencyKey.
+func (store *SQLStore) CreateTransferBatchTx(ctx context.Context, arg CreateTransferBatchTxParams) (TransferBatchTxResult, error) {
+	var result TransferBatchTxResult
+
+	err := store.ExecTx(ctx, func(q *Queries) error {
+		lines := make([]CreateTransferBatchLineParams, len(arg.Lines))
+		rejected := false
+		for i, line := range arg.Lines {
+			lines[i] = CreateTransferBatchLineParams{
+				LineNumber:    line.LineNumber,
+				Reference:     line.Reference,
+				FromAccountID: line.FromAccountID,
+				ToAccountID:   line.ToAccountID,
+				Amount:        line.Amount,
+				Currency:      line.Currency,
+				Status:        TransferBatchLineSkipped,
+			}
+
+			if line.FailureReason != "" {
+				lines[i].Status = TransferBatchLineFailed
+				lines[i].FailureReason = sql.NullString{String: line.FailureReason, Valid: true}
+				rejected = true
+			}
+		}
+
+		var err error
+		switch {
+		case arg.Mode == TransferBatchBestEffort:
+			err = runTransferBatchLines(ctx, q, lines)
+		case !rejected:
+			err = runAtomicTransferBatch(ctx, q, lines)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Batch, err = q.CreateTransferBatch(ctx, CreateTransferBatchParams{
+			Owner:  arg.Owner,
+			Mode:   arg.Mode,
+			Status: transferBatchStatus(lines),
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Lines = make([]TransferBatchLine, len(lines))
+		for i := range lines {
+			lines[i].BatchID = result.Batch.ID
+
+			result.Lines[i], err = q.CreateTransferBatchLine(ctx, lines[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		if arg.Idempotency != nil {
+			return saveIdempotencyKey(ctx, q, arg.Idempotency, result)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// runAtomicTransferBatch makes the transfers of all the lines within one savepoint.
+// When one fails, the savepoint is rolled back and the other lines are skipped.
+func runAtomicTransferBatch(ctx context.Context, q *Queries, lines []CreateTransferBatchLineParams) error {
+	err := lockTransferBatchAccounts(ctx, q, lines)
+	if err != nil {
+		return err
+	}
+
+	failed := -1
+
+	failure, err := execTransferInSavepoint(ctx, q, "transfer_batch", func() error {
+		for i := range lines {
+			transferID, err := transferBatchLine(ctx, q, lines[i])
+			if err != nil {
+				failed = i
+				return err
+			}
+
+			lines[i].Status = TransferBatchLineSucceeded
+			lines[i].TransferID = transferID
+		}
+
+		return nil
+	})
+	if err != nil || failure == nil {
+		return err
+	}
+
+	// the transfers of the lines before the failed one were rolled back with the savepoint
+	for i := range lines {
+		lines[i].Status = TransferBatchLineSkipped
+		lines[i].TransferID = sql.NullInt64{}
+	}
+
+	lines[failed].Status = TransferBatchLineFailed
+	lines[failed].FailureReason = sql.NullString{String: failure.Error(), Valid: true}
+	return nil
+}
+
+// runTransferBatchLines makes the transfer of each line not rejected yet within a savepoint of its own
+func runTransferBatchLines(ctx context.Context, q *Queries, lines []CreateTransferBatchLineParams) error {
+	err := lockTransferBatchAccounts(ctx, q, lines)
+	if err != nil {
+		return err
+	}
+
+	for i := range lines {
+		if lines[i].Status == TransferBatchLineFailed {
+			continue
+		}
+
+		failure, err := execTransferInSavepoint(ctx, q, "transfer_batch_line", func() error {
+			transferID, err := transferBatchLine(ctx, q, lines[i])
+			if err != nil {
+				return err
+			}
+
+			lines[i].TransferID = transferID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		lines[i].Status = TransferBatchLineSucceeded
+		if failure != nil {
+			lines[i].Status = TransferBatchLineFailed
+			lines[i].FailureReason = sql.NullString{String: failure.Error(), Valid: true}
+		}
+	}
+
+	return nil
+}
+
+// lockTransferBatchAccounts locks the accounts of the lines not rejected yet in ID order, before any
+// of their transfers, so batches running concurrently over the same accounts cannot deadlock.
+// A missing account is left for the transfer of its line to fail on.
+func lockTransferBatchAccounts(ctx context.Context, q *Queries, lines []CreateTransferBatchLineParams) error {
+	var accountIDs []int64
+	for _, line := range lines {
+		if line.Status != TransferBatchLineFailed {
+			accountIDs = append(accountIDs, line.FromAccountID, line.ToAccountID)
+		}
+	}
+
+	slices.Sort(accountIDs)
+	for _, accountID := range slices.Compact(accountIDs) {
+		_, err := q.GetAccountForUpdate(ctx, accountID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transferBatchLine makes the transfer of a line, which fails when the source account
+// no longer holds the currency of the line
+func transferBatchLine(ctx context.Context, q *Queries, line CreateTransferBatchLineParams) (sql.NullInt64, error) {
+	result, err := transferInCurrency(ctx, q, TransferTxParams{
+		FromAccountID: line.FromAccountID,
+		ToAccountID:   line.ToAccountID,
+		Amount:        line.Amount,
+	}, line.Currency)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	return sql.NullInt64{Int64: result.Transfer.ID, Valid: true}, nil
+}
+
+// transferBatchStatus sums up the outcome of the lines
+func transferBatchStatus(lines []CreateTransferBatchLineParams) string {
+	succeeded := 0
+	for _, line := range lines {
+		if line.Status == TransferBatchLineSucceeded {
+			succeeded++
+		}
+	}
+
+	switch succeeded {
+	case len(lines):
+		return TransferBatchSucceeded
+	case 0:
+		return TransferBatchFailed
+	default:
+		return TransferBatchPartiallySucceeded
+	}
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"master_class/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// transferBatchLines pays each receiver the amount from the sender, in the currency of the sender
+func transferBatchLines(sender Account, amount int64, receivers ...Account) []TransferBatchLineParams {
+	lines := make([]TransferBatchLineParams, len(receivers))
+	for i, receiver := range receivers {
+		lines[i] = TransferBatchLineParams{
+			LineNumber:    int32(i + 1),
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        amount,
+			Currency:      sender.Currency,
+		}
+	}
+
+	return lines
+}
+
+func requireBalance(t *testing.T, account Account, balance int64) {
+	accountFromDb, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, balance, accountFromDb.Balance)
+}
+
+func TestCreateTransferBatchTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	sender := createRandomAccountWithBalance(t, 1000, "USD")
+	receiver1 := createRandomAccountWithBalance(t, 0, "USD")
+	receiver2 := createRandomAccountWithBalance(t, 0, "USD")
+
+	result, err := store.CreateTransferBatchTx(context.Background(), CreateTransferBatchTxParams{
+		Owner: sender.Owner,
+		Mode:  TransferBatchAtomic,
+		Lines: transferBatchLines(sender, 300, receiver1, receiver2),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, result.Batch.ID)
+	require.Equal(t, TransferBatchSucceeded, result.Batch.Status)
+	require.Len(t, result.Lines, 2)
+
+	for i, line := range result.Lines {
+		require.Equal(t, result.Batch.ID, line.BatchID)
+		require.Equal(t, int32(i+1), line.LineNumber)
+		require.Equal(t, TransferBatchLineSucceeded, line.Status)
+		require.True(t, line.TransferID.Valid)
+		require.False(t, line.FailureReason.Valid)
+	}
+
+	requireBalance(t, sender, 400)
+	requireBalance(t, receiver1, 300)
+	requireBalance(t, receiver2, 300)
+
+	lines, err := testQueries.ListTransferBatchLines(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Lines, lines)
+}
+
+func TestCreateTransferBatchTxAtomicFailure(t *testing.T) {
+	store := NewStore(testDb)
+
+	sender := createRandomAccountWithBalance(t, 1000, "USD")
+	receiver1 := createRandomAccountWithBalance(t, 0, "USD")
+	receiver2 := createRandomAccountWithBalance(t, 0, "USD")
+
+	// the second line overdraws the sender, so the first one is undone
+	result, err := store.CreateTransferBatchTx(context.Background(), CreateTransferBatchTxParams{
+		Owner: sender.Owner,
+		Mode:  TransferBatchAtomic,
+		Lines: transferBatchLines(sender, 600, receiver1, receiver2),
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchFailed, result.Batch.Status)
+	require.Equal(t, TransferBatchLineSkipped, result.Lines[0].Status)
+	require.False(t, result.Lines[0].TransferID.Valid)
+	require.Equal(t, TransferBatchLineFailed, result.Lines[1].Status)
+	require.Contains(t, result.Lines[1].FailureReason.String, "insufficient funds")
+
+	requireBalance(t, sender, 1000)
+	requireBalance(t, receiver1, 0)
+	requireBalance(t, receiver2, 0)
+
+	// a line rejected by validation keeps the others from running
+	lines := transferBatchLines(sender, 100, receiver1, receiver2)
+	lines[1].FailureReason = "account not found"
+
+	result, err = store.CreateTransferBatchTx(context.Background(), CreateTransferBatchTxParams{
+		Owner: sender.Owner,
+		Mode:  TransferBatchAtomic,
+		Lines: lines,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchFailed, result.Batch.Status)
+	require.Equal(t, TransferBatchLineSkipped, result.Lines[0].Status)
+	require.Equal(t, "account not found", result.Lines[1].FailureReason.String)
+
+	requireBalance(t, sender, 1000)
+}
+
+func TestCreateTransferBatchTxBestEffort(t *testing.T) {
+	store := NewStore(testDb)
+
+	sender := createRandomAccountWithBalance(t, 1000, "USD")
+	receiver1 := createRandomAccountWithBalance(t, 0, "USD")
+	receiver2 := createRandomAccountWithBalance(t, 0, "USD")
+	receiver3 := createRandomAccountWithBalance(t, 0, "USD")
+
+	lines := transferBatchLines(sender, 400, receiver1, receiver2, receiver3)
+	lines[0].FailureReason = "from account doesn't belong to the authenticated user"
+
+	// the third line lacks funds once the second one went through
+	lines[2].Amount = 700
+
+	result, err := store.CreateTransferBatchTx(context.Background(), CreateTransferBatchTxParams{
+		Owner: sender.Owner,
+		Mode:  TransferBatchBestEffort,
+		Lines: lines,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferBatchPartiallySucceeded, result.Batch.Status)
+	require.Equal(t, TransferBatchLineFailed, result.Lines[0].Status)
+	require.Equal(t, TransferBatchLineSucceeded, result.Lines[1].Status)
+	require.True(t, result.Lines[1].TransferID.Valid)
+	require.Equal(t, TransferBatchLineFailed, result.Lines[2].Status)
+	require.Contains(t, result.Lines[2].FailureReason.String, "insufficient funds")
+
+	requireBalance(t, sender, 600)
+	requireBalance(t, receiver1, 0)
+	requireBalance(t, receiver2, 400)
+	requireBalance(t, receiver3, 0)
+
+	batch, err := testQueries.GetTransferBatch(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Batch, batch)
+}
+
+func TestCreateTransferBatchTxIdempotencyKey(t *testing.T) {
+	store := NewStore(testDb)
+
+	sender := createRandomAccountWithBalance(t, 1000, "USD")
+	receiver := createRandomAccountWithBalance(t, 0, "USD")
+
+	arg := CreateTransferBatchTxParams{
+		Owner: sender.Owner,
+		Mode:  TransferBatchAtomic,
+		Lines: transferBatchLines(sender, 300, receiver),
+		Idempotency: &TransferIdempotency{
+			Username:    sender.Owner,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(64),
+		},
+	}
+
+	result, err := store.CreateTransferBatchTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	record, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.Idempotency.Username,
+		Key:      arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.Idempotency.RequestHash, record.RequestHash)
+
+	var savedResult TransferBatchTxResult
+	err = json.Unmarshal(record.Response, &savedResult)
+	require.NoError(t, err)
+	require.Equal(t, result.Batch.ID, savedResult.Batch.ID)
+	require.Len(t, savedResult.Lines, 1)
+
+	// reusing the key rolls the second batch back entirely
+	_, err = store.CreateTransferBatchTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrDuplicateIdempotencyKey)
+
+	requireBalance(t, sender, 700)
+	requireBalance(t, receiver, 300)
+}
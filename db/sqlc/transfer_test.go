@@ -11,10 +11,14 @@ import (
 )
 
 func createRandomTransfer(t *testing.T, from, to Account) Transfer {
+	amount := util.RandomMoney()
 	args := CreateTransferParams{
 		FromAccountID: sql.NullInt64{Int64: from.ID, Valid: true},
 		ToAccountID:   sql.NullInt64{Int64: to.ID, Valid: true},
-		Amount:        util.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		Rate:          "1",
+		Spread:        "0",
 	}
 	transfer, err := testQueries.CreateTransfer(context.Background(), args)
 	require.NoError(t, err)
@@ -23,6 +27,9 @@ func createRandomTransfer(t *testing.T, from, to Account) Transfer {
 	require.Equal(t, args.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, args.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, args.Amount, transfer.Amount)
+	require.Equal(t, args.ToAmount, transfer.ToAmount)
+	require.Equal(t, "1.0000000000", transfer.Rate)
+	require.Equal(t, "0.000000", transfer.Spread)
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
 
@@ -66,31 +73,82 @@ func TestListTransfers(t *testing.T) {
 	require.Len(t, transfers, 5)
 }
 
-func TestUpdateTransfer(t *testing.T) {
-	transfer := createRandomTransfer(t, createRandomAccount(t), createRandomAccount(t))
+func TestListAccountTransfers(t *testing.T) {
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	out := createRandomTransfer(t, account, other)
+	in := createRandomTransfer(t, other, account)
+	latest := createRandomTransfer(t, account, other)
+
+	// transfers between other accounts are left out
+	createRandomTransfer(t, other, createRandomAccount(t))
 
-	args := UpdateTransferParams{
-		ID:     transfer.ID,
-		Amount: util.RandomMoney(),
+	arg := ListAccountTransfersParams{
+		AccountID: account.ID,
+		Direction: "all",
+		PageSize:  5,
 	}
 
-	updatedTransfer, err := testQueries.UpdateTransfer(context.Background(), args)
+	rows, err := testQueries.ListAccountTransfers(context.Background(), arg)
 	require.NoError(t, err)
-	require.NotEmpty(t, updatedTransfer)
+	require.Len(t, rows, 3)
+	require.Equal(t, latest.ID, rows[0].Transfer.ID)
+	require.Equal(t, in.ID, rows[1].Transfer.ID)
+	require.Equal(t, out.ID, rows[2].Transfer.ID)
+	require.Equal(t, other.Currency, rows[1].FromCurrency)
+	require.Equal(t, account.Currency, rows[1].ToCurrency)
+
+	// the cursor resumes after the given transfer
+	arg.BeforeID = sql.NullInt64{Int64: latest.ID, Valid: true}
+	rows, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, in.ID, rows[0].Transfer.ID)
 
-	require.Equal(t, transfer.ID, updatedTransfer.ID)
-	require.Equal(t, args.Amount, updatedTransfer.Amount)
-	require.WithinDuration(t, transfer.CreatedAt, updatedTransfer.CreatedAt, time.Second)
+	arg = ListAccountTransfersParams{
+		AccountID: account.ID,
+		Direction: "in",
+		PageSize:  5,
+	}
+
+	rows, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, in.ID, rows[0].Transfer.ID)
+
+	arg.Direction = "out"
+	arg.MinAmount = sql.NullInt64{Int64: latest.Amount, Valid: true}
+	arg.MaxAmount = sql.NullInt64{Int64: latest.Amount, Valid: true}
+	rows, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.Equal(t, account.ID, row.Transfer.FromAccountID.Int64)
+		require.Equal(t, latest.Amount, row.Transfer.Amount)
+	}
+
+	arg = ListAccountTransfersParams{
+		AccountID: account.ID,
+		Direction: "all",
+		FromTime:  sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		PageSize:  5,
+	}
+
+	rows, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, rows)
 }
 
-func TestDeleteTransfer(t *testing.T) {
+func TestTransfersAppendOnly(t *testing.T) {
 	transfer := createRandomTransfer(t, createRandomAccount(t), createRandomAccount(t))
 
-	err := testQueries.DeleteTransfer(context.Background(), transfer.ID)
-	require.NoError(t, err)
+	_, err := testDb.ExecContext(context.Background(), "UPDATE transfers SET amount = amount + 1 WHERE id = $1", transfer.ID)
+	requireAppendOnly(t, err)
+
+	_, err = testDb.ExecContext(context.Background(), "DELETE FROM transfers WHERE id = $1", transfer.ID)
+	requireAppendOnly(t, err)
 
 	transferFromDb, err := testQueries.GetTransfer(context.Background(), transfer.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, transferFromDb)
+	require.NoError(t, err)
+	require.Equal(t, transfer.Amount, transferFromDb.Amount)
 }
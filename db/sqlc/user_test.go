@@ -29,6 +29,8 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
 
+	require.Equal(t, util.CustomerRole, user.Role)
+
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
 
@@ -49,6 +51,43 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, generatedUser.HashedPassword, userFromDb.HashedPassword)
 	require.Equal(t, generatedUser.FullName, userFromDb.FullName)
 	require.Equal(t, generatedUser.Email, userFromDb.Email)
+	require.Equal(t, generatedUser.Role, userFromDb.Role)
 	require.Equal(t, generatedUser.PasswordChangedAt, userFromDb.PasswordChangedAt)
 	require.WithinDuration(t, generatedUser.CreatedAt, userFromDb.CreatedAt, time.Second)
 }
+
+func TestUpdateUserRole(t *testing.T) {
+	user := createRandomUser(t)
+
+	arg := UpdateUserRoleParams{
+		Username: user.Username,
+		Role:     util.AdminRole,
+	}
+
+	updatedUser, err := testQueries.UpdateUserRole(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updatedUser.Username)
+	require.Equal(t, util.AdminRole, updatedUser.Role)
+
+	arg.Role = "unknown"
+	_, err = testQueries.UpdateUserRole(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	user := createRandomUser(t)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: time.Now().Truncate(time.Microsecond),
+	}
+
+	updatedUser, err := testQueries.UpdateUserPassword(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.HashedPassword, updatedUser.HashedPassword)
+	require.True(t, arg.PasswordChangedAt.Equal(updatedUser.PasswordChangedAt))
+}
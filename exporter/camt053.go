@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CAMT053 writes an ISO 20022 bank to customer statement, message camt.053.001.02
+type CAMT053 struct{}
+
+type camtDocument struct {
+	XMLName   xml.Name           `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02 Document"`
+	Statement camtBankToCustomer `xml:"BkToCstmrStmt"`
+}
+
+type camtBankToCustomer struct {
+	GroupHeader camtGroupHeader `xml:"GrpHdr"`
+	Statement   camtStatement   `xml:"Stmt"`
+}
+
+type camtGroupHeader struct {
+	MessageID string `xml:"MsgId"`
+	CreatedAt string `xml:"CreDtTm"`
+}
+
+type camtStatement struct {
+	ID        string        `xml:"Id"`
+	CreatedAt string        `xml:"CreDtTm"`
+	From      string        `xml:"FrToDt>FrDtTm"`
+	To        string        `xml:"FrToDt>ToDtTm"`
+	Account   camtAccount   `xml:"Acct"`
+	Balances  []camtBalance `xml:"Bal"`
+	Entries   []camtEntry   `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	ID       string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Type        string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Date        string     `xml:"Dt>DtTm"`
+}
+
+type camtEntry struct {
+	Reference       string       `xml:"NtryRef"`
+	Amount          camtAmount   `xml:"Amt"`
+	CreditDebit     string       `xml:"CdtDbtInd"`
+	Status          string       `xml:"Sts"`
+	BookingDate     string       `xml:"BookgDt>DtTm"`
+	ValueDate       string       `xml:"ValDt>DtTm"`
+	TransactionCode string       `xml:"BkTxCd>Prtry>Cd"`
+	Details         *camtDetails `xml:"NtryDtls,omitempty"`
+	Information     string       `xml:"AddtlNtryInf"`
+}
+
+// camtDetails refers to the transfer behind an entry
+type camtDetails struct {
+	TransactionID string `xml:"TxDtls>Refs>TxId"`
+}
+
+// camtTime formats a time as an ISO 20022 datetime, in UTC
+func camtTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// camtCreditDebit tells which way an amount goes, zero counting as a credit
+func camtCreditDebit(amount int64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+
+	return "CRDT"
+}
+
+func (CAMT053) ContentType() string {
+	return "application/xml"
+}
+
+func (CAMT053) Extension() string {
+	return "xml"
+}
+
+func (CAMT053) Encode(w io.Writer, statement Statement) error {
+	balance := func(balanceType string, amount int64, at time.Time) (camtBalance, error) {
+		value, err := absAmount(amount, statement.Currency)
+		if err != nil {
+			return camtBalance{}, err
+		}
+
+		return camtBalance{
+			Type:        balanceType,
+			Amount:      camtAmount{Currency: statement.Currency, Value: value},
+			CreditDebit: camtCreditDebit(amount),
+			Date:        camtTime(at),
+		}, nil
+	}
+
+	opening, err := balance("OPBD", statement.OpeningBalance, statement.From)
+	if err != nil {
+		return err
+	}
+
+	closing, err := balance("CLBD", statement.ClosingBalance, statement.To)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]camtEntry, len(statement.Entries))
+	for i, entry := range statement.Entries {
+		value, err := absAmount(entry.Amount, statement.Currency)
+		if err != nil {
+			return err
+		}
+
+		entries[i] = camtEntry{
+			Reference:       strconv.FormatInt(entry.ID, 10),
+			Amount:          camtAmount{Currency: statement.Currency, Value: value},
+			CreditDebit:     camtCreditDebit(entry.Amount),
+			Status:          "BOOK",
+			BookingDate:     camtTime(entry.BookedAt),
+			ValueDate:       camtTime(entry.BookedAt),
+			TransactionCode: "ADJUSTMENT",
+			Information:     entry.Description(),
+		}
+
+		if entry.TransferID != 0 {
+			entries[i].TransactionCode = "TRANSFER"
+			entries[i].Details = &camtDetails{TransactionID: strconv.FormatInt(entry.TransferID, 10)}
+		}
+	}
+
+	document := camtDocument{
+		Statement: camtBankToCustomer{
+			GroupHeader: camtGroupHeader{
+				MessageID: fmt.Sprintf("STMT-%d-%s", statement.AccountID, statement.CreatedAt.UTC().Format("20060102150405")),
+				CreatedAt: camtTime(statement.CreatedAt),
+			},
+			Statement: camtStatement{
+				ID:        fmt.Sprintf("%d-%s-%s", statement.AccountID, statement.From.UTC().Format("20060102"), statement.To.UTC().Format("20060102")),
+				CreatedAt: camtTime(statement.CreatedAt),
+				From:      camtTime(statement.From),
+				To:        camtTime(statement.To),
+				Account: camtAccount{
+					ID:       strconv.FormatInt(statement.AccountID, 10),
+					Currency: statement.Currency,
+				},
+				Balances: []camtBalance{opening, closing},
+				Entries:  entries,
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return writeXML(w, document)
+}
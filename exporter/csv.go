@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"master_class/util"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{
+	"entry_id",
+	"booked_at",
+	"amount",
+	"currency",
+	"running_balance",
+	"transfer_id",
+	"counterparty_account_id",
+	"description",
+}
+
+// CSV writes one line per entry under a header line, amounts signed as decimals of the currency
+type CSV struct{}
+
+func (CSV) ContentType() string {
+	return "text/csv"
+}
+
+func (CSV) Extension() string {
+	return "csv"
+}
+
+func (CSV) Encode(w io.Writer, statement Statement) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range statement.Entries {
+		amount, err := util.FormatAmount(entry.Amount, statement.Currency)
+		if err != nil {
+			return err
+		}
+
+		runningBalance, err := util.FormatAmount(entry.RunningBalance, statement.Currency)
+		if err != nil {
+			return err
+		}
+
+		record := []string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.BookedAt.UTC().Format(time.RFC3339),
+			amount,
+			statement.Currency,
+			runningBalance,
+			"",
+			"",
+			entry.Description(),
+		}
+
+		if entry.TransferID != 0 {
+			record[5] = strconv.FormatInt(entry.TransferID, 10)
+			record[6] = strconv.FormatInt(entry.CounterpartyAccountID, 10)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
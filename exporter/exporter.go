@@ -0,0 +1,117 @@
+// Package exporter encodes account statements in the file formats of accounting tools.
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"master_class/util"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Statement is the activity of an account over a period, with its balances at both ends
+type Statement struct {
+	AccountID      int64
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	Entries        []Entry
+	// CreatedAt stamps the exported file. It is kept apart from the clock so that exports are reproducible.
+	CreatedAt time.Time
+}
+
+// Entry is a line of a statement. Amounts are in minor units of the currency of the statement.
+type Entry struct {
+	ID     int64
+	Amount int64
+	// RunningBalance is the balance of the account right after the entry
+	RunningBalance int64
+	BookedAt       time.Time
+	// TransferID and CounterpartyAccountID are zero for entries not made by a transfer
+	TransferID            int64
+	CounterpartyAccountID int64
+}
+
+// Description tells what the entry was made for, in a few words
+func (entry Entry) Description() string {
+	switch {
+	case entry.TransferID == 0:
+		return "Adjustment"
+	case entry.Amount < 0:
+		return fmt.Sprintf("Transfer %d to account %d", entry.TransferID, entry.CounterpartyAccountID)
+	default:
+		return fmt.Sprintf("Transfer %d from account %d", entry.TransferID, entry.CounterpartyAccountID)
+	}
+}
+
+// Encoder writes statements in one file format
+type Encoder interface {
+	// ContentType is the media type of the files, used to negotiate the format with clients
+	ContentType() string
+	// Extension is the usual file name extension of the format, without the dot
+	Extension() string
+	Encode(w io.Writer, statement Statement) error
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{}
+)
+
+func init() {
+	Register("csv", CSV{})
+	Register("ofx", OFX{BankID: DefaultBankID})
+	Register("camt053", CAMT053{})
+}
+
+// Register makes an encoder available under the given format name.
+// It panics if the name is taken, like database/sql does for drivers.
+func Register(format string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if encoder == nil {
+		panic("exporter: Register encoder is nil")
+	}
+
+	if _, dup := encoders[format]; dup {
+		panic("exporter: Register called twice for format " + format)
+	}
+
+	encoders[format] = encoder
+}
+
+// Lookup returns the encoder registered under the format name
+func Lookup(format string) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	encoder, ok := encoders[format]
+	return encoder, ok
+}
+
+// Formats returns the names of the registered formats, sorted
+func Formats() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	formats := make([]string, 0, len(encoders))
+	for format := range encoders {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// absAmount formats the magnitude of an amount, for formats carrying the sign apart
+func absAmount(amount int64, currency string) (string, error) {
+	if amount < 0 {
+		amount = -amount
+	}
+
+	return util.FormatAmount(amount, currency)
+}
@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files with the current output, run `go test ./exporter -update` after a deliberate change
+var update = flag.Bool("update", false, "update golden files")
+
+// testStatement covers credits, debits and an adjustment, with an opening balance on the debit side
+func testStatement() Statement {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	return Statement{
+		AccountID:      42,
+		Currency:       "USD",
+		From:           from,
+		To:             time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: -250,
+		ClosingBalance: 1425,
+		Entries: []Entry{
+			{
+				ID:                    101,
+				Amount:                2000,
+				RunningBalance:        1750,
+				BookedAt:              from.Add(9 * time.Hour),
+				TransferID:            7,
+				CounterpartyAccountID: 13,
+			},
+			{
+				ID:                    102,
+				Amount:                -350,
+				RunningBalance:        1400,
+				BookedAt:              from.AddDate(0, 0, 4).Add(14*time.Hour + 30*time.Minute),
+				TransferID:            8,
+				CounterpartyAccountID: 21,
+			},
+			{
+				ID:             103,
+				Amount:         25,
+				RunningBalance: 1425,
+				BookedAt:       from.AddDate(0, 0, 20),
+			},
+		},
+		CreatedAt: time.Date(2024, 4, 2, 8, 15, 0, 0, time.UTC),
+	}
+}
+
+func TestEncodeGolden(t *testing.T) {
+	testCases := []struct {
+		format string
+		golden string
+	}{
+		{"csv", "statement.csv"},
+		{"ofx", "statement.ofx"},
+		{"camt053", "statement.camt053.xml"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			encoder, ok := Lookup(tc.format)
+			require.True(t, ok)
+
+			var buf bytes.Buffer
+			require.NoError(t, encoder.Encode(&buf, testStatement()))
+
+			golden := filepath.Join("testdata", tc.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestEncodeEmptyStatement(t *testing.T) {
+	statement := testStatement()
+	statement.Entries = nil
+	statement.ClosingBalance = statement.OpeningBalance
+
+	for _, format := range Formats() {
+		encoder, _ := Lookup(format)
+
+		var buf bytes.Buffer
+		require.NoError(t, encoder.Encode(&buf, statement), format)
+		require.NotEmpty(t, buf.String(), format)
+	}
+}
+
+func TestEncodeUnsupportedCurrency(t *testing.T) {
+	statement := testStatement()
+	statement.Currency = "XXX"
+
+	for _, format := range Formats() {
+		encoder, _ := Lookup(format)
+		require.Error(t, encoder.Encode(&bytes.Buffer{}, statement), format)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	require.Equal(t, []string{"camt053", "csv", "ofx"}, Formats())
+
+	encoder, ok := Lookup("ofx")
+	require.True(t, ok)
+	require.Equal(t, "application/x-ofx", encoder.ContentType())
+	require.Equal(t, "ofx", encoder.Extension())
+
+	_, ok = Lookup("pdf")
+	require.False(t, ok)
+
+	require.Panics(t, func() { Register("csv", CSV{}) })
+	require.Panics(t, func() { Register("none", nil) })
+}
@@ -0,0 +1,165 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"io"
+	"master_class/util"
+	"strconv"
+	"time"
+)
+
+// DefaultBankID identifies the bank in OFX files when no other is configured
+const DefaultBankID = "000000000"
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// OFX writes a bank statement download in OFX 2.2, the XML flavour of Open Financial Exchange
+type OFX struct {
+	// BankID is the routing number of the bank holding the accounts
+	BankID string
+}
+
+type ofxDocument struct {
+	XMLName   xml.Name                `xml:"OFX"`
+	SignOn    ofxSignOnResponse       `xml:"SIGNONMSGSRSV1>SONRS"`
+	Statement ofxStatementTransaction `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOnResponse struct {
+	Status     ofxStatus `xml:"STATUS"`
+	ServerTime string    `xml:"DTSERVER"`
+	Language   string    `xml:"LANGUAGE"`
+}
+
+type ofxStatementTransaction struct {
+	TransactionUID string       `xml:"TRNUID"`
+	Status         ofxStatus    `xml:"STATUS"`
+	Statement      ofxStatement `xml:"STMTRS"`
+}
+
+type ofxStatement struct {
+	Currency      string             `xml:"CURDEF"`
+	Account       ofxBankAccount     `xml:"BANKACCTFROM"`
+	Transactions  ofxTransactionList `xml:"BANKTRANLIST"`
+	LedgerBalance ofxBalance         `xml:"LEDGERBAL"`
+}
+
+type ofxBankAccount struct {
+	BankID      string `xml:"BANKID"`
+	AccountID   string `xml:"ACCTID"`
+	AccountType string `xml:"ACCTTYPE"`
+}
+
+type ofxTransactionList struct {
+	Start        string           `xml:"DTSTART"`
+	End          string           `xml:"DTEND"`
+	Transactions []ofxTransaction `xml:"STMTTRN"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Memo   string `xml:"MEMO"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	AsOf   string `xml:"DTASOF"`
+}
+
+// ofxTime formats a time as an OFX datetime, in UTC
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+func (OFX) ContentType() string {
+	return "application/x-ofx"
+}
+
+func (OFX) Extension() string {
+	return "ofx"
+}
+
+func (encoder OFX) Encode(w io.Writer, statement Statement) error {
+	closingBalance, err := util.FormatAmount(statement.ClosingBalance, statement.Currency)
+	if err != nil {
+		return err
+	}
+
+	transactions := make([]ofxTransaction, len(statement.Entries))
+	for i, entry := range statement.Entries {
+		amount, err := util.FormatAmount(entry.Amount, statement.Currency)
+		if err != nil {
+			return err
+		}
+
+		transactions[i] = ofxTransaction{
+			Type:   "CREDIT",
+			Posted: ofxTime(entry.BookedAt),
+			Amount: amount,
+			FITID:  strconv.FormatInt(entry.ID, 10),
+			Memo:   entry.Description(),
+		}
+
+		if entry.Amount < 0 {
+			transactions[i].Type = "DEBIT"
+		}
+	}
+
+	document := ofxDocument{
+		SignOn: ofxSignOnResponse{
+			Status:     ofxStatus{Code: 0, Severity: "INFO"},
+			ServerTime: ofxTime(statement.CreatedAt),
+			Language:   "ENG",
+		},
+		Statement: ofxStatementTransaction{
+			TransactionUID: "0",
+			Status:         ofxStatus{Code: 0, Severity: "INFO"},
+			Statement: ofxStatement{
+				Currency: statement.Currency,
+				Account: ofxBankAccount{
+					BankID:      encoder.BankID,
+					AccountID:   strconv.FormatInt(statement.AccountID, 10),
+					AccountType: "CHECKING",
+				},
+				Transactions: ofxTransactionList{
+					Start:        ofxTime(statement.From),
+					End:          ofxTime(statement.To),
+					Transactions: transactions,
+				},
+				LedgerBalance: ofxBalance{
+					Amount: closingBalance,
+					AsOf:   ofxTime(statement.To),
+				},
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return err
+	}
+
+	return writeXML(w, document)
+}
+
+// writeXML writes the indented document, ended by a new line
+func writeXML(w io.Writer, document any) error {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
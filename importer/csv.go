@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	csvFromAccountID = "from_account_id"
+	csvToAccountID   = "to_account_id"
+	csvAmount        = "amount"
+	csvCurrency      = "currency"
+	csvReference     = "reference"
+)
+
+// ParseCSV reads a file with a header line naming its columns, in any order:
+// from_account_id, to_account_id, amount and currency, then an optional reference.
+// Unknown columns are ignored.
+func ParseCSV(r io.Reader) ([]Line, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing CSV header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{csvFromAccountID, csvToAccountID, csvAmount, csvCurrency} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %s", name)
+		}
+	}
+
+	var lines []Line
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line := Line{
+			Number:   len(lines) + 1,
+			Amount:   record[columns[csvAmount]],
+			Currency: strings.ToUpper(record[columns[csvCurrency]]),
+		}
+
+		if i, ok := columns[csvReference]; ok {
+			line.Reference = record[i]
+		}
+
+		line.FromAccountID, err = parseAccountID(record[columns[csvFromAccountID]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.Number, err)
+		}
+
+		line.ToAccountID, err = parseAccountID(record[columns[csvToAccountID]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.Number, err)
+		}
+
+		lines = append(lines, line)
+	}
+}
@@ -0,0 +1,57 @@
+// Package importer reads batches of transfers from the files of payroll and accounting tools.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+)
+
+// ErrUnsupportedContentType is returned by Parse for files of a media type no parser reads
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Line is a transfer of a batch, as given in the file. Accounts and amounts are not checked beyond their syntax.
+type Line struct {
+	// Number is the position of the transfer in the file, counting from 1
+	Number        int
+	Reference     string
+	FromAccountID int64
+	ToAccountID   int64
+	// Amount is a decimal in the currency, such as "12.34"
+	Amount   string
+	Currency string
+}
+
+// parsers read the files of each media type
+var parsers = map[string]func(r io.Reader) ([]Line, error){
+	"text/csv":        ParseCSV,
+	"application/xml": ParsePain001,
+	"text/xml":        ParsePain001,
+}
+
+// Parse reads the lines of a file with the parser of its media type, parameters such as the charset are ignored
+func Parse(contentType string, r io.Reader) ([]Line, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedContentType, contentType)
+	}
+
+	parse, ok := parsers[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedContentType, mediaType)
+	}
+
+	return parse(r)
+}
+
+// parseAccountID reads the ID of an account, which must be positive
+func parseAccountID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid account ID %q", value)
+	}
+
+	return id, nil
+}
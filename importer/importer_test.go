@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestdata(t *testing.T, contentType string, name string) []Line {
+	file, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer file.Close()
+
+	lines, err := Parse(contentType, file)
+	require.NoError(t, err)
+
+	return lines
+}
+
+func TestParseCSV(t *testing.T) {
+	lines := parseTestdata(t, "text/csv; charset=utf-8", "payroll.csv")
+
+	require.Equal(t, []Line{
+		{Number: 1, Reference: "salary-2024-03-alice", FromAccountID: 12, ToAccountID: 31, Amount: "2500.00", Currency: "USD"},
+		{Number: 2, Reference: "salary-2024-03-bob", FromAccountID: 12, ToAccountID: 32, Amount: "1875.50", Currency: "USD"},
+		{Number: 3, FromAccountID: 12, ToAccountID: 33, Amount: "990", Currency: "USD"},
+	}, lines)
+}
+
+func TestParseCSVInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		file string
+	}{
+		{"Empty", ""},
+		{"MissingColumn", "from_account_id,to_account_id,amount\n1,2,3.00\n"},
+		{"FieldCount", "from_account_id,to_account_id,amount,currency\n1,2,3.00\n"},
+		{"InvalidFromAccount", "from_account_id,to_account_id,amount,currency\nabc,2,3.00,USD\n"},
+		{"InvalidToAccount", "from_account_id,to_account_id,amount,currency\n1,0,3.00,USD\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tc.file))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParsePain001(t *testing.T) {
+	lines := parseTestdata(t, "application/xml", "payroll.pain001.xml")
+
+	require.Equal(t, []Line{
+		{Number: 1, Reference: "salary-2024-03-alice", FromAccountID: 12, ToAccountID: 31, Amount: "2500.00", Currency: "USD"},
+		{Number: 2, Reference: "salary-2024-03-bob", FromAccountID: 12, ToAccountID: 32, Amount: "1875.50", Currency: "USD"},
+		{Number: 3, Reference: "NOTPROVIDED", FromAccountID: 14, ToAccountID: 33, Amount: "990", Currency: "EUR"},
+	}, lines)
+}
+
+func TestParsePain001Invalid(t *testing.T) {
+	transaction := `<CdtTrfTxInf><Amt><InstdAmt Ccy="USD">1.00</InstdAmt></Amt><CdtrAcct><Id><Othr><Id>%s</Id></Othr></Id></CdtrAcct></CdtTrfTxInf>`
+	document := func(count string, debtor string, creditor string) string {
+		return `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"><CstmrCdtTrfInitn>` +
+			`<GrpHdr><NbOfTxs>` + count + `</NbOfTxs></GrpHdr>` +
+			`<PmtInf><DbtrAcct><Id><Othr><Id>` + debtor + `</Id></Othr></Id></DbtrAcct>` +
+			strings.Replace(transaction, "%s", creditor, 1) +
+			`</PmtInf></CstmrCdtTrfInitn></Document>`
+	}
+
+	_, err := ParsePain001(strings.NewReader(document("1", "12", "31")))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		file string
+	}{
+		{"Malformed", "<Document>"},
+		{"OtherMessage", `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt/></Document>`},
+		{"WrongCount", document("2", "12", "31")},
+		{"InvalidDebtor", document("1", "IBAN", "31")},
+		{"InvalidCreditor", document("1", "12", "")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParsePain001(strings.NewReader(tc.file))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseUnsupportedContentType(t *testing.T) {
+	for _, contentType := range []string{"application/json", "", "text/csv;;"} {
+		_, err := Parse(contentType, strings.NewReader(""))
+		require.ErrorIs(t, err, ErrUnsupportedContentType, contentType)
+	}
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pain001Namespace prefixes the namespaces of all the versions of the message
+const pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001."
+
+var errNotPain001 = errors.New("not an ISO 20022 customer credit transfer initiation (pain.001)")
+
+type painDocument struct {
+	XMLName    xml.Name
+	Initiation *painInitiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type painInitiation struct {
+	NumberOfTransactions string                   `xml:"GrpHdr>NbOfTxs"`
+	PaymentInformation   []painPaymentInformation `xml:"PmtInf"`
+}
+
+type painPaymentInformation struct {
+	DebtorAccount string            `xml:"DbtrAcct>Id>Othr>Id"`
+	Transactions  []painTransaction `xml:"CdtTrfTxInf"`
+}
+
+type painTransaction struct {
+	EndToEndID      string     `xml:"PmtId>EndToEndId"`
+	Amount          painAmount `xml:"Amt>InstdAmt"`
+	CreditorAccount string     `xml:"CdtrAcct>Id>Othr>Id"`
+}
+
+type painAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// ParsePain001 reads an ISO 20022 customer credit transfer initiation, of any version of pain.001.
+// Accounts are identified by their ID as the other identification of the debtor and creditor accounts,
+// the end to end IDs become the references of the lines.
+func ParsePain001(r io.Reader) ([]Line, error) {
+	var document painDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+
+	if document.XMLName.Local != "Document" ||
+		!strings.HasPrefix(document.XMLName.Space, pain001Namespace) ||
+		document.Initiation == nil {
+		return nil, errNotPain001
+	}
+
+	var lines []Line
+	for _, payment := range document.Initiation.PaymentInformation {
+		fromAccountID, err := parseAccountID(strings.TrimSpace(payment.DebtorAccount))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: debtor: %w", len(lines)+1, err)
+		}
+
+		for _, transaction := range payment.Transactions {
+			line := Line{
+				Number:        len(lines) + 1,
+				Reference:     strings.TrimSpace(transaction.EndToEndID),
+				FromAccountID: fromAccountID,
+				Amount:        strings.TrimSpace(transaction.Amount.Value),
+				Currency:      transaction.Amount.Currency,
+			}
+
+			line.ToAccountID, err = parseAccountID(strings.TrimSpace(transaction.CreditorAccount))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: creditor: %w", line.Number, err)
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	// the count is optional in some versions, but must agree when given
+	if count := strings.TrimSpace(document.Initiation.NumberOfTransactions); count != "" {
+		if count != strconv.Itoa(len(lines)) {
+			return nil, fmt.Errorf("group header counts %s transactions, the file has %d", count, len(lines))
+		}
+	}
+
+	return lines, nil
+}
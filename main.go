@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"master_class/api"
 	db "master_class/db/sqlc"
+	"master_class/scheduler"
 	"master_class/util"
+	"os"
 
 	_ "github.com/lib/pq"
 )
@@ -22,6 +25,26 @@ func main() {
 	}
 
 	store := db.NewStore(conn)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reconcile":
+			os.Exit(runReconcile(store, os.Args[2:]))
+		case "set-role":
+			os.Exit(runSetRole(store, os.Args[2:]))
+		}
+	}
+
+	if config.ScheduledTransferInterval > 0 {
+		executor := scheduler.NewTransferExecutor(store, config.ScheduledTransferInterval)
+		go executor.Start(context.Background())
+	}
+
+	if config.RevokedTokenPurgeInterval > 0 {
+		purger := scheduler.NewTokenPurger(store, config.RevokedTokenPurgeInterval)
+		go purger.Start(context.Background())
+	}
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
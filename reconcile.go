@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	db "master_class/db/sqlc"
+	"os"
+)
+
+// runReconcile checks the ledger and prints the report as JSON.
+// It returns the exit status, 1 when findings are left unrepaired so that it can alert from cron.
+func runReconcile(store db.Store, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	batchSize := flags.Int("batch-size", db.DefaultReconciliationBatchSize, "accounts and transfers read at a time")
+	repair := flags.Bool("repair", false, "make up each balance drift with an adjustment entry")
+	confirm := flags.Bool("confirm", false, "confirm the repair, nothing is written without it")
+	flags.Parse(args)
+
+	if *repair && !*confirm {
+		log.Print("repair writes adjustment entries, run again with -confirm to go ahead")
+		return 2
+	}
+
+	report, err := store.Reconcile(context.Background(), db.ReconcileParams{
+		BatchSize: int32(*batchSize),
+		Repair:    *repair,
+	})
+	if err != nil {
+		log.Print("cannot reconcile the ledger: ", err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Print("cannot print the report: ", err)
+		return 2
+	}
+
+	if report.Unresolved() {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	db "master_class/db/sqlc"
+	"time"
+)
+
+// TokenPurger periodically deletes the revoked tokens that have expired, which would be rejected anyway
+type TokenPurger struct {
+	store    db.Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewTokenPurger(store db.Store, interval time.Duration) *TokenPurger {
+	return &TokenPurger{
+		store:    store,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start purges the expired tokens every interval until the context is done
+func (purger *TokenPurger) Start(ctx context.Context) {
+	ticker := time.NewTicker(purger.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := purger.Purge(ctx); err != nil {
+			log.Println("cannot purge revoked tokens:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the revoked tokens expired by now
+func (purger *TokenPurger) Purge(ctx context.Context) error {
+	return purger.store.DeleteExpiredRevokedTokens(ctx, purger.now())
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	db "master_class/db/sqlc"
+	"time"
+)
+
+// TransferExecutor periodically runs the scheduled transfers and standing orders that have fallen due
+type TransferExecutor struct {
+	store    db.Store
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewTransferExecutor(store db.Store, interval time.Duration) *TransferExecutor {
+	return &TransferExecutor{
+		store:    store,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start runs the due transfers every interval until the context is done
+func (executor *TransferExecutor) Start(ctx context.Context) {
+	ticker := time.NewTicker(executor.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := executor.RunDue(ctx); err != nil {
+			log.Println("cannot run scheduled transfers:", err)
+		}
+
+		if _, err := executor.RunDueStandingOrders(ctx); err != nil {
+			log.Println("cannot run standing orders:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue executes every transfer due by now, one transaction each,
+// and returns how many were executed. A transfer whose attempt failed
+// on the store is retried by a later run.
+func (executor *TransferExecutor) RunDue(ctx context.Context) (int, error) {
+	now := executor.now()
+
+	count := 0
+	for {
+		if ctx.Err() != nil {
+			return count, ctx.Err()
+		}
+
+		scheduled, err := executor.store.ExecuteScheduledTransferTx(ctx, now)
+		if err == sql.ErrNoRows {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		switch scheduled.Status {
+		case db.ScheduledTransferPending:
+			log.Printf("scheduled transfer [%d] attempt %d failed, retrying at %s: %s",
+				scheduled.ID, scheduled.Attempts, scheduled.RetryAt.Time.Format(time.RFC3339), scheduled.FailureReason.String)
+			continue
+		case db.ScheduledTransferFailed:
+			log.Printf("scheduled transfer [%d] failed: %s", scheduled.ID, scheduled.FailureReason.String)
+		}
+
+		count++
+	}
+}
+
+// RunDueStandingOrders executes every standing order run due by now, one transaction each,
+// and returns how many runs were made. An occurrence whose run failed on the store is
+// retried by a later run.
+func (executor *TransferExecutor) RunDueStandingOrders(ctx context.Context) (int, error) {
+	now := executor.now()
+
+	for count := 0; ; count++ {
+		if ctx.Err() != nil {
+			return count, ctx.Err()
+		}
+
+		run, err := executor.store.ExecuteStandingOrderTx(ctx, now)
+		if err == sql.ErrNoRows {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		if run.Status != db.StandingOrderRunSucceeded {
+			log.Printf("standing order [%d] run %s: %s", run.StandingOrderID, run.Status, run.FailureReason.String)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	mockdb "master_class/db/mock"
+	db "master_class/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRunDue(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		expectedCount int
+		expectError   bool
+	}{
+		{
+			name: "NothingDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			expectedCount: 0,
+		},
+		{
+			name: "RunsUntilNoneDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.ScheduledTransfer{ID: 1, Status: db.ScheduledTransferSucceeded}, nil),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.ScheduledTransfer{
+							ID:            2,
+							Status:        db.ScheduledTransferFailed,
+							FailureReason: sql.NullString{String: "insufficient funds", Valid: true},
+						}, nil),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.ScheduledTransfer{}, sql.ErrNoRows),
+				)
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "RetriesLater",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.ScheduledTransfer{
+							ID:            1,
+							Status:        db.ScheduledTransferPending,
+							Attempts:      1,
+							RetryAt:       sql.NullTime{Time: now.Add(db.ScheduledTransferRetryDelay), Valid: true},
+							FailureReason: sql.NullString{String: "deadlock detected", Valid: true},
+						}, nil),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.ScheduledTransfer{ID: 2, Status: db.ScheduledTransferSucceeded}, nil),
+					store.EXPECT().
+						ExecuteScheduledTransferTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.ScheduledTransfer{}, sql.ErrNoRows),
+				)
+			},
+			expectedCount: 1,
+		},
+		{
+			name: "StoreFailed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrConnDone)
+			},
+			expectedCount: 0,
+			expectError:   true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			executor := NewTransferExecutor(store, time.Minute)
+			executor.now = func() time.Time { return now }
+
+			count, err := executor.RunDue(context.Background())
+			if tc.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectedCount, count)
+		})
+	}
+}
+
+func TestRunDueStandingOrders(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		expectedCount int
+		expectError   bool
+	}{
+		{
+			name: "NothingDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+					Times(1).
+					Return(db.StandingOrderRun{}, sql.ErrNoRows)
+			},
+			expectedCount: 0,
+		},
+		{
+			name: "RunsUntilNoneDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.StandingOrderRun{ID: 1, StandingOrderID: 1, Status: db.StandingOrderRunSucceeded}, nil),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.StandingOrderRun{
+							ID:              2,
+							StandingOrderID: 2,
+							Status:          db.StandingOrderRunRetrying,
+							FailureReason:   sql.NullString{String: "insufficient funds", Valid: true},
+						}, nil),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "RetriesLater",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.StandingOrderRun{
+							ID:              1,
+							StandingOrderID: 1,
+							Status:          db.StandingOrderRunRetrying,
+							FailureReason:   sql.NullString{String: "deadlock detected", Valid: true},
+						}, nil),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.StandingOrderRun{ID: 2, StandingOrderID: 2, Status: db.StandingOrderRunSucceeded}, nil),
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(now)).
+						Return(db.StandingOrderRun{}, sql.ErrNoRows),
+				)
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "StoreFailed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrderRun{}, sql.ErrConnDone)
+			},
+			expectedCount: 0,
+			expectError:   true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			executor := NewTransferExecutor(store, time.Minute)
+			executor.now = func() time.Time { return now }
+
+			count, err := executor.RunDueStandingOrders(context.Background())
+			if tc.expectError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectedCount, count)
+		})
+	}
+}
+
+func TestStartStopsWithContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+	store.EXPECT().
+		ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.StandingOrderRun{}, sql.ErrNoRows)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		NewTransferExecutor(store, time.Millisecond).Start(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("executor did not stop")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	db "master_class/db/sqlc"
+	"master_class/util"
+	"slices"
+)
+
+// runSetRole grants a role to a user, so the first admin can be set up before any can use the API.
+// It returns the exit status.
+func runSetRole(store db.Store, args []string) int {
+	flags := flag.NewFlagSet("set-role", flag.ExitOnError)
+	username := flags.String("username", "", "user to grant the role to")
+	role := flags.String("role", "", "role to grant: customer, teller or admin")
+	flags.Parse(args)
+
+	if *username == "" || !slices.Contains([]string{util.CustomerRole, util.TellerRole, util.AdminRole}, *role) {
+		flags.Usage()
+		return 2
+	}
+
+	user, err := store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+		Username: *username,
+		Role:     *role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("user %q not found", *username)
+			return 1
+		}
+
+		log.Print("cannot set the role: ", err)
+		return 1
+	}
+
+	log.Printf("user %q now has the %s role", user.Username, user.Role)
+	return 0
+}
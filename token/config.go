@@ -0,0 +1,172 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"master_class/util"
+	"strings"
+)
+
+// Token types selectable with TOKEN_TYPE
+const (
+	TypePasetoLocal  = "paseto_local"
+	TypePasetoPublic = "paseto_public"
+	TypeJWT          = "jwt"
+	TypeJWTEdDSA     = "jwt_eddsa"
+)
+
+// NewMaker creates a key ring signing with the key selected by the config.
+// PASETO v2.local is used when no token type is configured.
+func NewMaker(config util.Config) (*KeyRing, error) {
+	active, err := newMaker(config.TokenType, config.TokenSymmetricKey, config.TokenPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := newPreviousVerifiers(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(config.TokenKeyID, active, previous)
+}
+
+// NewVerifier creates a verify-only counterpart of the configured asymmetric key ring.
+// It needs only the public keys, so services using it cannot mint tokens.
+func NewVerifier(config util.Config) (*VerifierRing, error) {
+	active, err := newPublicVerifier(config.TokenType, config.TokenPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := newPreviousVerifiers(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVerifierRing(config.TokenKeyID, active, previous)
+}
+
+func newMaker(tokenType string, symmetricKey string, privateKey string) (Maker, error) {
+	switch tokenType {
+	case "", TypePasetoLocal:
+		return NewPasetoMaker(symmetricKey)
+	case TypeJWT:
+		return NewJWTMaker(symmetricKey)
+	case TypePasetoPublic, TypeJWTEdDSA:
+		key, err := ParsePrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if tokenType == TypePasetoPublic {
+			return NewPasetoPublicMaker(key)
+		}
+
+		return NewJWTEdDSAMaker(key)
+	}
+
+	return nil, fmt.Errorf("unsupported token type %q", tokenType)
+}
+
+func newPublicVerifier(tokenType string, publicKey string) (Verifier, error) {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tokenType {
+	case TypePasetoPublic:
+		return NewPasetoPublicVerifier(key)
+	case TypeJWTEdDSA:
+		return NewJWTEdDSAVerifier(key)
+	}
+
+	return nil, fmt.Errorf("token type %q cannot be verified with a public key", tokenType)
+}
+
+// newPreviousVerifiers creates the verifiers of the keys listed in TOKEN_PREVIOUS_KEYS.
+// Symmetric token types list the secret itself, asymmetric ones the public key.
+func newPreviousVerifiers(config util.Config) (map[string]Verifier, error) {
+	keys, err := ParseKeyList(config.TokenPreviousKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiers := make(map[string]Verifier, len(keys))
+	for keyID, key := range keys {
+		var verifier Verifier
+
+		switch config.TokenType {
+		case "", TypePasetoLocal, TypeJWT:
+			verifier, err = newMaker(config.TokenType, key, "")
+		default:
+			verifier, err = newPublicVerifier(config.TokenType, key)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous key %q: %w", keyID, err)
+		}
+
+		verifiers[keyID] = verifier
+	}
+
+	return verifiers, nil
+}
+
+// ParseKeyList decodes a comma separated list of "kid:key" entries
+func ParseKeyList(list string) (map[string]string, error) {
+	keys := make(map[string]string)
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyID, key, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" || key == "" {
+			return nil, fmt.Errorf("invalid key entry %q: must be formatted as kid:key", entry)
+		}
+
+		if _, ok := keys[keyID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", keyID)
+		}
+
+		keys[keyID] = key
+	}
+
+	return keys, nil
+}
+
+// ParsePrivateKey decodes a hex encoded Ed25519 seed or private key
+func ParsePrivateKey(hexKey string) (ed25519.PrivateKey, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode private key: %w", err)
+	}
+
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key), nil
+	}
+
+	return nil, fmt.Errorf("invalid private key size: must be %d or %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize)
+}
+
+// ParsePublicKey decodes a hex encoded Ed25519 public key
+func ParsePublicKey(hexKey string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode public key: %w", err)
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	return ed25519.PublicKey(key), nil
+}
@@ -0,0 +1,91 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"master_class/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMakerFromConfig(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, tokenType := range []string{TypePasetoPublic, TypeJWTEdDSA} {
+		config := util.Config{
+			TokenType:       tokenType,
+			TokenPrivateKey: hex.EncodeToString(privateKey.Seed()),
+			TokenPublicKey:  hex.EncodeToString(publicKey),
+		}
+
+		maker, err := NewMaker(config)
+		require.NoError(t, err)
+
+		verifier, err := NewVerifier(config)
+		require.NoError(t, err)
+
+		username := util.RandomOwner()
+		token, _, err := maker.CreateToken(username, util.CustomerRole, AccessToken, time.Minute)
+		require.NoError(t, err)
+
+		payload, err := verifier.VerifyToken(token)
+		require.NoError(t, err)
+		require.Equal(t, username, payload.Username)
+	}
+
+	maker, err := NewMaker(util.Config{TokenSymmetricKey: util.RandomString(32)})
+	require.NoError(t, err)
+	require.IsType(t, &PasetoMaker{}, maker.maker)
+
+	_, err = NewMaker(util.Config{TokenType: "unknown"})
+	require.Error(t, err)
+
+	_, err = NewVerifier(util.Config{TokenType: TypePasetoLocal, TokenPublicKey: hex.EncodeToString(publicKey)})
+	require.Error(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	parsed, err := ParsePrivateKey(hex.EncodeToString(privateKey))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsed)
+
+	parsed, err = ParsePrivateKey(hex.EncodeToString(privateKey.Seed()))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, parsed)
+
+	parsedPublic, err := ParsePublicKey(hex.EncodeToString(publicKey))
+	require.NoError(t, err)
+	require.Equal(t, publicKey, parsedPublic)
+
+	_, err = ParsePrivateKey("not-hex")
+	require.Error(t, err)
+
+	_, err = ParsePublicKey(hex.EncodeToString(publicKey[:10]))
+	require.Error(t, err)
+}
+
+func TestParseKeyList(t *testing.T) {
+	keys, err := ParseKeyList(" k1:secret-one, k2:secret:two ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"k1": "secret-one", "k2": "secret:two"}, keys)
+
+	keys, err = ParseKeyList("")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = ParseKeyList("k1")
+	require.Error(t, err)
+
+	_, err = ParseKeyList(":secret")
+	require.Error(t, err)
+
+	_, err = ParseKeyList("k1:a,k1:b")
+	require.Error(t, err)
+}
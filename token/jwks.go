@@ -0,0 +1,172 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const jwksFetchTimeout = 10 * time.Second
+
+// JSONWebKey is an Ed25519 public key in the JWK format (RFC 8037)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+}
+
+// JSONWebKeySet is a JWKS document listing verification keys
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// jsonWebKeyer is implemented by verifiers whose key can be published
+type jsonWebKeyer interface {
+	jsonWebKey(keyID string) JSONWebKey
+}
+
+func newEd25519JSONWebKey(publicKey ed25519.PublicKey, keyID string, algorithm string) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(publicKey),
+		KeyID:     keyID,
+		Use:       "sig",
+		Algorithm: algorithm,
+	}
+}
+
+func (verifier *PasetoPublicVerifier) jsonWebKey(keyID string) JSONWebKey {
+	return newEd25519JSONWebKey(verifier.publicKey, keyID, "")
+}
+
+func (verifier *JWTEdDSAVerifier) jsonWebKey(keyID string) JSONWebKey {
+	return newEd25519JSONWebKey(verifier.publicKey, keyID, "EdDSA")
+}
+
+// JSONWebKeySet returns the public keys of the ring, active key first.
+// Symmetric keys are never published, so the set is empty for them.
+func (ring *VerifierRing) JSONWebKeySet() JSONWebKeySet {
+	keyIDs := make([]string, 0, len(ring.verifiers))
+	for keyID := range ring.verifiers {
+		if keyID != ring.activeKeyID {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	sort.Strings(keyIDs)
+	keyIDs = append([]string{ring.activeKeyID}, keyIDs...)
+
+	keySet := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, keyID := range keyIDs {
+		if verifier, ok := ring.verifiers[keyID].(jsonWebKeyer); ok {
+			keySet.Keys = append(keySet.Keys, verifier.jsonWebKey(keyID))
+		}
+	}
+
+	return keySet
+}
+
+// publicKeyVerifier checks both PASETO v4.public tokens and EdDSA JWTs with one Ed25519 key
+type publicKeyVerifier struct {
+	paseto *PasetoPublicVerifier
+	jwt    *JWTEdDSAVerifier
+}
+
+func (verifier *publicKeyVerifier) VerifyToken(token string) (*Payload, error) {
+	if strings.HasPrefix(token, pasetoV4PublicHeader) {
+		return verifier.paseto.VerifyToken(token)
+	}
+
+	return verifier.jwt.VerifyToken(token)
+}
+
+// NewJWKSVerifier creates a verifier from the keys of a JWKS document.
+// Tokens without a key ID are checked with the first key of the set.
+// VerifyToken accepts tokens of any type, services authorizing requests use VerifyAccessToken.
+func NewJWKSVerifier(keySet JSONWebKeySet) (*VerifierRing, error) {
+	if len(keySet.Keys) == 0 {
+		return nil, fmt.Errorf("key set has no keys")
+	}
+
+	verifiers := make(map[string]Verifier, len(keySet.Keys))
+	for i, key := range keySet.Keys {
+		if key.KeyType != "OKP" || key.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported key %q: only Ed25519 keys are supported", key.KeyID)
+		}
+
+		publicKey, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key %q", key.KeyID)
+		}
+
+		if i > 0 && key.KeyID == "" {
+			return nil, fmt.Errorf("only the first key of the set may omit its ID")
+		}
+
+		if _, ok := verifiers[key.KeyID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", key.KeyID)
+		}
+
+		verifiers[key.KeyID] = &publicKeyVerifier{
+			paseto: &PasetoPublicVerifier{publicKey: publicKey},
+			jwt:    &JWTEdDSAVerifier{publicKey: publicKey},
+		}
+	}
+
+	activeKeyID := keySet.Keys[0].KeyID
+	active := verifiers[activeKeyID]
+	delete(verifiers, activeKeyID)
+
+	return NewVerifierRing(activeKeyID, active, verifiers)
+}
+
+// LoadJWKSVerifier creates a verifier from a JWKS document read from a file or an http(s) URL.
+// The keys are loaded once, so tokens are then checked offline.
+// Like NewJWKSVerifier, requests are authorized with VerifyAccessToken.
+func LoadJWKSVerifier(location string) (*VerifierRing, error) {
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = fetchJWKS(location)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot load key set: %w", err)
+	}
+
+	var keySet JSONWebKeySet
+	err = json.Unmarshal(data, &keySet)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode key set: %w", err)
+	}
+
+	return NewJWKSVerifier(keySet)
+}
+
+func fetchJWKS(url string) ([]byte, error) {
+	client := &http.Client{Timeout: jwksFetchTimeout}
+
+	rsp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", rsp.Status)
+	}
+
+	return io.ReadAll(rsp.Body)
+}
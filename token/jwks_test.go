@@ -0,0 +1,155 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"master_class/util"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONWebKeySet(t *testing.T) {
+	for _, tokenType := range []string{TypePasetoPublic, TypeJWTEdDSA} {
+		t.Run(tokenType, func(t *testing.T) {
+			_, after := rotationConfigs(t, tokenType)
+
+			ring, err := NewMaker(after)
+			require.NoError(t, err)
+
+			keySet := ring.JSONWebKeySet()
+			require.Len(t, keySet.Keys, 2)
+			require.Equal(t, "k2", keySet.Keys[0].KeyID)
+			require.Equal(t, "k1", keySet.Keys[1].KeyID)
+
+			for _, key := range keySet.Keys {
+				require.Equal(t, "OKP", key.KeyType)
+				require.Equal(t, "Ed25519", key.Curve)
+				require.Equal(t, "sig", key.Use)
+
+				publicKey, err := base64.RawURLEncoding.DecodeString(key.X)
+				require.NoError(t, err)
+				require.Len(t, publicKey, ed25519.PublicKeySize)
+			}
+		})
+	}
+
+	ring, err := NewMaker(util.Config{TokenKeyID: "k1", TokenSymmetricKey: util.RandomString(32)})
+	require.NoError(t, err)
+	require.Empty(t, ring.JSONWebKeySet().Keys)
+}
+
+func TestLoadJWKSVerifier(t *testing.T) {
+	for _, tokenType := range []string{TypePasetoPublic, TypeJWTEdDSA} {
+		t.Run(tokenType, func(t *testing.T) {
+			before, after := rotationConfigs(t, tokenType)
+
+			oldRing, err := NewMaker(before)
+			require.NoError(t, err)
+
+			ring, err := NewMaker(after)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(ring.JSONWebKeySet())
+			require.NoError(t, err)
+
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			err = os.WriteFile(path, data, 0o600)
+			require.NoError(t, err)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(data)
+			}))
+			defer server.Close()
+
+			for _, location := range []string{path, server.URL} {
+				verifier, err := LoadJWKSVerifier(location)
+				require.NoError(t, err)
+
+				username := util.RandomOwner()
+				for _, maker := range []Maker{ring, oldRing} {
+					token, _, err := maker.CreateToken(username, util.CustomerRole, AccessToken, time.Minute)
+					require.NoError(t, err)
+
+					payload, err := verifier.VerifyToken(token)
+					require.NoError(t, err)
+					require.Equal(t, username, payload.Username)
+				}
+			}
+		})
+	}
+}
+
+func TestJWKSVerifierRejectsUnknownKey(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewPasetoPublicMaker(privateKey)
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing("k3", maker, nil)
+	require.NoError(t, err)
+
+	_, after := rotationConfigs(t, TypePasetoPublic)
+	published, err := NewMaker(after)
+	require.NoError(t, err)
+
+	verifier, err := NewJWKSVerifier(published.JSONWebKeySet())
+	require.NoError(t, err)
+
+	token, _, err := ring.CreateToken(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := verifier.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestNewJWKSVerifierErrors(t *testing.T) {
+	_, err := NewJWKSVerifier(JSONWebKeySet{})
+	require.Error(t, err)
+
+	_, err = NewJWKSVerifier(JSONWebKeySet{Keys: []JSONWebKey{{KeyType: "RSA", KeyID: "k1"}}})
+	require.Error(t, err)
+
+	_, err = NewJWKSVerifier(JSONWebKeySet{Keys: []JSONWebKey{{KeyType: "OKP", Curve: "Ed25519", X: "short", KeyID: "k1"}}})
+	require.Error(t, err)
+
+	_, err = LoadJWKSVerifier(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestJWKSVerifierAccessToken(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewPasetoPublicMaker(privateKey)
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing("k1", maker, nil)
+	require.NoError(t, err)
+
+	verifier, err := NewJWKSVerifier(ring.JSONWebKeySet())
+	require.NoError(t, err)
+
+	accessToken, _, err := ring.CreateToken(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := verifier.VerifyAccessToken(accessToken)
+	require.NoError(t, err)
+	require.Equal(t, AccessToken, payload.Type)
+
+	refreshToken, _, err := ring.CreateToken(util.RandomOwner(), util.CustomerRole, RefreshToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyAccessToken(refreshToken)
+	require.EqualError(t, err, ErrTokenType.Error())
+	require.Nil(t, payload)
+}
@@ -0,0 +1,88 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// JWTEdDSAVerifier checks EdDSA signed JSON Web Tokens with an Ed25519 public key
+type JWTEdDSAVerifier struct {
+	publicKey ed25519.PublicKey
+}
+
+// JWTEdDSAMaker is a JSON Web Token maker signing with an Ed25519 private key
+type JWTEdDSAMaker struct {
+	*JWTEdDSAVerifier
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+func NewJWTEdDSAMaker(privateKey ed25519.PrivateKey) (Maker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	maker := &JWTEdDSAMaker{
+		JWTEdDSAVerifier: &JWTEdDSAVerifier{publicKey: privateKey.Public().(ed25519.PublicKey)},
+		privateKey:       privateKey,
+	}
+
+	return maker, nil
+}
+
+func NewJWTEdDSAVerifier(publicKey ed25519.PublicKey) (Verifier, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	return &JWTEdDSAVerifier{publicKey: publicKey}, nil
+}
+
+func (maker *JWTEdDSAMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload)
+	setJWTKeyID(jwtToken, maker.keyID)
+	token, err := jwtToken.SignedString(maker.privateKey)
+
+	return token, payload, err
+}
+
+func (maker *JWTEdDSAMaker) setKeyID(keyID string) {
+	maker.keyID = keyID
+}
+
+func (verifier *JWTEdDSAVerifier) VerifyToken(token string) (*Payload, error) {
+	keyfunc := func(token *jwt.Token) (interface{}, error) {
+		_, ok := token.Method.(*jwt.SigningMethodEd25519)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		return verifier.publicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyfunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,99 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"master_class/util"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTEdDSAMaker(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewJWTEdDSAMaker(privateKey)
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	role := util.CustomerRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, AccessToken, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, AccessToken, payload.Type)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestJWTEdDSAVerifier(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewJWTEdDSAMaker(privateKey)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTEdDSAVerifier(publicKey)
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	token, _, err := maker.CreateToken(username, util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := verifier.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, username, payload.Username)
+}
+
+func TestExpiredJWTEdDSAToken(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	maker, err := NewJWTEdDSAMaker(privateKey)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.CustomerRole, AccessToken, -time.Second)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.Error(t, err)
+	require.Nil(t, payload)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+}
+
+func TestInvalidJWTEdDSAToken(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	verifier, err := NewJWTEdDSAVerifier(publicKey)
+	require.NoError(t, err)
+
+	// an HMAC token signed with the public key must not pass as EdDSA
+	payload, err := NewPayload(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	token, err := jwtToken.SignedString([]byte(publicKey))
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyToken(token)
+	require.Error(t, err)
+	require.Nil(t, payload)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
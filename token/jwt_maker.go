@@ -13,6 +13,7 @@ const minSecretKeySize = 32
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
 	secretKey string
+	keyID     string
 }
 
 func NewJWTMaker(secretKey string) (Maker, error) {
@@ -20,18 +21,24 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
 	}
 
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey: secretKey}, nil
 }
 
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *JWTMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	setJWTKeyID(jwtToken, maker.keyID)
+	token, err := jwtToken.SignedString([]byte(maker.secretKey))
 
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	return token, payload, err
+}
+
+func (maker *JWTMaker) setKeyID(keyID string) {
+	maker.keyID = keyID
 }
 
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
@@ -0,0 +1,167 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/o1egl/paseto"
+)
+
+// keyedMaker is a Maker able to tag the tokens it creates with the ID of its key
+type keyedMaker interface {
+	Maker
+	setKeyID(keyID string)
+}
+
+// tokenFooter is the PASETO footer naming the key of the token
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// encodeTokenFooter returns the footer of a token signed with the given key, or no footer
+// without key ID. The footer is encoded here since PASETO libraries encode a nil footer as "null".
+func encodeTokenFooter(keyID string) ([]byte, error) {
+	if keyID == "" {
+		return nil, nil
+	}
+
+	return json.Marshal(tokenFooter{KeyID: keyID})
+}
+
+func setJWTKeyID(jwtToken *jwt.Token, keyID string) {
+	if keyID != "" {
+		jwtToken.Header["kid"] = keyID
+	}
+}
+
+// TokenKeyID returns the key ID carried by the token without verifying it.
+// Tokens created without a key ID return an empty string.
+func TokenKeyID(token string) (string, error) {
+	if strings.HasPrefix(token, "v2.") || strings.HasPrefix(token, "v4.") {
+		var footer tokenFooter
+		if err := paseto.ParseFooter(token, &footer); err != nil {
+			return "", ErrInvalidToken
+		}
+
+		return footer.KeyID, nil
+	}
+
+	jwtToken, _, err := new(jwt.Parser).ParseUnverified(token, &Payload{})
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	keyID, ok := jwtToken.Header["kid"]
+	if !ok {
+		return "", nil
+	}
+
+	kid, ok := keyID.(string)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+
+	return kid, nil
+}
+
+// VerifierRing verifies tokens with the key named by their key ID.
+// Tokens without a key ID are checked with the active key.
+type VerifierRing struct {
+	activeKeyID string
+	verifiers   map[string]Verifier
+}
+
+// KeyRing creates tokens with its active key and verifies tokens of every key it holds,
+// so the signing key can be rotated without invalidating the tokens already issued
+type KeyRing struct {
+	*VerifierRing
+	maker Maker
+}
+
+func NewVerifierRing(activeKeyID string, active Verifier, previous map[string]Verifier) (*VerifierRing, error) {
+	if activeKeyID == "" && len(previous) > 0 {
+		return nil, fmt.Errorf("active key must have an ID when previous keys are configured")
+	}
+
+	ring := &VerifierRing{
+		activeKeyID: activeKeyID,
+		verifiers:   map[string]Verifier{activeKeyID: active},
+	}
+
+	for keyID, verifier := range previous {
+		if keyID == "" {
+			return nil, fmt.Errorf("previous keys must have an ID")
+		}
+
+		if _, ok := ring.verifiers[keyID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", keyID)
+		}
+
+		ring.verifiers[keyID] = verifier
+	}
+
+	return ring, nil
+}
+
+func NewKeyRing(activeKeyID string, active Maker, previous map[string]Verifier) (*KeyRing, error) {
+	if activeKeyID != "" {
+		maker, ok := active.(keyedMaker)
+		if !ok {
+			return nil, fmt.Errorf("token maker %T does not support key IDs", active)
+		}
+
+		maker.setKeyID(activeKeyID)
+	}
+
+	verifierRing, err := NewVerifierRing(activeKeyID, active, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyRing{VerifierRing: verifierRing, maker: active}, nil
+}
+
+// ActiveKeyID returns the ID of the key signing new tokens
+func (ring *VerifierRing) ActiveKeyID() string {
+	return ring.activeKeyID
+}
+
+func (ring *KeyRing) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	return ring.maker.CreateToken(username, role, tokenType, duration)
+}
+
+func (ring *VerifierRing) VerifyToken(token string) (*Payload, error) {
+	keyID, err := TokenKeyID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyID == "" {
+		keyID = ring.activeKeyID
+	}
+
+	verifier, ok := ring.verifiers[keyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return verifier.VerifyToken(token)
+}
+
+// VerifyAccessToken verifies the token like VerifyToken and also checks that it is an access token,
+// so a refresh token can never authorize a request
+func (ring *VerifierRing) VerifyAccessToken(token string) (*Payload, error) {
+	payload, err := ring.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := payload.CheckType(AccessToken); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,173 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"master_class/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rotationConfigs returns the configs before and after rotating from key k1 to key k2
+func rotationConfigs(t *testing.T, tokenType string) (util.Config, util.Config) {
+	switch tokenType {
+	case TypePasetoLocal, TypeJWT:
+		oldKey := util.RandomString(32)
+		newKey := util.RandomString(32)
+
+		before := util.Config{TokenType: tokenType, TokenKeyID: "k1", TokenSymmetricKey: oldKey}
+		after := util.Config{
+			TokenType:         tokenType,
+			TokenKeyID:        "k2",
+			TokenSymmetricKey: newKey,
+			TokenPreviousKeys: fmt.Sprintf("k1:%s", oldKey),
+		}
+
+		return before, after
+	}
+
+	oldPublicKey, oldPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	newPublicKey, newPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	before := util.Config{
+		TokenType:       tokenType,
+		TokenKeyID:      "k1",
+		TokenPrivateKey: hex.EncodeToString(oldPrivateKey),
+		TokenPublicKey:  hex.EncodeToString(oldPublicKey),
+	}
+	after := util.Config{
+		TokenType:         tokenType,
+		TokenKeyID:        "k2",
+		TokenPrivateKey:   hex.EncodeToString(newPrivateKey),
+		TokenPublicKey:    hex.EncodeToString(newPublicKey),
+		TokenPreviousKeys: fmt.Sprintf("k1:%s", hex.EncodeToString(oldPublicKey)),
+	}
+
+	return before, after
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	for _, tokenType := range []string{TypePasetoLocal, TypeJWT, TypePasetoPublic, TypeJWTEdDSA} {
+		t.Run(tokenType, func(t *testing.T) {
+			before, after := rotationConfigs(t, tokenType)
+
+			oldRing, err := NewMaker(before)
+			require.NoError(t, err)
+
+			newRing, err := NewMaker(after)
+			require.NoError(t, err)
+			require.Equal(t, "k2", newRing.ActiveKeyID())
+
+			username := util.RandomOwner()
+
+			oldToken, _, err := oldRing.CreateToken(username, util.CustomerRole, AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			keyID, err := TokenKeyID(oldToken)
+			require.NoError(t, err)
+			require.Equal(t, "k1", keyID)
+
+			newToken, _, err := newRing.CreateToken(username, util.CustomerRole, AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			keyID, err = TokenKeyID(newToken)
+			require.NoError(t, err)
+			require.Equal(t, "k2", keyID)
+
+			// the previous key still verifies the tokens it signed
+			payload, err := newRing.VerifyToken(oldToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+
+			payload, err = newRing.VerifyToken(newToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+
+			// tokens of a key unknown to the ring are rejected
+			payload, err = oldRing.VerifyToken(newToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+
+			// once retired, the previous key no longer verifies anything
+			after.TokenPreviousKeys = ""
+			retiredRing, err := NewMaker(after)
+			require.NoError(t, err)
+
+			payload, err = retiredRing.VerifyToken(oldToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestVerifierRingRotation(t *testing.T) {
+	for _, tokenType := range []string{TypePasetoPublic, TypeJWTEdDSA} {
+		t.Run(tokenType, func(t *testing.T) {
+			before, after := rotationConfigs(t, tokenType)
+
+			oldRing, err := NewMaker(before)
+			require.NoError(t, err)
+
+			verifier, err := NewVerifier(after)
+			require.NoError(t, err)
+
+			oldToken, _, err := oldRing.CreateToken(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+			require.NoError(t, err)
+
+			_, err = verifier.VerifyToken(oldToken)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestKeyRingTokenWithoutKeyID(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+
+	maker, err := NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	// a token without key ID has no footer
+	require.Len(t, strings.Split(token, "."), 3)
+
+	keyID, err := TokenKeyID(token)
+	require.NoError(t, err)
+	require.Empty(t, keyID)
+
+	ring, err := NewMaker(util.Config{TokenKeyID: "k1", TokenSymmetricKey: symmetricKey})
+	require.NoError(t, err)
+
+	// tokens issued before key IDs were configured are checked with the active key
+	payload, err := ring.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotNil(t, payload)
+}
+
+func TestNewKeyRingErrors(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	previous := map[string]Verifier{"k1": maker}
+
+	_, err = NewKeyRing("", maker, previous)
+	require.Error(t, err)
+
+	_, err = NewKeyRing("k1", maker, previous)
+	require.Error(t, err)
+
+	_, err = NewKeyRing("k2", maker, map[string]Verifier{"": maker})
+	require.Error(t, err)
+
+	_, err = NewMaker(util.Config{TokenKeyID: "k2", TokenSymmetricKey: util.RandomString(32), TokenPreviousKeys: "k1:short"})
+	require.Error(t, err)
+}
@@ -3,9 +3,14 @@ package token
 import "time"
 
 type Maker interface {
-	// CreateToken creates a new token for the specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new token of the given type for the specific username, role and duration
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 
+	Verifier
+}
+
+// Verifier checks tokens without being able to create them
+type Verifier interface {
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
@@ -12,6 +12,7 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	keyID        string
 }
 
 func NewPasetoMaker(symmetricKey string) (Maker, error) {
@@ -27,13 +28,24 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	footer, err := encodeTokenFooter(maker.keyID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, footer)
+
+	return token, payload, err
+}
+
+func (maker *PasetoMaker) setKeyID(keyID string) {
+	maker.keyID = keyID
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
@@ -0,0 +1,161 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const pasetoV4PublicHeader = "v4.public."
+
+// PasetoPublicVerifier checks PASETO v4.public tokens with an Ed25519 public key
+type PasetoPublicVerifier struct {
+	publicKey ed25519.PublicKey
+}
+
+// PasetoPublicMaker is a PASETO v4.public token maker signing with an Ed25519 private key
+type PasetoPublicMaker struct {
+	*PasetoPublicVerifier
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey) (Maker, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	maker := &PasetoPublicMaker{
+		PasetoPublicVerifier: &PasetoPublicVerifier{publicKey: privateKey.Public().(ed25519.PublicKey)},
+		privateKey:           privateKey,
+	}
+
+	return maker, nil
+}
+
+func NewPasetoPublicVerifier(publicKey ed25519.PublicKey) (Verifier, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	return &PasetoPublicVerifier{publicKey: publicKey}, nil
+}
+
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	footer, err := encodeTokenFooter(maker.keyID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := signPasetoV4Public(maker.privateKey, message, footer)
+
+	return token, payload, nil
+}
+
+func (maker *PasetoPublicMaker) setKeyID(keyID string) {
+	maker.keyID = keyID
+}
+
+func (verifier *PasetoPublicVerifier) VerifyToken(token string) (*Payload, error) {
+	message, _, err := verifyPasetoV4Public(verifier.publicKey, token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	err = json.Unmarshal(message, payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// signPasetoV4Public builds a v4.public token without implicit assertions
+func signPasetoV4Public(privateKey ed25519.PrivateKey, message []byte, footer []byte) string {
+	signature := ed25519.Sign(privateKey, preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil))
+
+	token := pasetoV4PublicHeader + base64.RawURLEncoding.EncodeToString(append(message, signature...))
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+
+	return token
+}
+
+// verifyPasetoV4Public checks the token signature and returns its message and footer
+func verifyPasetoV4Public(publicKey ed25519.PublicKey, token string) (message []byte, footer []byte, err error) {
+	message, footer, err = splitPasetoV4Public(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature := message[len(message)-ed25519.SignatureSize:]
+	message = message[:len(message)-ed25519.SignatureSize]
+
+	if !ed25519.Verify(publicKey, preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil), signature) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return message, footer, nil
+}
+
+// splitPasetoV4Public decodes the signed body and the footer of the token
+func splitPasetoV4Public(token string) (body []byte, footer []byte, err error) {
+	if !strings.HasPrefix(token, pasetoV4PublicHeader) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	parts := strings.Split(token[len(pasetoV4PublicHeader):], ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	body, err = base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(body) < ed25519.SignatureSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if len(parts) == 2 {
+		footer, err = base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, nil, ErrInvalidToken
+		}
+	}
+
+	return body, footer, nil
+}
+
+// preAuthEncode implements PAE from the PASETO specification
+func preAuthEncode(pieces ...[]byte) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(len(pieces)))
+
+	for _, piece := range pieces {
+		size := make([]byte, 8)
+		binary.LittleEndian.PutUint64(size, uint64(len(piece)))
+		buf = append(buf, size...)
+		buf = append(buf, piece...)
+	}
+
+	return buf
+}
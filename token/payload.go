@@ -10,18 +10,31 @@ import (
 var (
 	ErrExpiredToken = errors.New("Token has expired")
 	ErrInvalidToken = errors.New("Invalid token")
+	ErrTokenType    = errors.New("Wrong token type")
+)
+
+// TokenType tells what a token may be used for
+type TokenType string
+
+const (
+	// AccessToken authorizes the requests of its user
+	AccessToken TokenType = "access"
+	// RefreshToken only renews access tokens and ends its session
+	RefreshToken TokenType = "refresh"
 )
 
 // Payload contains the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Type      TokenType `json:"type"`
 	IssuedAt  time.Time `json:"issuedAt"`
 	ExpiredAt time.Time `json:"expiredAt"`
 }
 
-// NewPayload creates a new Payload for the specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new Payload for the specific username, role, token type and duration
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -30,6 +43,8 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenID,
 		Username:  username,
+		Role:      role,
+		Type:      tokenType,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -45,3 +60,12 @@ func (payload *Payload) Valid() error {
 
 	return nil
 }
+
+// CheckType checks that the token is of the expected type
+func (payload *Payload) CheckType(tokenType TokenType) error {
+	if payload.Type != tokenType {
+		return ErrTokenType
+	}
+
+	return nil
+}
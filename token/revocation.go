@@ -0,0 +1,78 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrRevokedToken = errors.New("Token has been revoked")
+
+// RevocationStore keeps track of tokens invalidated before their expiration
+type RevocationStore interface {
+	// RevokeToken invalidates a single token until it expires
+	RevokeToken(ctx context.Context, payload *Payload) error
+
+	// RevokeUserTokens invalidates every token of the user issued up to the given time
+	RevokeUserTokens(ctx context.Context, username string, issuedBefore time.Time) error
+
+	// IsRevoked checks if the token has been revoked
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+}
+
+// MemoryRevocationStore is a RevocationStore kept in the process memory
+type MemoryRevocationStore struct {
+	mu            sync.RWMutex
+	revokedTokens map[uuid.UUID]time.Time
+	revokedUsers  map[string]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revokedTokens: make(map[uuid.UUID]time.Time),
+		revokedUsers:  make(map[string]time.Time),
+	}
+}
+
+func (store *MemoryRevocationStore) RevokeToken(ctx context.Context, payload *Payload) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	for id, expiredAt := range store.revokedTokens {
+		if now.After(expiredAt) {
+			delete(store.revokedTokens, id)
+		}
+	}
+
+	store.revokedTokens[payload.ID] = payload.ExpiredAt
+
+	return nil
+}
+
+func (store *MemoryRevocationStore) RevokeUserTokens(ctx context.Context, username string, issuedBefore time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if issuedBefore.After(store.revokedUsers[username]) {
+		store.revokedUsers[username] = issuedBefore
+	}
+
+	return nil
+}
+
+func (store *MemoryRevocationStore) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if _, ok := store.revokedTokens[payload.ID]; ok {
+		return true, nil
+	}
+
+	revokedBefore, ok := store.revokedUsers[payload.Username]
+
+	return ok && !payload.IssuedAt.After(revokedBefore), nil
+}
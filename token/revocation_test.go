@@ -0,0 +1,66 @@
+package token
+
+import (
+	"context"
+	"master_class/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationStoreRevokeToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	payload1, err := NewPayload(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload2, err := NewPayload(payload1.Username, util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.RevokeToken(context.Background(), payload1)
+	require.NoError(t, err)
+
+	revoked, err = store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryRevocationStoreRevokeUserTokens(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	oldPayload, err := NewPayload(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+	oldPayload.IssuedAt = time.Now().Add(-time.Second)
+
+	otherPayload, err := NewPayload(util.RandomOwner(), util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+	otherPayload.IssuedAt = oldPayload.IssuedAt
+
+	err = store.RevokeUserTokens(context.Background(), oldPayload.Username, time.Now())
+	require.NoError(t, err)
+
+	newPayload, err := NewPayload(oldPayload.Username, util.CustomerRole, AccessToken, time.Minute)
+	require.NoError(t, err)
+	newPayload.IssuedAt = time.Now().Add(time.Second)
+
+	revoked, err := store.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -7,11 +7,24 @@ import (
 )
 
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver             string        `mapstructure:"DB_DRIVER"`
+	DBSource             string        `mapstructure:"DB_SOURCE"`
+	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
+	TokenType            string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenPrivateKey      string        `mapstructure:"TOKEN_PRIVATE_KEY"`
+	TokenPublicKey       string        `mapstructure:"TOKEN_PUBLIC_KEY"`
+	TokenKeyID           string        `mapstructure:"TOKEN_KEY_ID"`
+	TokenPreviousKeys    string        `mapstructure:"TOKEN_PREVIOUS_KEYS"`
+	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	FXRates              string        `mapstructure:"FX_RATES"`
+	FXRatesFile          string        `mapstructure:"FX_RATES_FILE"`
+	FXSpread             string        `mapstructure:"FX_SPREAD"`
+	// ScheduledTransferInterval is how often due scheduled transfers and standing orders are executed, 0 disables it
+	ScheduledTransferInterval time.Duration `mapstructure:"SCHEDULED_TRANSFER_INTERVAL"`
+	// RevokedTokenPurgeInterval is how often expired revoked tokens are deleted, 0 disables it
+	RevokedTokenPurgeInterval time.Duration `mapstructure:"REVOKED_TOKEN_PURGE_INTERVAL"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -1,17 +1,163 @@
 package util
 
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 )
 
+// Currency describes an ISO 4217 currency
+type Currency struct {
+	Code        string `json:"code"`
+	NumericCode string `json:"numeric_code"`
+	// MinorUnits is the number of decimal places of the currency.
+	// Amounts are stored as integers counting units of 10^-MinorUnits.
+	MinorUnits int    `json:"minor_units"`
+	Symbol     string `json:"symbol"`
+	// Enabled currencies can be used for accounts and transfers
+	Enabled bool `json:"enabled"`
+}
+
+// maxMinorUnits is the largest exponent used by ISO 4217
+const maxMinorUnits = 4
+
+var (
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	numericCodePattern  = regexp.MustCompile(`^[0-9]{3}$`)
+)
+
+// FormatAmount formats an amount of minor units as a decimal, such as "12.34" for 1234 cents
+func (currency Currency) FormatAmount(amount int64) string {
+	return new(big.Rat).SetFrac(big.NewInt(amount), currency.minorUnitsPerUnit()).FloatString(currency.MinorUnits)
+}
+
+func (currency Currency) minorUnitsPerUnit() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currency.MinorUnits)), nil)
+}
+
+// MinorUnitRate turns a rate between units of two currencies into a rate between their minor units
+func MinorUnitRate(rate *big.Rat, from Currency, to Currency) *big.Rat {
+	scale := new(big.Rat).SetFrac(to.minorUnitsPerUnit(), from.minorUnitsPerUnit())
+	return scale.Mul(scale, rate)
+}
+
+// CurrencyRegistry holds the currencies known to the bank, keyed by their code
+type CurrencyRegistry struct {
+	currencies map[string]Currency
+}
+
+func NewCurrencyRegistry(currencies []Currency) (*CurrencyRegistry, error) {
+	registry := &CurrencyRegistry{currencies: make(map[string]Currency, len(currencies))}
+
+	for _, currency := range currencies {
+		if !currencyCodePattern.MatchString(currency.Code) {
+			return nil, fmt.Errorf("invalid currency code %q", currency.Code)
+		}
+
+		if !numericCodePattern.MatchString(currency.NumericCode) {
+			return nil, fmt.Errorf("invalid numeric code %q for %s", currency.NumericCode, currency.Code)
+		}
+
+		if currency.MinorUnits < 0 || currency.MinorUnits > maxMinorUnits {
+			return nil, fmt.Errorf("invalid minor units %d for %s", currency.MinorUnits, currency.Code)
+		}
+
+		if _, ok := registry.currencies[currency.Code]; ok {
+			return nil, fmt.Errorf("duplicate currency %s", currency.Code)
+		}
+
+		registry.currencies[currency.Code] = currency
+	}
+
+	return registry, nil
+}
+
+// LoadCurrencyRegistry reads a registry from a JSON list of currencies
+func LoadCurrencyRegistry(r io.Reader) (*CurrencyRegistry, error) {
+	var currencies []Currency
+	if err := json.NewDecoder(r).Decode(&currencies); err != nil {
+		return nil, fmt.Errorf("cannot decode currencies: %w", err)
+	}
+
+	return NewCurrencyRegistry(currencies)
+}
+
+// Lookup returns the currency of the code, whether it is enabled or not
+func (registry *CurrencyRegistry) Lookup(code string) (Currency, bool) {
+	currency, ok := registry.currencies[code]
+	return currency, ok
+}
+
+func (registry *CurrencyRegistry) IsEnabled(code string) bool {
+	currency, ok := registry.currencies[code]
+	return ok && currency.Enabled
+}
+
+// Enabled returns the enabled currencies sorted by code
+func (registry *CurrencyRegistry) Enabled() []Currency {
+	var enabled []Currency
+	for _, currency := range registry.currencies {
+		if currency.Enabled {
+			enabled = append(enabled, currency)
+		}
+	}
+
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].Code < enabled[j].Code
+	})
+
+	return enabled
+}
+
+//go:embed currencies.json
+var currenciesFile []byte
+
+// currencies is the registry of the embedded ISO 4217 file
+var currencies = mustLoadCurrencyRegistry(currenciesFile)
+
+func mustLoadCurrencyRegistry(data []byte) *CurrencyRegistry {
+	registry, err := LoadCurrencyRegistry(bytes.NewReader(data))
+	if err != nil {
+		panic(fmt.Sprintf("cannot load embedded currencies: %v", err))
+	}
+
+	return registry
+}
+
+// Currencies returns the registry of currencies the bank knows about
+func Currencies() *CurrencyRegistry {
+	return currencies
+}
+
+// LookupCurrency returns the currency of the code from the registry
+func LookupCurrency(code string) (Currency, bool) {
+	return currencies.Lookup(code)
+}
+
+// IsSupportedCurrency reports whether accounts and transfers may use the currency
 func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD:
-		return true
+	return currencies.IsEnabled(currency)
+}
+
+// FormatAmount formats an amount of minor units of the currency as a decimal
+func FormatAmount(amount int64, code string) (string, error) {
+	currency, ok := LookupCurrency(code)
+	if !ok {
+		return "", fmt.Errorf("unknown currency %q", code)
 	}
-	return false
+
+	return currency.FormatAmount(amount), nil
 }
 
 func PickOtherCurrency(currency string) string {
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedCurrencies(t *testing.T) {
+	for _, code := range []string{USD, EUR, CAD} {
+		require.True(t, IsSupportedCurrency(code), code)
+	}
+
+	require.False(t, IsSupportedCurrency("XYZ"))
+
+	yen, ok := LookupCurrency("JPY")
+	require.True(t, ok)
+	require.Equal(t, "392", yen.NumericCode)
+	require.Zero(t, yen.MinorUnits)
+	require.False(t, IsSupportedCurrency("JPY"))
+
+	enabled := Currencies().Enabled()
+	require.NotEmpty(t, enabled)
+	for i := 1; i < len(enabled); i++ {
+		require.Less(t, enabled[i-1].Code, enabled[i].Code)
+	}
+}
+
+func TestLoadCurrencyRegistry(t *testing.T) {
+	registry, err := LoadCurrencyRegistry(strings.NewReader(
+		`[{"code": "KWD", "numeric_code": "414", "minor_units": 3, "symbol": "KD", "enabled": true}]`,
+	))
+	require.NoError(t, err)
+	require.True(t, registry.IsEnabled("KWD"))
+	require.False(t, registry.IsEnabled(USD))
+
+	invalid := []string{
+		`[{"code": "usd", "numeric_code": "840", "minor_units": 2}]`,
+		`[{"code": "USD", "numeric_code": "84", "minor_units": 2}]`,
+		`[{"code": "USD", "numeric_code": "840", "minor_units": 9}]`,
+		`[{"code": "USD", "numeric_code": "840"}, {"code": "USD", "numeric_code": "840"}]`,
+		`{"code": "USD"}`,
+	}
+	for _, data := range invalid {
+		_, err := LoadCurrencyRegistry(strings.NewReader(data))
+		require.Error(t, err, data)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		code     string
+		expected string
+	}{
+		{1234, USD, "12.34"},
+		{-5, EUR, "-0.05"},
+		{0, CAD, "0.00"},
+		{1234, "JPY", "1234"},
+		{1234, "KWD", "1.234"},
+	}
+
+	for _, tc := range testCases {
+		formatted, err := FormatAmount(tc.amount, tc.code)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, formatted)
+	}
+
+	_, err := FormatAmount(1, "XYZ")
+	require.Error(t, err)
+}
+
+func TestMinorUnitRate(t *testing.T) {
+	usd, _ := LookupCurrency(USD)
+	yen, _ := LookupCurrency("JPY")
+	dinar, _ := LookupCurrency("KWD")
+
+	// 100 cents buy 150 yen at 150 JPY per USD
+	rate := MinorUnitRate(big.NewRat(150, 1), usd, yen)
+	converted, err := ConvertAmount(100, rate, new(big.Rat))
+	require.NoError(t, err)
+	require.Equal(t, int64(150), converted)
+
+	// 1000 fils buy 325 cents at 3.25 USD per KWD
+	rate = MinorUnitRate(big.NewRat(325, 100), dinar, usd)
+	converted, err = ConvertAmount(1000, rate, new(big.Rat))
+	require.NoError(t, err)
+	require.Equal(t, int64(325), converted)
+}
@@ -0,0 +1,214 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrRateNotFound = errors.New("exchange rate is not available")
+
+// RateProvider gives the exchange rates used to convert transfers between currencies
+type RateProvider interface {
+	// Rate returns how many units of the to currency one unit of the from currency buys
+	Rate(ctx context.Context, from string, to string) (*big.Rat, error)
+}
+
+// ParseDecimal parses a non-negative decimal number such as "1.0825"
+func ParseDecimal(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return new(big.Rat), nil
+	}
+
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok || strings.ContainsAny(value, "/eE") {
+		return nil, fmt.Errorf("invalid decimal %q", value)
+	}
+
+	if rat.Sign() < 0 {
+		return nil, fmt.Errorf("decimal %q must not be negative", value)
+	}
+
+	return rat, nil
+}
+
+// ConvertAmount converts an amount at the rate, minus the spread kept by the bank.
+// The result is rounded down so conversions never credit more than they debit.
+// It fails with ErrMoneyOverflow when the converted amount does not fit in an int64.
+func ConvertAmount(amount int64, rate *big.Rat, spread *big.Rat) (int64, error) {
+	kept := new(big.Rat).Sub(big.NewRat(1, 1), spread)
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	converted.Mul(converted, kept)
+
+	toAmount := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !toAmount.IsInt64() {
+		return 0, ErrMoneyOverflow
+	}
+
+	return toAmount.Int64(), nil
+}
+
+// StaticRateProvider serves a fixed set of rates
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider creates a provider from rates keyed by "FROM/TO" pairs.
+// The inverse of a pair is derived when only one direction is given.
+func NewStaticRateProvider(rates map[string]string) (*StaticRateProvider, error) {
+	provider := &StaticRateProvider{rates: make(map[string]*big.Rat, len(rates))}
+
+	for pair, value := range rates {
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid currency pair %q: must be formatted as FROM/TO", pair)
+		}
+
+		rate, err := ParseDecimal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate for %s: %w", pair, err)
+		}
+
+		if rate.Sign() == 0 {
+			return nil, fmt.Errorf("invalid rate for %s: must be positive", pair)
+		}
+
+		provider.rates[rateKey(from, to)] = rate
+	}
+
+	return provider, nil
+}
+
+func rateKey(from string, to string) string {
+	return strings.ToUpper(from) + "/" + strings.ToUpper(to)
+}
+
+func (provider *StaticRateProvider) Rate(ctx context.Context, from string, to string) (*big.Rat, error) {
+	if strings.EqualFold(from, to) {
+		return big.NewRat(1, 1), nil
+	}
+
+	if rate, ok := provider.rates[rateKey(from, to)]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+
+	if rate, ok := provider.rates[rateKey(to, from)]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+}
+
+// ParseRateList decodes a comma separated list of "FROM/TO:rate" entries
+func ParseRateList(list string) (map[string]string, error) {
+	rates := make(map[string]string)
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pair, rate, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate entry %q: must be formatted as FROM/TO:rate", entry)
+		}
+
+		rates[pair] = rate
+	}
+
+	return rates, nil
+}
+
+// FileRateProvider serves the rates of a JSON file mapping "FROM/TO" pairs to rates.
+// The file is read again whenever it changes, so rates can be updated without a restart.
+type FileRateProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	rates   *StaticRateProvider
+}
+
+func NewFileRateProvider(path string) (*FileRateProvider, error) {
+	provider := &FileRateProvider{path: path}
+
+	_, err := provider.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// load returns the rates of the file, reading it only when it was modified
+func (provider *FileRateProvider) load() (*StaticRateProvider, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	info, err := os.Stat(provider.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	if provider.rates != nil && info.ModTime().Equal(provider.modTime) {
+		return provider.rates, nil
+	}
+
+	data, err := os.ReadFile(provider.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	var values map[string]json.Number
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode rates file: %w", err)
+	}
+
+	rates := make(map[string]string, len(values))
+	for pair, value := range values {
+		rates[pair] = value.String()
+	}
+
+	static, err := NewStaticRateProvider(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.rates = static
+	provider.modTime = info.ModTime()
+
+	return static, nil
+}
+
+func (provider *FileRateProvider) Rate(ctx context.Context, from string, to string) (*big.Rat, error) {
+	rates, err := provider.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return rates.Rate(ctx, from, to)
+}
+
+// NewRateProvider creates the rate provider selected by the config:
+// the FX_RATES_FILE file when set, the static FX_RATES list otherwise
+func NewRateProvider(config Config) (RateProvider, error) {
+	if config.FXRatesFile != "" {
+		return NewFileRateProvider(config.FXRatesFile)
+	}
+
+	rates, err := ParseRateList(config.FXRates)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStaticRateProvider(rates)
+}
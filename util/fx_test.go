@@ -0,0 +1,118 @@
+package util
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertAmount(t *testing.T) {
+	rate, err := ParseDecimal("1.08")
+	require.NoError(t, err)
+
+	spread, err := ParseDecimal("0.005")
+	require.NoError(t, err)
+
+	converted, err := ConvertAmount(1000, rate, spread)
+	require.NoError(t, err)
+	require.Equal(t, int64(1074), converted)
+
+	converted, err = ConvertAmount(1000, rate, new(big.Rat))
+	require.NoError(t, err)
+	require.Equal(t, int64(1080), converted)
+
+	converted, err = ConvertAmount(0, rate, spread)
+	require.NoError(t, err)
+	require.Zero(t, converted)
+
+	_, err = ConvertAmount(math.MaxInt64, big.NewRat(2, 1), new(big.Rat))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+}
+
+func TestParseDecimal(t *testing.T) {
+	value, err := ParseDecimal(" 0.25 ")
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(1, 4), value)
+
+	value, err = ParseDecimal("")
+	require.NoError(t, err)
+	require.Zero(t, value.Sign())
+
+	for _, invalid := range []string{"abc", "1/4", "1e3", "-1"} {
+		_, err = ParseDecimal(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestStaticRateProvider(t *testing.T) {
+	rates, err := ParseRateList("EUR/USD:1.25, USD/CAD:1.36")
+	require.NoError(t, err)
+
+	provider, err := NewStaticRateProvider(rates)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(5, 4), rate)
+
+	// the inverse is derived from the configured direction
+	rate, err = provider.Rate(context.Background(), USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(4, 5), rate)
+
+	rate, err = provider.Rate(context.Background(), CAD, CAD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(1, 1), rate)
+
+	_, err = provider.Rate(context.Background(), EUR, CAD)
+	require.ErrorIs(t, err, ErrRateNotFound)
+
+	_, err = NewStaticRateProvider(map[string]string{"EURUSD": "1.1"})
+	require.Error(t, err)
+
+	_, err = NewStaticRateProvider(map[string]string{"EUR/USD": "0"})
+	require.Error(t, err)
+
+	_, err = ParseRateList("EUR/USD")
+	require.Error(t, err)
+}
+
+func TestFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"EUR/USD": 1.25}`), 0o600)
+	require.NoError(t, err)
+
+	provider, err := NewFileRateProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(5, 4), rate)
+
+	// updates of the file are picked up
+	err = os.WriteFile(path, []byte(`{"EUR/USD": 1.5}`), 0o600)
+	require.NoError(t, err)
+	err = os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+
+	rate, err = provider.Rate(context.Background(), EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(3, 2), rate)
+
+	_, err = NewFileRateProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+
+	provider2, err := NewRateProvider(Config{FXRatesFile: path})
+	require.NoError(t, err)
+	require.IsType(t, &FileRateProvider{}, provider2)
+
+	provider2, err = NewRateProvider(Config{FXRates: "EUR/USD:1.1"})
+	require.NoError(t, err)
+	require.IsType(t, &StaticRateProvider{}, provider2)
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrMixedCurrencies = errors.New("cannot combine amounts of different currencies")
+	// ErrMoneyOverflow is returned when an amount, parsed or computed, does not fit in 64 bits of minor units
+	ErrMoneyOverflow = errors.New("amount is out of range")
+)
+
+var decimalAmountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an amount in minor units of a currency, such as 1234 cents of USD for $12.34
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount such as "12.34" in the given currency.
+// It refuses amounts with more decimal places than the currency has minor units.
+func ParseMoney(value string, currencyCode string) (Money, error) {
+	currency, ok := LookupCurrency(currencyCode)
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency %q", currencyCode)
+	}
+
+	value = strings.TrimSpace(value)
+	if !decimalAmountPattern.MatchString(value) {
+		return Money{}, fmt.Errorf("invalid amount %q: must be a decimal such as 12.34", value)
+	}
+
+	if _, fraction, ok := strings.Cut(value, "."); ok && len(fraction) > currency.MinorUnits {
+		return Money{}, fmt.Errorf("invalid amount %q: %s has %d decimal places", value, currency.Code, currency.MinorUnits)
+	}
+
+	amount, _ := new(big.Rat).SetString(value)
+	amount.Mul(amount, new(big.Rat).SetInt(currency.minorUnitsPerUnit()))
+
+	minorUnits := amount.Num()
+	if !minorUnits.IsInt64() {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", value, ErrMoneyOverflow)
+	}
+
+	return NewMoney(minorUnits.Int64(), currency.Code), nil
+}
+
+// Decimal is an amount as clients send it, a decimal such as "12.34" whose currency is given apart,
+// by the request or by the account it applies to
+type Decimal string
+
+// UnmarshalJSON decodes a decimal from a JSON string, refusing any other value or a malformed decimal
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid amount %s: must be a string such as \"12.34\"", data)
+	}
+
+	value = strings.TrimSpace(value)
+	if value != "" && !decimalAmountPattern.MatchString(value) {
+		return fmt.Errorf("invalid amount %q: must be a decimal such as 12.34", value)
+	}
+
+	*d = Decimal(value)
+	return nil
+}
+
+// Money scales the decimal to minor units of the currency like ParseMoney
+func (d Decimal) Money(currency string) (Money, error) {
+	return ParseMoney(string(d), currency)
+}
+
+// Add returns the sum of both amounts, which must be of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrMixedCurrencies, m.Currency, other.Currency)
+	}
+
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return NewMoney(m.Amount+other.Amount, m.Currency), nil
+}
+
+// Sub returns the difference of both amounts, which must be of the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrMixedCurrencies, m.Currency, other.Currency)
+	}
+
+	if (other.Amount < 0 && m.Amount > math.MaxInt64+other.Amount) ||
+		(other.Amount > 0 && m.Amount < math.MinInt64+other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return NewMoney(m.Amount-other.Amount, m.Currency), nil
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Decimal formats the amount as a decimal scaled by the minor units of its currency
+func (m Money) Decimal() (string, error) {
+	return FormatAmount(m.Amount, m.Currency)
+}
+
+func (m Money) String() string {
+	decimal, err := m.Decimal()
+	if err != nil {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	return decimal + " " + m.Currency
+}
+
+// MarshalJSON encodes the amount as a decimal string such as "12.34".
+// The currency is left to the enclosing object, which carries it already.
+func (m Money) MarshalJSON() ([]byte, error) {
+	decimal, err := m.Decimal()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decimal)
+}
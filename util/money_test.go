@@ -0,0 +1,110 @@
+package util
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		value    string
+		currency string
+		expected int64
+	}{
+		{"12.34", USD, 1234},
+		{"12.3", EUR, 1230},
+		{"12", CAD, 1200},
+		{"-0.05", USD, -5},
+		{"1234", "JPY", 1234},
+		{"1.234", "KWD", 1234},
+	}
+
+	for _, tc := range testCases {
+		money, err := ParseMoney(tc.value, tc.currency)
+		require.NoError(t, err, tc.value)
+		require.Equal(t, NewMoney(tc.expected, tc.currency), money)
+	}
+
+	invalid := []struct {
+		value    string
+		currency string
+	}{
+		{"12.345", USD},
+		{"1.5", "JPY"},
+		{"abc", USD},
+		{"1e3", USD},
+		{".5", USD},
+		{"", USD},
+		{"92233720368547758.08", USD},
+		{"1.00", "XYZ"},
+	}
+
+	for _, tc := range invalid {
+		_, err := ParseMoney(tc.value, tc.currency)
+		require.Error(t, err, tc.value)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	sum, err := NewMoney(1234, USD).Add(NewMoney(66, USD))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1300, USD), sum)
+
+	difference, err := NewMoney(100, USD).Sub(NewMoney(250, USD))
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(-150, USD), difference)
+
+	_, err = NewMoney(100, USD).Add(NewMoney(100, EUR))
+	require.ErrorIs(t, err, ErrMixedCurrencies)
+
+	_, err = NewMoney(100, USD).Sub(NewMoney(100, EUR))
+	require.ErrorIs(t, err, ErrMixedCurrencies)
+
+	_, err = NewMoney(math.MaxInt64, USD).Add(NewMoney(1, USD))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = NewMoney(math.MinInt64, USD).Add(NewMoney(-1, USD))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = NewMoney(-2, USD).Sub(NewMoney(math.MaxInt64, USD))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = NewMoney(0, USD).Sub(NewMoney(math.MinInt64, USD))
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Balance Money `json:"balance"`
+	}{NewMoney(-1234, USD)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"balance": "-12.34"}`, string(data))
+
+	_, err = json.Marshal(NewMoney(1, "XYZ"))
+	require.Error(t, err)
+
+	require.Equal(t, "12.34 EUR", NewMoney(1234, EUR).String())
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var req struct {
+		Amount Decimal `json:"amount"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": " 12.34 "}`), &req))
+	require.Equal(t, Decimal("12.34"), req.Amount)
+
+	amount, err := req.Amount.Money(USD)
+	require.NoError(t, err)
+	require.Equal(t, NewMoney(1234, USD), amount)
+
+	_, err = req.Amount.Money("XYZ")
+	require.Error(t, err)
+
+	for _, body := range []string{`{"amount": 12.34}`, `{"amount": "1e3"}`, `{"amount": "12,34"}`, `{"amount": true}`} {
+		require.Error(t, json.Unmarshal([]byte(body), &req), body)
+	}
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Frequencies of a recurrence
+const (
+	DailyFrequency   = "daily"
+	WeeklyFrequency  = "weekly"
+	MonthlyFrequency = "monthly"
+)
+
+// Recurrence describes when the occurrences of a repeated event fall
+type Recurrence struct {
+	Frequency string
+	// Interval is the number of days, weeks or months between occurrences
+	Interval int
+	// DayOfMonth pins monthly occurrences to a day, moved back to the last day of shorter months.
+	// Zero keeps the day of StartAt.
+	DayOfMonth int
+	StartAt    time.Time
+	// EndAt is the latest time an occurrence may fall, zero for none
+	EndAt time.Time
+	// MaxOccurrences bounds the number of occurrences, zero for none
+	MaxOccurrences int
+}
+
+func (r Recurrence) Validate() error {
+	switch r.Frequency {
+	case DailyFrequency, WeeklyFrequency, MonthlyFrequency:
+	default:
+		return fmt.Errorf("invalid frequency %q: must be %s, %s or %s", r.Frequency, DailyFrequency, WeeklyFrequency, MonthlyFrequency)
+	}
+
+	if r.Interval < 1 {
+		return errors.New("interval must be at least 1")
+	}
+
+	if r.DayOfMonth < 0 || r.DayOfMonth > 31 {
+		return errors.New("day of month must be between 1 and 31")
+	}
+
+	if r.DayOfMonth != 0 && r.Frequency != MonthlyFrequency {
+		return errors.New("day of month only applies to monthly recurrences")
+	}
+
+	if r.StartAt.IsZero() {
+		return errors.New("start time is required")
+	}
+
+	if !r.EndAt.IsZero() && r.EndAt.Before(r.StartAt) {
+		return errors.New("end time must not be before the start time")
+	}
+
+	if r.MaxOccurrences < 0 {
+		return errors.New("max occurrences must not be negative")
+	}
+
+	return nil
+}
+
+// Occurrence returns the time of the occurrence of index n, counting from 0,
+// regardless of the end of the recurrence
+func (r Recurrence) Occurrence(n int) time.Time {
+	switch r.Frequency {
+	case DailyFrequency:
+		return r.StartAt.AddDate(0, 0, n*r.Interval)
+	case WeeklyFrequency:
+		return r.StartAt.AddDate(0, 0, 7*n*r.Interval)
+	}
+
+	// a pinned day before the start day falls in the next period
+	if r.monthlyOccurrence(0).Before(r.StartAt) {
+		n++
+	}
+
+	return r.monthlyOccurrence(n)
+}
+
+func (r Recurrence) monthlyOccurrence(n int) time.Time {
+	day := r.DayOfMonth
+	if day == 0 {
+		day = r.StartAt.Day()
+	}
+
+	// count months from the first of the month, so AddDate cannot overflow into the next one
+	first := time.Date(
+		r.StartAt.Year(), r.StartAt.Month()+time.Month(n*r.Interval), 1,
+		r.StartAt.Hour(), r.StartAt.Minute(), r.StartAt.Second(), r.StartAt.Nanosecond(),
+		r.StartAt.Location(),
+	)
+
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+// Next returns the time of the occurrence of index n, or false when the recurrence ends before it
+func (r Recurrence) Next(n int) (time.Time, bool) {
+	if r.MaxOccurrences > 0 && n >= r.MaxOccurrences {
+		return time.Time{}, false
+	}
+
+	at := r.Occurrence(n)
+	if !r.EndAt.IsZero() && at.After(r.EndAt) {
+		return time.Time{}, false
+	}
+
+	return at, true
+}
+
+// NextFrom returns the index and time of the first occurrence from index n that is not
+// before t, skipping the ones missed in between. It returns false when none is left.
+func (r Recurrence) NextFrom(n int, t time.Time) (int, time.Time, bool) {
+	for {
+		at, ok := r.Next(n)
+		if !ok || !at.Before(t) {
+			return n, at, ok
+		}
+
+		n++
+	}
+}
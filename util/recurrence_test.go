@@ -0,0 +1,117 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+}
+
+func TestRecurrenceOccurrence(t *testing.T) {
+	testCases := []struct {
+		name       string
+		recurrence Recurrence
+		expected   []time.Time
+	}{
+		{
+			name:       "Daily",
+			recurrence: Recurrence{Frequency: DailyFrequency, Interval: 3, StartAt: date(2024, time.February, 27)},
+			expected:   []time.Time{date(2024, time.February, 27), date(2024, time.March, 1), date(2024, time.March, 4)},
+		},
+		{
+			name:       "EveryTwoWeeks",
+			recurrence: Recurrence{Frequency: WeeklyFrequency, Interval: 2, StartAt: date(2024, time.December, 20)},
+			expected:   []time.Time{date(2024, time.December, 20), date(2025, time.January, 3), date(2025, time.January, 17)},
+		},
+		{
+			name:       "MonthlyClampedToMonthEnd",
+			recurrence: Recurrence{Frequency: MonthlyFrequency, Interval: 1, StartAt: date(2024, time.January, 31)},
+			expected:   []time.Time{date(2024, time.January, 31), date(2024, time.February, 29), date(2024, time.March, 31)},
+		},
+		{
+			name:       "DayOfMonthAfterStart",
+			recurrence: Recurrence{Frequency: MonthlyFrequency, Interval: 1, DayOfMonth: 25, StartAt: date(2024, time.January, 10)},
+			expected:   []time.Time{date(2024, time.January, 25), date(2024, time.February, 25), date(2024, time.March, 25)},
+		},
+		{
+			name:       "DayOfMonthBeforeStart",
+			recurrence: Recurrence{Frequency: MonthlyFrequency, Interval: 2, DayOfMonth: 1, StartAt: date(2024, time.November, 10)},
+			expected:   []time.Time{date(2025, time.January, 1), date(2025, time.March, 1), date(2025, time.May, 1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.recurrence.Validate())
+
+			for n, expected := range tc.expected {
+				require.Equal(t, expected, tc.recurrence.Occurrence(n), n)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	recurrence := Recurrence{
+		Frequency: WeeklyFrequency,
+		Interval:  2,
+		StartAt:   date(2024, time.December, 6),
+		EndAt:     date(2024, time.December, 31),
+	}
+
+	at, ok := recurrence.Next(1)
+	require.True(t, ok)
+	require.Equal(t, date(2024, time.December, 20), at)
+
+	// January 3 is past the end
+	_, ok = recurrence.Next(2)
+	require.False(t, ok)
+
+	recurrence.EndAt = time.Time{}
+	recurrence.MaxOccurrences = 2
+
+	_, ok = recurrence.Next(1)
+	require.True(t, ok)
+
+	_, ok = recurrence.Next(2)
+	require.False(t, ok)
+}
+
+func TestRecurrenceNextFrom(t *testing.T) {
+	recurrence := Recurrence{Frequency: DailyFrequency, Interval: 1, StartAt: date(2024, time.March, 1), MaxOccurrences: 10}
+
+	n, at, ok := recurrence.NextFrom(0, date(2024, time.March, 4))
+	require.True(t, ok)
+	require.Equal(t, 3, n)
+	require.Equal(t, date(2024, time.March, 4), at)
+
+	n, at, ok = recurrence.NextFrom(5, date(2024, time.March, 4))
+	require.True(t, ok)
+	require.Equal(t, 5, n)
+	require.Equal(t, date(2024, time.March, 6), at)
+
+	_, _, ok = recurrence.NextFrom(0, date(2024, time.April, 1))
+	require.False(t, ok)
+}
+
+func TestRecurrenceValidate(t *testing.T) {
+	start := date(2024, time.March, 1)
+
+	invalid := []Recurrence{
+		{Frequency: "yearly", Interval: 1, StartAt: start},
+		{Frequency: DailyFrequency, Interval: 0, StartAt: start},
+		{Frequency: WeeklyFrequency, Interval: 1, DayOfMonth: 5, StartAt: start},
+		{Frequency: MonthlyFrequency, Interval: 1, DayOfMonth: 32, StartAt: start},
+		{Frequency: MonthlyFrequency, Interval: 1},
+		{Frequency: MonthlyFrequency, Interval: 1, StartAt: start, EndAt: start.Add(-time.Hour)},
+		{Frequency: MonthlyFrequency, Interval: 1, StartAt: start, MaxOccurrences: -1},
+	}
+
+	for _, recurrence := range invalid {
+		require.Error(t, recurrence.Validate(), recurrence)
+	}
+}
@@ -0,0 +1,8 @@
+package util
+
+// Constants for all supported user roles
+const (
+	CustomerRole = "customer"
+	TellerRole   = "teller"
+	AdminRole    = "admin"
+)